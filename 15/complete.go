@@ -0,0 +1,261 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+	"wb_l2/15/editor"
+	"wb_l2/15/parser"
+)
+
+// Символы, которые в дополненном слове экранируются обратным слешем, чтобы
+// слово разобралось так же, как имя файла
+const specialChars = " \t\n\\'\"$`|&;()<>*?#!{}[]~"
+
+// Дополняет слово под курсором: первое слово команды — именем builtin или
+// программы из $PATH, остальные слова и команды с / — путями к файлам
+func completeLine(line []rune, pos int) (int, []editor.Completion) {
+	start, command := wordAt(line, pos)
+	word := string(line[start:pos])
+
+	if prefix := unquoteWord(word); command && !strings.Contains(prefix, "/") {
+		return start, commandCompletions(prefix)
+	}
+
+	return start, pathCompletions(word)
+}
+
+// Возвращает начало слова перед курсором и стоит ли оно на месте имени
+// команды: в начале строки, после |, &, ;, ( или присваиваний
+func wordAt(line []rune, pos int) (int, bool) {
+	start, command, inWord := pos, true, false
+	var quote rune
+
+	// Слово закончилось: после присваивания еще может идти имя команды
+	endWord := func(end int) {
+		if inWord {
+			name, _, ok := strings.Cut(string(line[start:end]), "=")
+			command = command && ok && parser.ValidName(name)
+		}
+		inWord = false
+	}
+
+	for i := 0; i < pos; i++ {
+		c := line[i]
+		if !inWord && !unicode.IsSpace(c) && !strings.ContainsRune("|&;()<>", c) {
+			start, inWord = i, true
+		}
+
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			} else if c == '\\' && quote == '"' {
+				i++
+			}
+		case c == '\\':
+			i++
+		case c == '\'' || c == '"':
+			quote = c
+		case unicode.IsSpace(c):
+			endWord(i)
+		case strings.ContainsRune("|&;(", c):
+			endWord(i)
+			command = true
+		case c == '<' || c == '>' || c == ')':
+			endWord(i)
+			command = false
+		}
+	}
+
+	if !inWord {
+		return pos, command
+	}
+
+	return start, command
+}
+
+// Снимает кавычки и экранирование с начала слова
+func unquoteWord(word string) string {
+	unquoted, _ := scanWord(word)
+	return unquoted
+}
+
+// Возвращает начало слова без кавычек и кавычку, которая осталась открытой
+// в его конце, или 0
+func scanWord(word string) (string, rune) {
+	var b strings.Builder
+	var quote rune
+	escaped := false
+	for _, c := range word {
+		switch {
+		case escaped:
+			b.WriteRune(c)
+			escaped = false
+		case c == '\\' && quote != '\'':
+			escaped = true
+		case quote != 0 && c == quote:
+			quote = 0
+		case quote == 0 && (c == '\'' || c == '"'):
+			quote = c
+		default:
+			b.WriteRune(c)
+		}
+	}
+
+	return b.String(), quote
+}
+
+func quoteWord(word string) string {
+	var b strings.Builder
+	for _, c := range word {
+		if strings.ContainsRune(specialChars, c) {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(c)
+	}
+
+	return b.String()
+}
+
+// Экранирует имя внутри открытой кавычки quote так же, как quoteWord вне
+// кавычек
+func quoteIn(quote rune, word string) string {
+	switch quote {
+	case '\'':
+		return strings.ReplaceAll(word, "'", `'\''`)
+	case '"':
+		var b strings.Builder
+		for _, c := range word {
+			if strings.ContainsRune("$`\"\\", c) {
+				b.WriteByte('\\')
+			}
+			b.WriteRune(c)
+		}
+		return b.String()
+	default:
+		return quoteWord(word)
+	}
+}
+
+// Имена builtin и исполняемых файлов из $PATH с началом prefix. Алиасов в
+// шелле нет, поэтому ими первое слово не дополняется
+func commandCompletions(prefix string) []editor.Completion {
+	names := make(map[string]bool)
+	for name := range builtinMap {
+		if strings.HasPrefix(name, prefix) {
+			names[name] = true
+		}
+	}
+
+	pathEnv, _ := shell.lookup("PATH")
+	for _, dir := range filepath.SplitList(pathEnv) {
+		if dir == "" {
+			dir = "."
+		}
+
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+
+		for _, entry := range entries {
+			name := entry.Name()
+			if names[name] || !strings.HasPrefix(name, prefix) {
+				continue
+			}
+
+			// Stat идет по символическим ссылкам, как поиск в PATH
+			info, err := os.Stat(filepath.Join(dir, name))
+			if err != nil || info.IsDir() || info.Mode()&0o111 == 0 {
+				continue
+			}
+			names[name] = true
+		}
+	}
+
+	completions := make([]editor.Completion, 0, len(names))
+	for name := range names {
+		completions = append(completions, editor.Completion{Text: quoteWord(name) + " ", Display: name})
+	}
+	sort.Slice(completions, func(i, j int) bool {
+		return completions[i].Display < completions[j].Display
+	})
+
+	return completions
+}
+
+// Файлы и каталоги для набранного слова word. Каталог из слова остается как
+// набран, с кавычками и параметрами вроде $HOME/, а экранируется только
+// дополненное имя. Каталог дополняется /, чтобы можно было продолжить путь,
+// файл — закрывающей кавычкой и пробелом. Скрытые файлы предлагаются, только
+// если имя начинается с точки. Тильду шелл не раскрывает, поэтому ~/ — это
+// каталог с именем ~
+func pathCompletions(word string) []editor.Completion {
+	dir, base := "", unquoteWord(word)
+	if i := strings.LastIndex(base, "/"); i >= 0 {
+		base = base[i+1:]
+	}
+	if i := strings.LastIndex(word, "/"); i >= 0 {
+		dir = word[:i+1]
+	}
+	_, quote := scanWord(dir)
+
+	readDir := "."
+	if dir != "" {
+		var ok bool
+		if readDir, ok = expandDir(dir, quote); !ok {
+			return nil
+		}
+	}
+
+	entries, err := os.ReadDir(readDir)
+	if err != nil {
+		return nil
+	}
+
+	var completions []editor.Completion
+	for _, entry := range entries {
+		name := entry.Name()
+		if !strings.HasPrefix(name, base) || (strings.HasPrefix(name, ".") && !strings.HasPrefix(base, ".")) {
+			continue
+		}
+
+		isDir := entry.IsDir()
+		if entry.Type()&os.ModeSymlink != 0 {
+			info, err := os.Stat(filepath.Join(readDir, name))
+			isDir = err == nil && info.IsDir()
+		}
+
+		completion := editor.Completion{Text: dir + quoteIn(quote, name), Display: name}
+		if isDir {
+			completion.Text += "/"
+			completion.Display += "/"
+		} else {
+			if quote != 0 {
+				completion.Text += string(quote)
+			}
+			completion.Text += " "
+		}
+		completions = append(completions, completion)
+	}
+
+	return completions
+}
+
+// Раскрывает каталог, набранный в начале слова, как шелл раскроет все слово.
+// Открытая кавычка закрывается, чтобы каталог разобрался отдельно
+func expandDir(dir string, quote rune) (string, bool) {
+	if quote != 0 {
+		dir += string(quote)
+	}
+
+	tokens, err := parser.Lex(dir)
+	if err != nil || len(tokens) != 2 || tokens[0].Kind != parser.Word {
+		return "", false
+	}
+
+	return tokens[0].Expr.String(shell.lookup), true
+}
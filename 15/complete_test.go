@@ -0,0 +1,174 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"wb_l2/15/editor"
+)
+
+func TestWordAt(t *testing.T) {
+	tests := []struct {
+		line    string
+		start   int
+		command bool
+	}{
+		// место имени команды
+		{"", 0, true},
+		{"ec", 0, true},
+		{"  ec", 2, true},
+		{"ls | gr", 5, true},
+		{"a && b; c", 8, true},
+		{"sleep 1 & j", 10, true},
+		{"A=1 B=2 ec", 8, true},
+		{"(ec", 1, true},
+
+		// аргументы
+		{"ls ", 3, false},
+		{"ls -l fi", 6, false},
+		{"cat <in", 5, false},
+		{"echo >", 6, false},
+		{"echo A=1 x", 9, false},
+		{"A=1", 0, true},
+
+		// кавычки и экранирование не разделяют слова
+		{`cat a\ b`, 4, false},
+		{`cat "a b`, 4, false},
+		{`cat 'a | b`, 4, false},
+		{`cat "a \" b`, 4, false},
+	}
+
+	for _, tt := range tests {
+		line := []rune(tt.line)
+		start, command := wordAt(line, len(line))
+		if start != tt.start || command != tt.command {
+			t.Errorf("wordAt(%q) = %d, %v, want %d, %v", tt.line, start, command, tt.start, tt.command)
+		}
+	}
+}
+
+func TestQuoteWord(t *testing.T) {
+	tests := []struct {
+		word   string
+		quoted string
+	}{
+		{"plain.txt", "plain.txt"},
+		{"a b", `a\ b`},
+		{"it's", `it\'s`},
+		{`a"b$c`, `a\"b\$c`},
+		{"x|y;z&", `x\|y\;z\&`},
+		{"*?[]", `\*\?\[\]`},
+		{"файл 1", `файл\ 1`},
+	}
+
+	for _, tt := range tests {
+		if got := quoteWord(tt.word); got != tt.quoted {
+			t.Errorf("quoteWord(%q) = %q, want %q", tt.word, got, tt.quoted)
+		}
+		// дополненное слово читается обратно как имя файла
+		if got := unquoteWord(tt.quoted); got != tt.word {
+			t.Errorf("unquoteWord(%q) = %q, want %q", tt.quoted, got, tt.word)
+		}
+	}
+}
+
+func TestUnquoteWord(t *testing.T) {
+	tests := []struct {
+		word string
+		want string
+	}{
+		{`"a b`, "a b"},
+		{`'a\b`, `a\b`},
+		{`"a\"b"c`, `a"bc`},
+		{`a\`, "a"},
+		{`'it'\''s'`, "it's"},
+	}
+
+	for _, tt := range tests {
+		if got := unquoteWord(tt.word); got != tt.want {
+			t.Errorf("unquoteWord(%q) = %q, want %q", tt.word, got, tt.want)
+		}
+	}
+}
+
+func TestPathCompletions(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"my file.txt", "my dir", ".hidden", "other"} {
+		path := filepath.Join(dir, name)
+		var err error
+		if name == "my dir" {
+			err = os.Mkdir(path, 0o755)
+		} else {
+			err = os.WriteFile(path, nil, 0o644)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// каталог из переменной шелла
+	prev := shell
+	shell = &shellState{env: map[string]string{"D": dir}}
+	t.Cleanup(func() { shell = prev })
+
+	prefix := quoteWord(dir) + "/"
+	tests := []struct {
+		line string
+		want []editor.Completion
+	}{
+		{"cat " + prefix + "my", []editor.Completion{
+			{Text: prefix + `my\ dir/`, Display: "my dir/"},
+			{Text: prefix + `my\ file.txt `, Display: "my file.txt"},
+		}},
+		// скрытые файлы — только с точкой в начале
+		{"cat " + prefix + ".h", []editor.Completion{{Text: prefix + ".hidden ", Display: ".hidden"}}},
+		{"cat " + prefix + "o", []editor.Completion{{Text: prefix + "other ", Display: "other"}}},
+		// слово с экранированным пробелом
+		{"cat " + prefix + `my\ f`, []editor.Completion{{Text: prefix + `my\ file.txt `, Display: "my file.txt"}}},
+		{"cat " + prefix + "none", nil},
+		// каталог остается как набран, экранируется только имя
+		{`cat $D/my\ f`, []editor.Completion{{Text: `$D/my\ file.txt `, Display: "my file.txt"}}},
+		{"cat ${D}/my", []editor.Completion{
+			{Text: `${D}/my\ dir/`, Display: "my dir/"},
+			{Text: `${D}/my\ file.txt `, Display: "my file.txt"},
+		}},
+		// открытая в каталоге кавычка закрывается после имени файла
+		{`cat "$D/my f`, []editor.Completion{{Text: `"$D/my file.txt" `, Display: "my file.txt"}}},
+		{`cat "$D/my d`, []editor.Completion{{Text: `"$D/my dir/`, Display: "my dir/"}}},
+		{`cat '` + dir + `/o`, []editor.Completion{{Text: `'` + dir + `/other' `, Display: "other"}}},
+	}
+
+	for _, tt := range tests {
+		line := []rune(tt.line)
+		start, got := completeLine(line, len(line))
+		if start != 4 || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("completeLine(%q) = %d, %q, want 4, %q", tt.line, start, got, tt.want)
+		}
+	}
+}
+
+func TestCommandCompletions(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "jobrunner"), nil, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "jobdata"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	// PATH берется из окружения шелла
+	prev := shell
+	shell = &shellState{env: map[string]string{"PATH": dir}}
+	t.Cleanup(func() { shell = prev })
+
+	// builtin и исполняемые файлы из PATH, но не обычные файлы
+	line := []rune("jo")
+	_, got := completeLine(line, len(line))
+	want := []editor.Completion{
+		{Text: "jobrunner ", Display: "jobrunner"},
+		{Text: "jobs ", Display: "jobs"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("completeLine(%q) = %q, want %q", "jo", got, want)
+	}
+}
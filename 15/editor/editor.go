@@ -0,0 +1,469 @@
+// Package editor читает строку с терминала в raw-режиме: движение курсора,
+// удаление слов и строк как в readline, листание истории, обратный поиск по
+// ней и дополнение по Tab
+package editor
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/sys/unix"
+)
+
+// ErrInterrupt — строка прервана Ctrl+C
+var ErrInterrupt = errors.New("interrupt")
+
+// Клавиши из escape-последовательностей, отрицательные, чтобы не пересекаться
+// с символами
+const (
+	keyUp rune = -(iota + 1)
+	keyDown
+	keyRight
+	keyLeft
+	keyHome
+	keyEnd
+	keyDelete
+	keyUnknown
+)
+
+// ctrl возвращает код клавиши Ctrl+c
+func ctrl(c byte) rune {
+	return rune(c & 0x1f)
+}
+
+// Больше стольких вариантов дополнения выводятся только после подтверждения
+const completionQueryItems = 100
+
+// Completion — вариант дополнения слова под курсором
+type Completion struct {
+	// Text заменяет слово целиком, с экранированием и пробелом или / в конце
+	Text string
+	// Display — вариант в списке по двойному Tab
+	Display string
+}
+
+// Completer возвращает начало слова под курсором в line[:pos] и варианты его
+// дополнения
+type Completer func(line []rune, pos int) (start int, completions []Completion)
+
+// Editor читает строки с терминала in и рисует их в out
+type Editor struct {
+	// Complete дополняет слово по Tab, без него Tab ничего не делает
+	Complete Completer
+
+	fd  int
+	in  *bufio.Reader
+	out io.Writer
+	// Режим терминала при создании, его получают команды между строками
+	cooked  unix.Termios
+	history *History
+	// Последний текст, удаленный Ctrl+K, Ctrl+U или Ctrl+W, для Ctrl+Y
+	killed []rune
+}
+
+// New создает редактор для терминала in. history может быть nil
+func New(in *os.File, out io.Writer, history *History) (*Editor, error) {
+	fd := int(in.Fd())
+	termios, err := unix.IoctlGetTermios(fd, unix.TCGETS)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Editor{fd: fd, in: bufio.NewReader(in), out: out, cooked: *termios, history: history}, nil
+}
+
+// Строка, которую редактирует пользователь
+type line struct {
+	prompt string
+	buf    []rune
+	pos    int
+	// Позиция в истории, len(entries) — новая строка
+	hist    int
+	entries []string
+	// Новая строка, пока пользователь листает историю
+	draft []rune
+}
+
+// ReadLine печатает приглашение и читает строку без перевода строки. Ctrl+D
+// на пустой строке возвращает io.EOF, Ctrl+C — ErrInterrupt
+func (e *Editor) ReadLine(prompt string) (string, error) {
+	raw := e.cooked
+	raw.Iflag &^= unix.ICRNL | unix.IXON | unix.BRKINT | unix.INPCK | unix.ISTRIP
+	raw.Lflag &^= unix.ECHO | unix.ICANON | unix.ISIG | unix.IEXTEN
+	raw.Cc[unix.VMIN] = 1
+	raw.Cc[unix.VTIME] = 0
+	if err := unix.IoctlSetTermios(e.fd, unix.TCSETSW, &raw); err != nil {
+		return "", err
+	}
+	defer unix.IoctlSetTermios(e.fd, unix.TCSETSW, &e.cooked)
+
+	l := &line{prompt: prompt}
+	if e.history != nil {
+		_, l.entries = e.history.Entries()
+	}
+	l.hist = len(l.entries)
+
+	// Второй Tab подряд после неоднозначного дополнения выводит варианты
+	ambiguous := false
+
+	e.refresh(l)
+	for {
+		key, err := e.readKey()
+		if err != nil {
+			return "", err
+		}
+
+		if key == '\t' && e.Complete != nil {
+			if ambiguous, err = e.complete(l, ambiguous); err != nil {
+				return "", err
+			}
+			e.refresh(l)
+			continue
+		}
+		ambiguous = false
+
+		if key == ctrl('R') {
+			if key, err = e.search(l); err != nil {
+				return "", err
+			}
+		}
+
+		switch key {
+		case '\r', '\n':
+			l.pos = len(l.buf)
+			e.refresh(l)
+			fmt.Fprint(e.out, "\n")
+			return string(l.buf), nil
+		case ctrl('C'):
+			fmt.Fprint(e.out, "^C\n")
+			return "", ErrInterrupt
+		case ctrl('D'):
+			if len(l.buf) == 0 {
+				return "", io.EOF
+			}
+			l.delete(l.pos, l.pos+1)
+		case 0x7f, ctrl('H'):
+			l.delete(l.pos-1, l.pos)
+		case keyDelete:
+			l.delete(l.pos, l.pos+1)
+		case ctrl('A'), keyHome:
+			l.pos = 0
+		case ctrl('E'), keyEnd:
+			l.pos = len(l.buf)
+		case ctrl('B'), keyLeft:
+			l.pos = max(l.pos-1, 0)
+		case ctrl('F'), keyRight:
+			l.pos = min(l.pos+1, len(l.buf))
+		case ctrl('K'):
+			e.kill(l, l.pos, len(l.buf))
+		case ctrl('U'):
+			e.kill(l, 0, l.pos)
+		case ctrl('W'):
+			e.kill(l, l.wordStart(), l.pos)
+		case ctrl('Y'):
+			l.insert(e.killed...)
+		case ctrl('P'), keyUp:
+			l.move(-1)
+		case ctrl('N'), keyDown:
+			l.move(1)
+		case ctrl('L'):
+			fmt.Fprint(e.out, "\x1b[H\x1b[2J")
+		default:
+			if key >= ' ' && key != 0x7f {
+				l.insert(key)
+			}
+		}
+
+		e.refresh(l)
+	}
+}
+
+// Перерисовывает строку целиком и ставит курсор на место
+func (e *Editor) refresh(l *line) {
+	var b strings.Builder
+	b.WriteString("\r")
+	b.WriteString(l.prompt)
+	b.WriteString(string(l.buf))
+	b.WriteString("\x1b[K")
+	if n := len(l.buf) - l.pos; n > 0 {
+		fmt.Fprintf(&b, "\x1b[%dD", n)
+	}
+
+	io.WriteString(e.out, b.String())
+}
+
+// Читает клавишу: символ или разобранную escape-последовательность
+func (e *Editor) readKey() (rune, error) {
+	r, _, err := e.in.ReadRune()
+	if err != nil || r != 0x1b {
+		return r, err
+	}
+
+	b, err := e.in.ReadByte()
+	if err != nil {
+		return 0, err
+	}
+	if b != '[' && b != 'O' {
+		return keyUnknown, nil
+	}
+
+	// Параметры последовательности идут до финального байта
+	var seq []byte
+	for {
+		c, err := e.in.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+
+		seq = append(seq, c)
+		if c >= 0x40 && c <= 0x7e {
+			break
+		}
+	}
+
+	switch string(seq) {
+	case "A":
+		return keyUp, nil
+	case "B":
+		return keyDown, nil
+	case "C":
+		return keyRight, nil
+	case "D":
+		return keyLeft, nil
+	case "H", "1~", "7~":
+		return keyHome, nil
+	case "F", "4~", "8~":
+		return keyEnd, nil
+	case "3~":
+		return keyDelete, nil
+	default:
+		return keyUnknown, nil
+	}
+}
+
+// Обратный поиск по истории, как Ctrl+R в readline. Enter и другие
+// управляющие клавиши оставляют найденную строку и возвращаются в обычный
+// режим, Ctrl+G и Ctrl+C возвращают строку, которая была до поиска
+func (e *Editor) search(l *line) (rune, error) {
+	origBuf, origPos, origHist := l.buf, l.pos, l.hist
+
+	var query []rune
+	match := l.hist
+	failed := false
+
+	// Ищет query в строках истории с from вниз
+	find := func(from int) {
+		for i := min(from, len(l.entries)-1); i >= 0; i-- {
+			if len(query) > 0 && strings.Contains(l.entries[i], string(query)) {
+				match, failed = i, false
+				return
+			}
+		}
+		failed = len(query) > 0
+	}
+
+	for {
+		status := "reverse-i-search"
+		if failed {
+			status = "failed reverse-i-search"
+		}
+		found := ""
+		if match < len(l.entries) {
+			found = l.entries[match]
+		}
+		fmt.Fprintf(e.out, "\r(%s)`%s': %s\x1b[K", status, string(query), found)
+
+		key, err := e.readKey()
+		if err != nil {
+			return 0, err
+		}
+
+		switch {
+		case key == ctrl('R'):
+			find(match - 1)
+		case key == 0x7f || key == ctrl('H'):
+			if len(query) > 0 {
+				query = query[:len(query)-1]
+				find(len(l.entries) - 1)
+			}
+		case key == ctrl('G') || key == ctrl('C'):
+			l.buf, l.pos, l.hist = origBuf, origPos, origHist
+			return keyUnknown, nil
+		case key >= ' ' && key != 0x7f:
+			query = append(query, key)
+			find(match)
+		default:
+			if match < len(l.entries) {
+				l.buf = []rune(l.entries[match])
+				l.pos = len(l.buf)
+				l.hist = match
+			}
+			return key, nil
+		}
+	}
+}
+
+// Дополняет слово под курсором. Возвращает true, если вариантов несколько и
+// общее начало уже введено, тогда при list они выводятся списком
+func (e *Editor) complete(l *line, list bool) (bool, error) {
+	start, completions := e.Complete(l.buf, l.pos)
+	switch len(completions) {
+	case 0:
+		fmt.Fprint(e.out, "\a")
+		return false, nil
+	case 1:
+		l.replace(start, completions[0].Text)
+		return false, nil
+	}
+
+	prefix := completions[0].Text
+	for _, c := range completions[1:] {
+		n := 0
+		for n < len(prefix) && n < len(c.Text) && prefix[n] == c.Text[n] {
+			n++
+		}
+		// Общее начало не должно обрывать многобайтный символ
+		for n > 0 && n < len(prefix) && !utf8.RuneStart(prefix[n]) {
+			n--
+		}
+		prefix = prefix[:n]
+	}
+
+	if utf8.RuneCountInString(prefix) > l.pos-start {
+		l.replace(start, prefix)
+		return false, nil
+	}
+
+	if !list {
+		fmt.Fprint(e.out, "\a")
+		return true, nil
+	}
+
+	return false, e.list(completions)
+}
+
+// Выводит варианты дополнения под строкой по столбцам, как ls: сверху вниз,
+// затем слева направо
+func (e *Editor) list(completions []Completion) error {
+	if len(completions) > completionQueryItems {
+		fmt.Fprintf(e.out, "\nDisplay all %d possibilities? (y or n)", len(completions))
+		key, err := e.readKey()
+		if err != nil {
+			return err
+		}
+		if key != 'y' && key != 'Y' {
+			fmt.Fprint(e.out, "\n")
+			return nil
+		}
+	}
+
+	width := 80
+	if ws, err := unix.IoctlGetWinsize(e.fd, unix.TIOCGWINSZ); err == nil && ws.Col > 0 {
+		width = int(ws.Col)
+	}
+
+	colWidth := 0
+	for _, c := range completions {
+		colWidth = max(colWidth, utf8.RuneCountInString(c.Display)+2)
+	}
+
+	cols := max(width/colWidth, 1)
+	rows := (len(completions) + cols - 1) / cols
+
+	var b strings.Builder
+	b.WriteString("\n")
+	for row := 0; row < rows; row++ {
+		for col := 0; col < cols; col++ {
+			i := col*rows + row
+			if i >= len(completions) {
+				break
+			}
+
+			b.WriteString(completions[i].Display)
+			if i+rows < len(completions) {
+				b.WriteString(strings.Repeat(" ", colWidth-utf8.RuneCountInString(completions[i].Display)))
+			}
+		}
+		b.WriteString("\n")
+	}
+
+	_, err := io.WriteString(e.out, b.String())
+	return err
+}
+
+func (e *Editor) kill(l *line, from, to int) {
+	if from < to {
+		e.killed = append([]rune(nil), l.buf[from:to]...)
+		l.delete(from, to)
+	}
+}
+
+func (l *line) insert(r ...rune) {
+	buf := make([]rune, 0, len(l.buf)+len(r))
+	buf = append(buf, l.buf[:l.pos]...)
+	buf = append(buf, r...)
+	l.buf = append(buf, l.buf[l.pos:]...)
+	l.pos += len(r)
+}
+
+// Заменяет текст от start до курсора
+func (l *line) replace(start int, text string) {
+	l.delete(start, l.pos)
+	l.insert([]rune(text)...)
+}
+
+// Удаляет символы с from по to, границы обрезаются по строке
+func (l *line) delete(from, to int) {
+	from, to = max(from, 0), min(to, len(l.buf))
+	if from >= to {
+		return
+	}
+
+	l.buf = append(l.buf[:from], l.buf[to:]...)
+	if l.pos > to {
+		l.pos -= to - from
+	} else if l.pos > from {
+		l.pos = from
+	}
+}
+
+// Возвращает начало слова перед курсором для Ctrl+W: пробелы перед курсором
+// и слово до них
+func (l *line) wordStart() int {
+	i := l.pos
+	for i > 0 && unicode.IsSpace(l.buf[i-1]) {
+		i--
+	}
+	for i > 0 && !unicode.IsSpace(l.buf[i-1]) {
+		i--
+	}
+
+	return i
+}
+
+// Листает историю на delta строк, новая строка запоминается и возвращается
+// после самой свежей строки истории
+func (l *line) move(delta int) {
+	hist := l.hist + delta
+	if hist < 0 || hist > len(l.entries) {
+		return
+	}
+
+	if l.hist == len(l.entries) {
+		l.draft = l.buf
+	}
+
+	l.hist = hist
+	if hist == len(l.entries) {
+		l.buf = l.draft
+	} else {
+		l.buf = []rune(l.entries[hist])
+	}
+	l.pos = len(l.buf)
+}
@@ -0,0 +1,202 @@
+package editor
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// History — введенные строки, старые первыми. Номера строк для !n и
+// history идут через всю историю, включая прочитанную из файла, и не
+// меняются, когда старые строки вытесняются
+type History struct {
+	mu      sync.Mutex
+	entries []string
+	// base — номер первой строки в entries минус 1
+	base int
+	size int
+	path string
+}
+
+// NewHistory читает историю из файла path, если он есть, и хранит не
+// больше size строк. Пустой path — история без файла
+func NewHistory(path string, size int) (*History, error) {
+	h := &History{size: size, path: path}
+	if path == "" {
+		return h, nil
+	}
+
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return h, nil
+	}
+	if err != nil {
+		return h, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if line := scanner.Text(); line != "" {
+			h.entries = append(h.entries, line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return h, err
+	}
+
+	// Файл дописывается на каждой строке, поэтому обрезается при чтении
+	if len(h.entries) > size {
+		h.trim()
+		return h, h.save()
+	}
+
+	return h, nil
+}
+
+// Resize меняет число хранимых строк, лишние старые строки вытесняются
+func (h *History) Resize(size int) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.size = size
+	h.trim()
+}
+
+func (h *History) trim() {
+	if n := len(h.entries) - max(h.size, 0); n > 0 {
+		h.entries = append([]string(nil), h.entries[n:]...)
+		h.base += n
+	}
+}
+
+// Add добавляет строку в историю и дописывает ее в файл
+func (h *History) Add(line string) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if line == "" || h.size <= 0 {
+		return nil
+	}
+
+	h.entries = append(h.entries, line)
+	h.trim()
+
+	if h.path == "" {
+		return nil
+	}
+
+	f, err := os.OpenFile(h.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = fmt.Fprintln(f, line)
+	return err
+}
+
+// Clear очищает историю вместе с файлом
+func (h *History) Clear() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.base += len(h.entries)
+	h.entries = nil
+	if h.path == "" {
+		return nil
+	}
+
+	return h.save()
+}
+
+func (h *History) save() error {
+	var b strings.Builder
+	for _, line := range h.entries {
+		b.WriteString(line)
+		b.WriteByte('\n')
+	}
+
+	return os.WriteFile(h.path, []byte(b.String()), 0o600)
+}
+
+// Entries возвращает копию строк и номер первой из них
+func (h *History) Entries() (int, []string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return h.base + 1, append([]string(nil), h.entries...)
+}
+
+// Expand раскрывает ссылки на историю, как sh: !! — предыдущая строка, !n —
+// строка с номером n, !-n — n-я с конца. В одинарных кавычках, после \ и
+// перед пробелом или = восклицательный знак остается как есть
+func (h *History) Expand(line string) (string, error) {
+	if !strings.Contains(line, "!") {
+		return line, nil
+	}
+
+	first, entries := h.Entries()
+
+	var b strings.Builder
+	quoted := false
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case c == '\'':
+			quoted = !quoted
+		case c == '\\' && !quoted && i+1 < len(line):
+			b.WriteByte(c)
+			i++
+			c = line[i]
+		case c == '!' && !quoted && i+1 < len(line) && !strings.ContainsRune(" \t=(", rune(line[i+1])):
+			n, ref := historyRef(line[i+1:])
+			if n == 0 {
+				break
+			}
+
+			event := len(entries) + n
+			if n > 0 {
+				event = n - first
+			}
+			if event < 0 || event >= len(entries) {
+				return "", fmt.Errorf("!%s: event not found", ref)
+			}
+
+			b.WriteString(entries[event])
+			i += len(ref)
+			continue
+		}
+
+		b.WriteByte(c)
+	}
+
+	return b.String(), nil
+}
+
+// historyRef разбирает ссылку после !: номер строки, отрицательный — с
+// конца, 0 — ссылки нет. Вторым значением возвращает текст ссылки
+func historyRef(s string) (int, string) {
+	if strings.HasPrefix(s, "!") {
+		return -1, "!"
+	}
+
+	end := 0
+	if strings.HasPrefix(s, "-") {
+		end++
+	}
+	for end < len(s) && s[end] >= '0' && s[end] <= '9' {
+		end++
+	}
+
+	n, err := strconv.Atoi(s[:end])
+	if err != nil {
+		return 0, ""
+	}
+
+	return n, s[:end]
+}
@@ -0,0 +1,142 @@
+package editor
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// Возвращает историю без файла со строками lines
+func newTestHistory(t *testing.T, lines ...string) *History {
+	t.Helper()
+
+	h, err := NewHistory("", 100)
+	if err != nil {
+		t.Fatalf("NewHistory error = %v", err)
+	}
+	for _, line := range lines {
+		if err := h.Add(line); err != nil {
+			t.Fatalf("Add(%q) error = %v", line, err)
+		}
+	}
+
+	return h
+}
+
+func TestHistoryExpand(t *testing.T) {
+	h := newTestHistory(t, "echo one", "ls -l", "echo three")
+
+	tests := []struct {
+		line    string
+		want    string
+		wantErr bool
+	}{
+		// ссылки
+		{"!!", "echo three", false},
+		{"sudo !!", "sudo echo three", false},
+		{"!1", "echo one", false},
+		{"!2 | wc", "ls -l | wc", false},
+		{"!-1", "echo three", false},
+		{"!-3", "echo one", false},
+		{"!!; !1", "echo three; echo one", false},
+
+		// восклицательный знак как есть
+		{"echo hi!", "echo hi!", false},
+		{"echo ! x", "echo ! x", false},
+		{"a!=b", "a!=b", false},
+		{"echo '!!'", "echo '!!'", false},
+		{`echo \!!`, `echo \!!`, false},
+		{"echo !x", "echo !x", false},
+		{"no refs", "no refs", false},
+
+		// нет такой строки
+		{"!4", "", true},
+		{"!-4", "", true},
+	}
+
+	for _, tt := range tests {
+		got, err := h.Expand(tt.line)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("Expand(%q) error = %v, wantErr = %v", tt.line, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("Expand(%q) = %q, want %q", tt.line, got, tt.want)
+		}
+	}
+
+	if _, err := newTestHistory(t).Expand("!!"); err == nil || err.Error() != "!!: event not found" {
+		t.Errorf("Expand(%q) on empty history error = %v", "!!", err)
+	}
+}
+
+func TestHistoryRef(t *testing.T) {
+	tests := []struct {
+		s   string
+		n   int
+		ref string
+	}{
+		{"!", -1, "!"},
+		{"!x", -1, "!"},
+		{"12 rest", 12, "12"},
+		{"-2x", -2, "-2"},
+		{"x", 0, ""},
+		{"-", 0, ""},
+		{"", 0, ""},
+	}
+
+	for _, tt := range tests {
+		n, ref := historyRef(tt.s)
+		if n != tt.n || ref != tt.ref {
+			t.Errorf("historyRef(%q) = %d, %q, want %d, %q", tt.s, n, ref, tt.n, tt.ref)
+		}
+	}
+}
+
+func TestHistoryNumbering(t *testing.T) {
+	h := newTestHistory(t, "a", "b", "c")
+	h.Resize(2)
+
+	// номера не меняются, когда старые строки вытесняются
+	first, entries := h.Entries()
+	if first != 2 || !reflect.DeepEqual(entries, []string{"b", "c"}) {
+		t.Errorf("Entries() = %d, %q, want 2, [b c]", first, entries)
+	}
+	if got, _ := h.Expand("!3"); got != "c" {
+		t.Errorf("Expand(%q) = %q, want %q", "!3", got, "c")
+	}
+	if _, err := h.Expand("!1"); err == nil {
+		t.Errorf("Expand(%q) of an evicted line error = nil", "!1")
+	}
+
+	if err := h.Clear(); err != nil {
+		t.Fatalf("Clear error = %v", err)
+	}
+	h.Add("d")
+	if first, entries := h.Entries(); first != 4 || !reflect.DeepEqual(entries, []string{"d"}) {
+		t.Errorf("Entries() after Clear = %d, %q, want 4, [d]", first, entries)
+	}
+}
+
+func TestHistoryFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history")
+	if err := os.WriteFile(path, []byte("a\n\nb\nc\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	// лишние строки файла обрезаются при чтении
+	h, err := NewHistory(path, 2)
+	if err != nil {
+		t.Fatalf("NewHistory error = %v", err)
+	}
+	h.Add("d")
+
+	h, err = NewHistory(path, 10)
+	if err != nil {
+		t.Fatalf("NewHistory error = %v", err)
+	}
+	if _, entries := h.Entries(); !reflect.DeepEqual(entries, []string{"b", "c", "d"}) {
+		t.Errorf("Entries() from file = %q, want [b c d]", entries)
+	}
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"wb_l2/15/editor"
+)
+
+// Файл истории в домашнем каталоге и число строк в нем, если HISTSIZE не
+// задан
+const (
+	historyFile        = ".minishell_history"
+	defaultHistorySize = 1000
+)
+
+// История интерактивного шелла, nil, когда ввод не из терминала, как в sh
+var history *editor.History
+
+// Источник строк: редактор на терминале или простое чтение stdin
+type lineReader interface {
+	// ReadLine печатает приглашение и возвращает строку без перевода строки.
+	// Недочитанная строка в конце ввода возвращается вместе с io.EOF
+	ReadLine(prompt string) (string, error)
+}
+
+type plainReader struct {
+	r *bufio.Reader
+}
+
+func (p *plainReader) ReadLine(prompt string) (string, error) {
+	fmt.Fprint(os.Stdout, prompt)
+	line, err := p.r.ReadString('\n')
+	return strings.TrimSuffix(line, "\n"), err
+}
+
+// Возвращает редактор строк с историей из файла и дополнением, если stdin —
+// терминал
+func newLineReader() lineReader {
+	plain := &plainReader{r: bufio.NewReader(os.Stdin)}
+	if !terminal.interactive {
+		return plain
+	}
+
+	path := ""
+	if home, err := os.UserHomeDir(); err == nil {
+		path = filepath.Join(home, historyFile)
+	}
+
+	h, err := editor.NewHistory(path, historySize())
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "history:", err)
+	}
+
+	e, err := editor.New(os.Stdin, os.Stdout, h)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "editor:", err)
+		return plain
+	}
+
+	e.Complete = completeLine
+	history = h
+	return e
+}
+
+// Возвращает размер истории из HISTSIZE шелла или окружения
+func historySize() int {
+	if value, ok := shell.lookup("HISTSIZE"); ok {
+		if size, err := strconv.Atoi(value); err == nil && size >= 0 {
+			return size
+		}
+	}
+
+	return defaultHistorySize
+}
+
+// Раскрывает !! и !n и запоминает строку в истории. Раскрытую строку
+// печатает, как sh, чтобы было видно, что выполнится
+func recordHistory(line string) (string, error) {
+	if history == nil {
+		return line, nil
+	}
+
+	expanded, err := history.Expand(line)
+	if err != nil {
+		return "", err
+	}
+	if expanded != line {
+		fmt.Fprintln(os.Stdout, expanded)
+	}
+
+	history.Resize(historySize())
+	if err := history.Add(expanded); err != nil {
+		fmt.Fprintln(os.Stderr, "history:", err)
+	}
+
+	return expanded, nil
+}
+
+// history [-c | n] печатает историю с номерами, последние n строк или
+// очищает ее
+func builtinHistory(_ context.Context, _ *shellState, args []string, _ io.Reader, w io.Writer) error {
+	if history == nil {
+		return nil
+	}
+
+	first, entries := history.Entries()
+	if len(args) > 0 {
+		if args[0] == "-c" {
+			return history.Clear()
+		}
+
+		n, err := strconv.Atoi(args[0])
+		if err != nil || n < 0 {
+			return fmt.Errorf("history: %s: numeric argument required", args[0])
+		}
+		if n < len(entries) {
+			first += len(entries) - n
+			entries = entries[len(entries)-n:]
+		}
+	}
+
+	bw := bufio.NewWriter(w)
+	defer bw.Flush()
+
+	for i, line := range entries {
+		if _, err := fmt.Fprintf(bw, "%5d  %s\n", first+i, line); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Признак Ctrl+C в редакторе: строка отменена, код как у прерванной команды
+func interrupted(err error) bool {
+	return errors.Is(err, editor.ErrInterrupt)
+}
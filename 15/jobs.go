@@ -0,0 +1,637 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"wb_l2/15/parser"
+
+	"golang.org/x/sys/unix"
+)
+
+// Коды si_code из waitid для остановки и продолжения процесса,
+// x/sys/unix их не объявляет
+const (
+	cldStopped   = 5
+	cldContinued = 6
+)
+
+// Терминал шелла. Когда ввод идет из терминала, задание на переднем плане
+// получает его на время работы, а потом терминал возвращается группе шелла
+var terminal struct {
+	interactive bool
+	pgid        int
+}
+
+// Включает job control, если stdin — терминал
+func initTerminal() {
+	if _, err := unix.IoctlGetTermios(0, unix.TCGETS); err != nil {
+		return
+	}
+
+	terminal.interactive = true
+	terminal.pgid = syscall.Getpgrp()
+	setForeground(terminal.pgid)
+}
+
+// Отдает терминал группе процессов. Шелл в этот момент может быть не на
+// переднем плане, поэтому SIGTTOU на время вызова блокируется, иначе ядро
+// остановит сам шелл
+func setForeground(pgid int) {
+	if !terminal.interactive || pgid == 0 {
+		return
+	}
+
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	var mask, old unix.Sigset_t
+	mask.Val[0] = 1 << (uint(unix.SIGTTOU) - 1)
+	_ = unix.PthreadSigmask(unix.SIG_BLOCK, &mask, &old)
+	_ = unix.IoctlSetPointerInt(0, unix.TIOCSPGRP, pgid)
+	_ = unix.PthreadSigmask(unix.SIG_SETMASK, &old, nil)
+}
+
+// Состояние задания
+type jobState int
+
+const (
+	jobRunning jobState = iota
+	jobStopped
+	jobDone
+)
+
+// Задание — пайплайн переднего плана или фоновая цепочка && и ||. Процессы
+// текущего пайплайна задания живут в одной группе, ей шелл отдает терминал и
+// шлет сигналы
+type job struct {
+	// Номер в таблице заданий, 0 — задание не в таблице
+	id   int
+	text string
+	// Задание ведет горутина фоновой цепочки, она и завершает его после
+	// последнего пайплайна
+	chain bool
+	// Закрывается, когда фоновая цепочка запустила первый пайплайн и $!
+	// известен
+	started chan struct{}
+	// Контекст процессов задания, отменяется после завершения задания или по
+	// Ctrl+C
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	// Поля ниже меняются под jobs.mu
+	// Задание запущено через & или продолжено через bg
+	background bool
+	pgid       int
+	// Этапы текущего пайплайна задания
+	stages []*pipelineStage
+	state  jobState
+	// Код последнего завершенного пайплайна
+	status int
+	// Состояние, о котором пользователь уже знает
+	reported jobState
+	// Порядок для текущего (+) и предыдущего (-) заданий
+	touched int
+}
+
+func newJob(text string, background bool) *job {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &job{text: text, background: background, ctx: ctx, cancel: cancel}
+}
+
+// Таблица заданий. Горутины, которые ждут процессы, меняют состояние заданий
+// под mu и будят ждущих через cond
+type jobTable struct {
+	mu    sync.Mutex
+	cond  *sync.Cond
+	list  []*job
+	touch int
+}
+
+var jobs = newJobTable()
+
+func newJobTable() *jobTable {
+	t := &jobTable{}
+	t.cond = sync.NewCond(&t.mu)
+	return t
+}
+
+// Добавляет задание со следующим номером после последнего, как sh.
+// Вызывается под mu
+func (t *jobTable) add(j *job) {
+	j.id = 1
+	if len(t.list) > 0 {
+		j.id = t.list[len(t.list)-1].id + 1
+	}
+
+	t.list = append(t.list, j)
+	t.bump(j)
+}
+
+// Делает задание текущим для fg и bg без номера. Вызывается под mu
+func (t *jobTable) bump(j *job) {
+	t.touch++
+	j.touched = t.touch
+}
+
+// Вызывается под mu
+func (t *jobTable) remove(j *job) {
+	for i, other := range t.list {
+		if other == j {
+			t.list = append(t.list[:i], t.list[i+1:]...)
+			return
+		}
+	}
+}
+
+// Возвращает текущее и предыдущее задания. Вызывается под mu
+func (t *jobTable) recent() (cur, prev *job) {
+	for _, j := range t.list {
+		switch {
+		case cur == nil || j.touched > cur.touched:
+			cur, prev = j, cur
+		case prev == nil || j.touched > prev.touched:
+			prev = j
+		}
+	}
+
+	return cur, prev
+}
+
+// Находит задание по %n, %+, %%, %-, номеру или pid процесса. Пустая строка —
+// текущее задание. Вызывается под mu
+func (t *jobTable) find(spec string) (*job, error) {
+	cur, prev := t.recent()
+	switch spec {
+	case "", "%", "%%", "%+":
+		if cur == nil {
+			return nil, errors.New("current: no such job")
+		}
+		return cur, nil
+	case "%-":
+		if prev == nil {
+			return nil, errors.New("previous: no such job")
+		}
+		return prev, nil
+	}
+
+	n, err := strconv.Atoi(strings.TrimPrefix(spec, "%"))
+	if err != nil {
+		return nil, fmt.Errorf("%s: no such job", spec)
+	}
+
+	for _, j := range t.list {
+		if strings.HasPrefix(spec, "%") && j.id == n {
+			return j, nil
+		}
+		if !strings.HasPrefix(spec, "%") && j.hasPid(n) {
+			return j, nil
+		}
+	}
+
+	return nil, fmt.Errorf("%s: no such job", spec)
+}
+
+// Строка задания для jobs и уведомлений. Вызывается под mu
+func (t *jobTable) format(j *job) string {
+	marker := ' '
+	if cur, prev := t.recent(); j == cur {
+		marker = '+'
+	} else if j == prev {
+		marker = '-'
+	}
+
+	state, text := "Running", j.text
+	switch {
+	case j.state == jobStopped:
+		state = "Stopped"
+	case j.state == jobDone && j.status != 0:
+		state = fmt.Sprintf("Exit %d", j.status)
+	case j.state == jobDone:
+		state = "Done"
+	case j.background:
+		text += " &"
+	}
+
+	return fmt.Sprintf("[%d]%c  %-24s%s", j.id, marker, state, text)
+}
+
+// Возвращает строки о заданиях, которые завершились или остановились с
+// прошлого раза, и убирает завершенные из таблицы. С all возвращает строки
+// обо всех заданиях, как jobs
+func (t *jobTable) report(all bool) []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var lines []string
+	var done []*job
+	for _, j := range t.list {
+		if all || (j.state != jobRunning && j.state != j.reported) {
+			lines = append(lines, t.format(j))
+		}
+		j.reported = j.state
+
+		if j.state == jobDone {
+			done = append(done, j)
+		}
+	}
+
+	for _, j := range done {
+		t.remove(j)
+	}
+
+	return lines
+}
+
+// Выводит уведомления о заданиях перед приглашением
+func notifyJobs() {
+	for _, line := range jobs.report(false) {
+		fmt.Fprintln(os.Stderr, line)
+	}
+}
+
+func (j *job) hasPid(pid int) bool {
+	for _, s := range j.stages {
+		if s.cmd != nil && s.cmd.Process.Pid == pid {
+			return true
+		}
+	}
+
+	return j.pgid == pid && pid != 0
+}
+
+// Возвращает pid последнего процесса текущего пайплайна для $! или 0, если
+// в пайплайне одни builtin
+func (j *job) lastPid() int {
+	jobs.mu.Lock()
+	defer jobs.mu.Unlock()
+
+	for i := len(j.stages) - 1; i >= 0; i-- {
+		if s := j.stages[i]; s.cmd != nil {
+			return s.cmd.Process.Pid
+		}
+	}
+
+	return 0
+}
+
+// Пересчитывает состояние задания после изменения этапа: задание
+// остановлено, если остановлен хоть один его процесс. Вызывается под jobs.mu
+func (j *job) update() {
+	active, stopped := 0, 0
+	for _, s := range j.stages {
+		if !s.finished {
+			active++
+			if s.stopped {
+				stopped++
+			}
+		}
+	}
+
+	switch {
+	case active == 0:
+		j.status = j.stages[len(j.stages)-1].status
+		if !j.chain {
+			j.finish(j.status)
+		}
+	case stopped > 0:
+		j.state = jobStopped
+	default:
+		j.state = jobRunning
+	}
+
+	jobs.cond.Broadcast()
+}
+
+// Завершает задание с кодом. Вызывается под jobs.mu
+func (j *job) finish(status int) {
+	j.state = jobDone
+	j.status = status
+	j.cancel()
+	jobs.cond.Broadcast()
+}
+
+// Ждет, пока завершатся все этапы текущего пайплайна задания, остановки не в
+// счет. Так фоновая цепочка переходит к следующему пайплайну
+func (j *job) waitPipeline() int {
+	jobs.mu.Lock()
+	defer jobs.mu.Unlock()
+
+	for {
+		finished := true
+		for _, s := range j.stages {
+			finished = finished && s.finished
+		}
+		if finished {
+			return j.status
+		}
+
+		jobs.cond.Wait()
+	}
+}
+
+// Продолжает остановленное задание на переднем плане или в фоне
+func (j *job) resume(background bool) {
+	jobs.mu.Lock()
+	j.background = background
+	for _, s := range j.stages {
+		s.stopped = false
+	}
+	if j.state == jobStopped {
+		j.state = jobRunning
+	}
+	j.reported = j.state
+	jobs.bump(j)
+	pgid := j.pgid
+	jobs.mu.Unlock()
+
+	if !background {
+		setForeground(pgid)
+	}
+
+	if pgid != 0 {
+		_ = syscall.Kill(-pgid, syscall.SIGCONT)
+	}
+}
+
+// Ждет задание на переднем плане, пока оно не завершится или не
+// остановится, и возвращает терминал шеллу. Остановленное задание попадает в
+// таблицу, завершенное уходит из нее без уведомления
+func waitForeground(j *job) int {
+	current.mu.Lock()
+	prev := current.job
+	current.job = j
+	current.mu.Unlock()
+
+	defer func() {
+		current.mu.Lock()
+		current.job = prev
+		current.mu.Unlock()
+	}()
+
+	jobs.mu.Lock()
+	for j.state == jobRunning {
+		jobs.cond.Wait()
+	}
+
+	status, pgid := j.status, j.pgid
+	var line string
+	if j.state == jobStopped {
+		if j.id == 0 {
+			jobs.add(j)
+		} else {
+			jobs.bump(j)
+		}
+		j.reported = jobStopped
+		line = jobs.format(j)
+		status = 128 + int(syscall.SIGTSTP)
+	} else if j.id != 0 {
+		jobs.remove(j)
+	}
+	jobs.mu.Unlock()
+
+	setForeground(terminal.pgid)
+	switch {
+	case line != "":
+		fmt.Fprintf(os.Stderr, "\n%s\n", line)
+	case status == 128+int(syscall.SIGINT) && pgid != 0 && terminal.interactive:
+		// Приглашение после ^C начинается с новой строки. У fg своей группы
+		// нет, строку уже вывело продолженное задание
+		fmt.Fprintln(os.Stderr)
+	}
+
+	return status
+}
+
+// Ждет процесс этапа: остановки и продолжения отмечает в задании, а
+// завершившийся процесс забирает через cmd.Wait, чтобы exec дописал и закрыл
+// пайпы. WNOWAIT оставляет завершение для cmd.Wait
+func watchStage(j *job, s *pipelineStage) {
+	pid := s.cmd.Process.Pid
+	for {
+		var info unix.Siginfo
+		err := unix.Waitid(unix.P_PID, pid, &info, unix.WEXITED|unix.WSTOPPED|unix.WCONTINUED|unix.WNOWAIT, nil)
+		if errors.Is(err, unix.EINTR) {
+			continue
+		}
+		if err != nil || (info.Code != cldStopped && info.Code != cldContinued) {
+			break
+		}
+
+		// Снимаем уведомление об остановке или продолжении, иначе waitid
+		// вернет его снова
+		var consumed unix.Siginfo
+		_ = unix.Waitid(unix.P_PID, pid, &consumed, unix.WSTOPPED|unix.WCONTINUED|unix.WNOHANG, nil)
+
+		jobs.mu.Lock()
+		s.stopped = info.Code == cldStopped
+		j.update()
+		jobs.mu.Unlock()
+	}
+
+	status := exitStatus(s.cmd.Wait())
+	s.close()
+
+	jobs.mu.Lock()
+	s.finished, s.status = true, status
+	j.update()
+	jobs.mu.Unlock()
+}
+
+// Запускает цепочку в фоне с копией состояния шелла, как подоболочку sh, и
+// ждет только запуска ее первого пайплайна
+func startBackground(item *parser.AndOr) {
+	sh := shell.subshell()
+
+	j := newJob(item.Text, true)
+	j.chain = true
+	j.started = make(chan struct{})
+
+	jobs.mu.Lock()
+	jobs.add(j)
+	jobs.mu.Unlock()
+
+	go func() {
+		runAndOr(sh, item, j)
+
+		jobs.mu.Lock()
+		j.finish(sh.lastStatus)
+		jobs.mu.Unlock()
+	}()
+
+	<-j.started
+	pid := j.lastPid()
+	if pid != 0 {
+		shell.lastBackground = pid
+	}
+
+	if terminal.interactive {
+		fmt.Fprintf(os.Stderr, "[%d] %d\n", j.id, pid)
+	}
+}
+
+// Код завершения builtin без сообщения об ошибке, как код задания у fg и
+// wait
+type exitCode int
+
+func (c exitCode) Error() string {
+	return "exit status " + strconv.Itoa(int(c))
+}
+
+func statusError(status int) error {
+	if status == 0 {
+		return nil
+	}
+
+	return exitCode(status)
+}
+
+func builtinJobs(_ context.Context, _ *shellState, _ []string, _ io.Reader, w io.Writer) error {
+	for _, line := range jobs.report(true) {
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Находит задание для fg, bg или wait по первому аргументу
+func findJob(name string, args []string) (*job, error) {
+	spec := ""
+	if len(args) > 0 {
+		spec = args[0]
+	}
+
+	jobs.mu.Lock()
+	defer jobs.mu.Unlock()
+
+	j, err := jobs.find(spec)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", name, err)
+	}
+
+	if j.state == jobDone {
+		jobs.remove(j)
+		return nil, fmt.Errorf("%s: job has terminated", name)
+	}
+
+	return j, nil
+}
+
+// fg [%n] продолжает задание на переднем плане и ждет его, код fg — код
+// задания
+func builtinFg(_ context.Context, _ *shellState, args []string, _ io.Reader, w io.Writer) error {
+	j, err := findJob("fg", args)
+	if err != nil {
+		return err
+	}
+
+	if _, err := fmt.Fprintln(w, j.text); err != nil {
+		return err
+	}
+
+	j.resume(false)
+	return statusError(waitForeground(j))
+}
+
+// bg [%n] продолжает остановленное задание в фоне
+func builtinBg(_ context.Context, _ *shellState, args []string, _ io.Reader, w io.Writer) error {
+	j, err := findJob("bg", args)
+	if err != nil {
+		return err
+	}
+
+	jobs.mu.Lock()
+	running := j.state == jobRunning
+	jobs.mu.Unlock()
+	if running {
+		return fmt.Errorf("bg: job %d already in background", j.id)
+	}
+
+	j.resume(true)
+
+	jobs.mu.Lock()
+	line := jobs.format(j)
+	jobs.mu.Unlock()
+
+	_, err = fmt.Fprintln(w, line)
+	return err
+}
+
+// wait [%n | pid]... ждет фоновые задания, без аргументов — все работающие.
+// Код wait — код последнего задания, остановленное задание не ждется
+// бесконечно. Ctrl+C прерывает ожидание с кодом 130, задания продолжают
+// работать
+func builtinWait(ctx context.Context, _ *shellState, args []string, _ io.Reader, _ io.Writer) error {
+	jobs.mu.Lock()
+	defer jobs.mu.Unlock()
+
+	// Ctrl+C отменяет контекст задания самого wait, ждущего надо разбудить.
+	// Broadcast под mu, чтобы он не проскочил между проверкой и Wait
+	stop := context.AfterFunc(ctx, func() {
+		jobs.mu.Lock()
+		jobs.cond.Broadcast()
+		jobs.mu.Unlock()
+	})
+	defer stop()
+
+	var waited []*job
+	if len(args) == 0 {
+		for _, j := range jobs.list {
+			if j.state != jobStopped {
+				waited = append(waited, j)
+			}
+		}
+	}
+
+	var errs []error
+	for _, arg := range args {
+		j, err := jobs.find(arg)
+		if err != nil {
+			if !strings.HasPrefix(arg, "%") {
+				err = fmt.Errorf("pid %s is not a child of this shell", arg)
+			}
+			errs = append(errs, fmt.Errorf("wait: %v", err))
+			continue
+		}
+		waited = append(waited, j)
+	}
+
+	status := 0
+	for _, j := range waited {
+		for j.state == jobRunning {
+			if ctx.Err() != nil {
+				if terminal.interactive {
+					fmt.Fprintln(os.Stderr)
+				}
+				return exitCode(128 + int(syscall.SIGINT))
+			}
+			jobs.cond.Wait()
+		}
+
+		status = j.status
+		if j.state == jobStopped {
+			status = 128 + int(syscall.SIGTSTP)
+			continue
+		}
+		jobs.remove(j)
+	}
+
+	if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+
+	if len(args) == 0 {
+		return nil
+	}
+
+	return statusError(status)
+}
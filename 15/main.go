@@ -3,6 +3,7 @@ package main
 import (
 	"bufio"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"os"
@@ -12,27 +13,68 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"syscall"
+	"wb_l2/15/parser"
 )
 
-// Представляет состояние выполнения текущей программы
-type runningState struct {
-	cancel context.CancelFunc
-	pgid   int
+// Хранит задание на переднем плане. Ему шелл пересылает SIGINT и SIGTSTP,
+// которые пришли самому шеллу, а не группе задания через терминал
+var current struct {
+	mu  sync.Mutex
+	job *job
 }
 
-// Хранит текущее состояние терминала
-var current struct {
-	state runningState
+// Состояние шелла: переменные и значения специальных параметров.
+// Экспортированные переменные лежат в env, из него собирается окружение
+// внешних команд, остальные — в vars. Фоновая цепочка работает с копией,
+// как подоболочка sh, и не меняет ни переменные, ни каталог шелла
+type shellState struct {
+	// Защищает vars, env и dir, builtin меняют их из своих горутин
+	mu   sync.Mutex
+	vars map[string]string
+	env  map[string]string
+	// Рабочий каталог фоновой цепочки. Пустой у самого шелла, его каталог —
+	// каталог процесса
+	dir string
+	// Код завершения последнего пайплайна для $?
+	lastStatus int
+	// pid последнего фонового процесса для $!, 0 — его еще не было
+	lastBackground int
+}
+
+var shell = newShellState()
+
+// Возвращает состояние шелла с окружением процесса
+func newShellState() *shellState {
+	sh := &shellState{env: make(map[string]string)}
+	for _, kv := range os.Environ() {
+		name, value, _ := strings.Cut(kv, "=")
+		sh.env[name] = value
+	}
+
+	return sh
 }
 
+// builtin получает контекст своего задания и состояние шелла или фоновой
+// цепочки, в которой запущен
+type builtinFunc func(ctx context.Context, sh *shellState, args []string, r io.Reader, w io.Writer) error
+
 // builtin команды в мапе для удобства
-var builtinMap = map[string]func(args []string, r io.Reader, w io.Writer) error{
-	"cd":   builtinCd,
-	"pwd":  builtinPwd,
-	"echo": builtinEcho,
-	"kill": builtinKill,
-	"ps":   builtinPs,
+var builtinMap = map[string]builtinFunc{
+	"cd":      builtinCd,
+	"pwd":     builtinPwd,
+	"echo":    builtinEcho,
+	"kill":    builtinKill,
+	"ps":      builtinPs,
+	"export":  builtinExport,
+	"unset":   builtinUnset,
+	"env":     builtinEnv,
+	"jobs":    builtinJobs,
+	"fg":      builtinFg,
+	"bg":      builtinBg,
+	"wait":    builtinWait,
+	"history": builtinHistory,
 }
 
 // Представляет один из этапов пайплайна, который может быть либо builtin функцией,
@@ -43,29 +85,80 @@ type pipelineStage struct {
 	// Флаг, указывающий, является ли этап пайплайна builtin функцией
 	isBuiltin bool
 	// Функция builtin, которую нужно выполнить
-	builtin func(args []string, r io.Reader, w io.Writer) error
+	builtin builtinFunc
 	// Аргументы для builtin функции
 	args []string
 	// Входной поток для этапа
 	in io.Reader
 	// Выходной поток для этапа
 	out io.Writer
+	// Поток ошибок для этапа
+	errOut io.Writer
+	// Концы пайпов к соседним этапам. Закрываются после завершения этапа, даже
+	// если перенаправления заменили их файлами, чтобы соседи не зависли
+	pipeIn  *io.PipeReader
+	pipeOut *io.PipeWriter
+	// Файлы, открытые для перенаправлений этапа
+	files []*os.File
+	// Состояние шелла, в котором выполняется этап
+	sh *shellState
+	// Окружение внешней команды: экспортированные переменные и присваивания
+	// перед ней
+	env []string
 	// external command, которую нужно выполнить
 	cmd *exec.Cmd
-	// Канал для ожидания завершения этапа
-	doneCh chan error
+	// Состояние этапа, меняется под jobs.mu
+	stopped  bool
+	finished bool
+	status   int
 }
 
-// Возвращает слайс этапов пайплайна из разобранной команды
-func buildPipelineStages(segments [][]string) []*pipelineStage {
-	stages := make([]*pipelineStage, 0, len(segments))
-	for i, argv := range segments {
-		s := &pipelineStage{argv: argv}
+// Команда из одних перенаправлений только открывает файлы
+func builtinNoop(_ context.Context, _ *shellState, _ []string, _ io.Reader, _ io.Writer) error {
+	return nil
+}
+
+// Возвращает слайс этапов пайплайна из разобранных команд. Параметры в словах
+// раскрываются здесь, перед запуском, чтобы $? видел предыдущую команду.
+// Перенаправления применяются поверх пайпов, ошибка возвращается, если файл
+// не открылся
+func buildPipelineStages(sh *shellState, commands []*parser.Command, background bool) ([]*pipelineStage, error) {
+	stages := make([]*pipelineStage, 0, len(commands))
+	for i, command := range commands {
+		var argv []string
+		for _, arg := range command.Args {
+			argv = append(argv, arg.Expand(sh.lookup)...)
+		}
+
+		assigns := make([]string, 0, len(command.Assigns))
+		for _, assign := range command.Assigns {
+			assigns = append(assigns, assign.Name+"="+assign.Value.String(sh.lookup))
+		}
+
+		s := &pipelineStage{argv: argv, sh: sh, errOut: os.Stderr}
 		// Если команда является builtin, то устанавливаем флаг и функцию
-		if fn, ok := builtinMap[argv[0]]; ok {
+		if len(argv) == 0 {
+			s.isBuiltin = true
+			s.builtin = builtinNoop
+			// Присваивания без команды меняют переменные шелла, а в пайплайне
+			// теряются, как в подоболочке sh
+			if len(commands) == 1 {
+				for _, assign := range assigns {
+					name, value, _ := strings.Cut(assign, "=")
+					sh.set(name, value)
+				}
+			}
+		} else if fn, ok := builtinMap[argv[0]]; ok {
 			s.isBuiltin = true
 			s.builtin = fn
 			s.args = argv[1:]
+			// builtin работают в процессе шелла, присваивания перед ними видит
+			// только env, он принимает их как свои аргументы
+			if argv[0] == "env" {
+				s.args = append(assigns, s.args...)
+			}
+		} else {
+			s.env = append(sh.environ(), assigns...)
 		}
 
 		// Если это первый этап, то устанавливаем Stdin
@@ -74,7 +167,7 @@ func buildPipelineStages(segments [][]string) []*pipelineStage {
 		}
 
 		// Если это последний этап, то устанавливаем Stdout
-		if i == len(segments)-1 {
+		if i == len(commands)-1 {
 			s.out = os.Stdout
 		}
 
@@ -82,42 +175,210 @@ func buildPipelineStages(segments [][]string) []*pipelineStage {
 		if i > 0 {
 			pr, pw := io.Pipe()
 			stages[i-1].out = pw
+			stages[i-1].pipeOut = pw
 			s.in = pr
+			s.pipeIn = pr
 		}
 
 		stages = append(stages, s)
 	}
-	return stages
+
+	// Без терминала фоновое задание не читает ввод шелла, как в sh
+	if background && !terminal.interactive {
+		f, err := os.Open(os.DevNull)
+		if err != nil {
+			return nil, err
+		}
+		stages[0].in = f
+		stages[0].files = append(stages[0].files, f)
+	}
+
+	for i, s := range stages {
+		if err := s.redirect(commands[i].Redirects); err != nil {
+			for _, s := range stages {
+				s.close()
+			}
+			return nil, err
+		}
+	}
+
+	return stages, nil
+}
+
+// Применяет перенаправления по порядку записи, так что 2>&1 >file и
+// >file 2>&1 дают разный результат, как в sh
+func (s *pipelineStage) redirect(redirects []*parser.Redirect) error {
+	lookup := s.sh.lookup
+	for _, r := range redirects {
+		if err := checkFd(r); err != nil {
+			return err
+		}
+
+		var err error
+		switch r.Op {
+		case parser.RedirectIn:
+			var f *os.File
+			if f, err = s.open(r.Target.String(lookup), os.O_RDONLY); err == nil {
+				err = s.setInput(r.Fd, f)
+			}
+		case parser.RedirectOut, parser.RedirectAppend, parser.RedirectOutErr:
+			flag := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+			if r.Op == parser.RedirectAppend {
+				flag = os.O_WRONLY | os.O_CREATE | os.O_APPEND
+			}
+
+			var f *os.File
+			if f, err = s.open(r.Target.String(lookup), flag); err == nil {
+				err = s.setOutput(r.Fd, f)
+				if err == nil && r.Op == parser.RedirectOutErr {
+					err = s.setOutput(2, f)
+				}
+			}
+		case parser.RedirectDup:
+			var target io.Writer
+			if target, err = s.output(r.Target.String(lookup)); err == nil {
+				err = s.setOutput(r.Fd, target)
+			}
+		case parser.RedirectHereDoc:
+			// Тело раскрывается, только если разделитель без кавычек
+			body := r.Body
+			if !r.Quoted {
+				body, err = parser.ExpandHereDoc(body, lookup)
+			}
+			if err == nil {
+				err = s.setInput(r.Fd, strings.NewReader(body))
+			}
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Проверяет дескриптор перенаправления до открытия файла, чтобы 3>file не
+// создавал и не обрезал file
+func checkFd(r *parser.Redirect) error {
+	input := r.Op == parser.RedirectIn || r.Op == parser.RedirectHereDoc
+	if (input && r.Fd != 0) || (!input && r.Fd != 1 && r.Fd != 2) {
+		return fmt.Errorf("%d: bad file descriptor", r.Fd)
+	}
+
+	return nil
 }
 
-// Запускает builtin в горутинах или внешние команды пайплайна. Возвращает
-// первый process group id для пайплайна
-func startPipelineStages(ctx context.Context, stages []*pipelineStage) (int, error) {
+// Открывает файл перенаправления с правами 0666 с учетом umask, как sh
+func (s *pipelineStage) open(name string, flag int) (*os.File, error) {
+	f, err := os.OpenFile(s.sh.path(name), flag, 0o666)
+	if err != nil {
+		var pathErr *os.PathError
+		if errors.As(err, &pathErr) {
+			err = pathErr.Err
+		}
+		return nil, fmt.Errorf("%s: %v", name, err)
+	}
+
+	s.files = append(s.files, f)
+	return f, nil
+}
+
+func (s *pipelineStage) setInput(fd int, r io.Reader) error {
+	if fd != 0 {
+		return fmt.Errorf("%d: bad file descriptor", fd)
+	}
+
+	s.in = r
+	return nil
+}
+
+func (s *pipelineStage) setOutput(fd int, w io.Writer) error {
+	switch fd {
+	case 1:
+		s.out = w
+	case 2:
+		s.errOut = w
+	default:
+		return fmt.Errorf("%d: bad file descriptor", fd)
+	}
+
+	return nil
+}
+
+// Возвращает текущий выходной поток по номеру дескриптора для n>&m
+func (s *pipelineStage) output(fd string) (io.Writer, error) {
+	switch fd {
+	case "1":
+		return s.out, nil
+	case "2":
+		return s.errOut, nil
+	default:
+		return nil, fmt.Errorf("%s: bad file descriptor", fd)
+	}
+}
+
+// Закрывает файлы и пайпы этапа после его завершения
+func (s *pipelineStage) close() {
+	for _, f := range s.files {
+		_ = f.Close()
+	}
+
+	if s.pipeOut != nil {
+		_ = s.pipeOut.Close()
+	}
+
+	// Предыдущий этап получит ошибку записи вместо вечного ожидания, если
+	// этот этап не дочитал вход
+	if s.pipeIn != nil {
+		_ = s.pipeIn.Close()
+	}
+}
+
+// Запускает builtin в горутинах или внешние команды пайплайна в задании.
+// Процессы попадают в одну группу, на переднем плане ей сразу отдается
+// терминал. Завершение этапов отмечается в задании
+func startPipelineStages(j *job, stages []*pipelineStage, foreground bool) error {
+	jobs.mu.Lock()
+	j.stages = stages
+	j.pgid = 0
+	jobs.mu.Unlock()
+
 	firstPGID := 0
 
-	for _, s := range stages {
+	for i, s := range stages {
 		// Если этап является builtin, то запускаем его в горутине
 		if s.isBuiltin {
-			s.doneCh = make(chan error, 1)
 			go func(st *pipelineStage) {
-				err := st.builtin(st.args, st.in, st.out)
-
-				if pw, ok := st.out.(*io.PipeWriter); ok {
-					_ = pw.Close()
+				err := st.builtin(j.ctx, st.sh, st.args, st.in, st.out)
+				// Ошибку builtin пишем в его stderr, чтобы работало 2>. Код
+				// задания у fg и wait идет без сообщения
+				var code exitCode
+				if err != nil && !errors.As(err, &code) {
+					fmt.Fprintln(st.errOut, err)
 				}
 
-				st.doneCh <- err
-				close(st.doneCh)
+				st.close()
+
+				jobs.mu.Lock()
+				st.finished, st.status = true, exitStatus(err)
+				j.update()
+				jobs.mu.Unlock()
 			}(s)
 
 			continue
 		}
 
-		// Иначе запускаем внешнюю команду
-		cmd := exec.CommandContext(ctx, s.argv[0], s.argv[1:]...)
+		// Иначе запускаем внешнюю команду. Она ищется в PATH шелла, а не
+		// процесса
+		path, err := s.sh.lookPath(s.argv[0])
+		cmd := exec.CommandContext(j.ctx, path, s.argv[1:]...)
+		cmd.Args[0] = s.argv[0]
 		cmd.Stdin = s.in
 		cmd.Stdout = s.out
-		cmd.Stderr = os.Stderr
+		cmd.Stderr = s.errOut
+		cmd.Env = s.env
+		cmd.Dir = s.sh.dir
 
 		if cmd.SysProcAttr == nil {
 			cmd.SysProcAttr = &syscall.SysProcAttr{}
@@ -126,73 +387,80 @@ func startPipelineStages(ctx context.Context, stages []*pipelineStage) (int, err
 		cmd.SysProcAttr.Setpgid = true
 		if firstPGID != 0 {
 			cmd.SysProcAttr.Pgid = firstPGID
+		} else if foreground && terminal.interactive {
+			// Группу на передний план переводит сам дочерний процесс до exec,
+			// чтобы он не успел прочитать терминал из фона
+			cmd.SysProcAttr.Foreground = true
+			cmd.SysProcAttr.Ctty = 0
 		}
 
-		if err := cmd.Start(); err != nil {
-			if pw, ok := s.out.(*io.PipeWriter); ok {
-				_ = pw.Close()
+		if err == nil {
+			err = cmd.Start()
+		}
+		if err != nil {
+			// Этот и следующие этапы не запустятся, задание завершится вместе
+			// с уже запущенными
+			jobs.mu.Lock()
+			for _, rest := range stages[i:] {
+				rest.close()
+				rest.finished, rest.status = true, exitStatus(err)
 			}
-			return 0, err
+			j.update()
+			jobs.mu.Unlock()
+			return err
 		}
 
+		jobs.mu.Lock()
 		if firstPGID == 0 {
 			firstPGID = cmd.Process.Pid
+			j.pgid = firstPGID
 		}
-
 		s.cmd = cmd
-	}
-
-	return firstPGID, nil
-}
-
-// Ожидает завершения всех этапов пайплайна и возвращает первую ошибку, если она есть.
-func waitForPipelineStages(stages []*pipelineStage) error {
-	var firstErr error
-
-	for _, s := range stages {
-		if s.cmd != nil {
-			// Если этап является внешней командой, то ожидаем ее завершения
-			if err := s.cmd.Wait(); err != nil && firstErr == nil {
-				firstErr = err
-			}
+		jobs.mu.Unlock()
 
-			if pw, ok := s.out.(*io.PipeWriter); ok {
-				_ = pw.Close()
-			}
-		} else if s.doneCh != nil {
-			// Иначе ожидаем завершения builtin функции
-			if err := <-s.doneCh; err != nil && firstErr == nil {
-				firstErr = err
-			}
-		}
+		go watchStage(j, s)
 	}
 
-	return firstErr
+	return nil
 }
 
 func main() {
+	initTerminal()
+
+	// Ctrl+Z на приглашении не должен останавливать сам шелл
 	sigCh := make(chan os.Signal, 1)
-	signal.Notify(sigCh, os.Interrupt)
+	signal.Notify(sigCh, os.Interrupt, syscall.SIGTSTP)
 
 	go func() {
-		for range sigCh {
-			// Если есть процесс группа, то отправляем сигнал SIGINT в нее
-			if current.state.pgid != 0 {
-				_ = syscall.Kill(-current.state.pgid, syscall.SIGINT)
+		for sig := range sigCh {
+			current.mu.Lock()
+			j := current.job
+			current.mu.Unlock()
+			if j == nil {
+				continue
+			}
+
+			// Если есть процесс группа, то пересылаем сигнал в нее
+			jobs.mu.Lock()
+			pgid := j.pgid
+			jobs.mu.Unlock()
+			if pgid != 0 {
+				_ = syscall.Kill(-pgid, sig.(syscall.Signal))
 			}
 
-			if current.state.cancel != nil {
-				current.state.cancel()
+			if sig == os.Interrupt {
+				j.cancel()
 			}
 		}
 	}()
 
-	reader := bufio.NewReader(os.Stdin)
+	reader := newLineReader()
 	// Бесконечный цикл для чтения команд из Stdin
 	for {
+		notifyJobs()
+
 		wd, _ := os.Getwd()
-		fmt.Fprintf(os.Stdout, "%s$ ", wd)
-		line, err := reader.ReadString('\n')
+		line, err := reader.ReadLine(wd + "$ ")
 		if err != nil {
 			// Ctrl+D - выход из программы
 			if err == io.EOF {
@@ -200,6 +468,12 @@ func main() {
 				return
 			}
 
+			// Ctrl+C отменяет строку
+			if interrupted(err) {
+				shell.lastStatus = 130
+				continue
+			}
+
 			fmt.Fprintln(os.Stderr, "read error:", err)
 			continue
 		}
@@ -209,76 +483,344 @@ func main() {
 			continue
 		}
 
-		if err := evalLine(line); err != nil {
+		if line, err = recordHistory(line); err != nil {
+			shell.lastStatus = 1
+			fmt.Fprintln(os.Stderr, err)
+			continue
+		}
+
+		if err := evalLine(line, reader); err != nil {
 			fmt.Fprintln(os.Stderr, err)
 		}
 	}
 }
 
-func evalLine(line string) error {
-	// Разбиваем строку на сегменты на поиск пайплайнов
-	segTexts := strings.Split(line, "|")
-	for _, p := range segTexts {
-		t := strings.TrimSpace(p)
-		if t == "" {
-			fmt.Fprintln(os.Stderr, "syntax error, unexpected token")
+// Выполняет строку и возвращает только синтаксическую ошибку, ошибки команд
+// выводятся сразу и попадают в $?
+func evalLine(line string, reader lineReader) error {
+	// Разбираем строку в список команд с учетом кавычек и экранирования
+	list, err := parser.Parse(line)
+	if err != nil {
+		// Код синтаксической ошибки, как в sh
+		shell.lastStatus = 2
+		return err
+	}
+	if list == nil {
+		return nil
+	}
+
+	// Тела here-document идут в следующих строках ввода
+	for _, doc := range list.HereDocs() {
+		if doc.Body, err = readHereDoc(reader, doc.Delim); err != nil {
+			shell.lastStatus = 130
 			return nil
 		}
 	}
-	if len(segTexts) == 0 {
-		return nil
+
+	for _, item := range list.Items {
+		if item.Background {
+			startBackground(item)
+			continue
+		}
+
+		runAndOr(shell, item, nil)
 	}
 
-	// Каждый сегмент разбиваем на поля
-	var segments [][]string
-	segments = make([][]string, 0, len(segTexts))
-	for _, seg := range segTexts {
-		args := strings.Fields(seg)
-		if len(args) == 0 {
+	return nil
+}
+
+// Выполняет цепочку && и ||: пропущенный пайплайн не меняет код, так что в
+// false && a || b выполнится b. Без задания каждый пайплайн идет на переднем
+// плане, иначе — в задании фоновой цепочки
+func runAndOr(sh *shellState, item *parser.AndOr, j *job) {
+	sh.lastStatus = runPipeline(sh, item.Pipelines[0], j)
+
+	for i, op := range item.Ops {
+		if (op == parser.AndIf) != (sh.lastStatus == 0) {
 			continue
 		}
 
-		segments = append(segments, args)
+		sh.lastStatus = runPipeline(sh, item.Pipelines[i+1], j)
 	}
-	if len(segments) == 0 {
-		return nil
+}
+
+// Возвращает код завершения по ошибке команды, как его видит $?
+func exitStatus(err error) int {
+	var exitErr *exec.ExitError
+	var code exitCode
+	switch {
+	case err == nil:
+		return 0
+	case errors.As(err, &code):
+		return int(code)
+	case errors.As(err, &exitErr):
+		// Процесс, убитый сигналом, завершается с кодом 128+сигнал
+		if status, ok := exitErr.Sys().(syscall.WaitStatus); ok && status.Signaled() {
+			return 128 + int(status.Signal())
+		}
+		return exitErr.ExitCode()
+	case errors.Is(err, exec.ErrNotFound):
+		return 127
+	default:
+		return 1
+	}
+}
+
+// Возвращает копию состояния для фоновой цепочки
+func (sh *shellState) subshell() *shellState {
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+
+	sub := &shellState{
+		vars:           make(map[string]string, len(sh.vars)),
+		env:            make(map[string]string, len(sh.env)),
+		dir:            sh.dir,
+		lastStatus:     sh.lastStatus,
+		lastBackground: sh.lastBackground,
+	}
+	for name, value := range sh.vars {
+		sub.vars[name] = value
+	}
+	for name, value := range sh.env {
+		sub.env[name] = value
+	}
+
+	// cd в цепочке меняет только ее каталог
+	if sub.dir == "" {
+		sub.dir, _ = os.Getwd()
 	}
 
-	// Если сегмент один, то проверяем, является ли он builtin командой
-	if len(segments) == 1 {
-		argv := segments[0]
-		if fn, ok := builtinMap[argv[0]]; ok {
-			return fn(argv[1:], os.Stdin, os.Stdout)
+	return sub
+}
+
+// Возвращает значение параметра: специального, переменной шелла или
+// окружения
+func (sh *shellState) lookup(name string) (string, bool) {
+	switch name {
+	case "?":
+		return strconv.Itoa(sh.lastStatus), true
+	case "$":
+		return strconv.Itoa(os.Getpid()), true
+	case "!":
+		if sh.lastBackground == 0 {
+			return "", false
 		}
+		return strconv.Itoa(sh.lastBackground), true
+	}
+
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+
+	if value, ok := sh.vars[name]; ok {
+		return value, true
+	}
+
+	value, ok := sh.env[name]
+	return value, ok
+}
+
+// Меняет переменную: экспортированная остается в окружении, новая
+// становится переменной шелла
+func (sh *shellState) set(name, value string) {
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+
+	if _, exported := sh.env[name]; exported {
+		sh.env[name] = value
+		return
+	}
+
+	if sh.vars == nil {
+		sh.vars = make(map[string]string)
+	}
+	sh.vars[name] = value
+}
+
+// Переносит переменную в окружение. Без значения экспортируется текущее,
+// неизвестная переменная экспортируется пустой
+func (sh *shellState) export(name, value string, hasValue bool) {
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+
+	if !hasValue {
+		value = sh.vars[name]
+		if env, ok := sh.env[name]; ok {
+			value = env
+		}
+	}
+
+	delete(sh.vars, name)
+	if sh.env == nil {
+		sh.env = make(map[string]string)
 	}
+	sh.env[name] = value
+}
+
+func (sh *shellState) unset(name string) {
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+
+	delete(sh.vars, name)
+	delete(sh.env, name)
+}
 
-	// Иначе запускаем пайплайн
-	return runPipeline(segments)
+// Возвращает окружение для внешних команд, отсортированное по именам
+func (sh *shellState) environ() []string {
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+
+	env := make([]string, 0, len(sh.env))
+	for name, value := range sh.env {
+		env = append(env, name+"="+value)
+	}
+	sort.Strings(env)
+
+	return env
 }
 
-func runPipeline(segments [][]string) error {
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
+// Возвращает путь относительно рабочего каталога шелла или цепочки
+func (sh *shellState) path(name string) string {
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+
+	if sh.dir == "" || filepath.IsAbs(name) {
+		return name
+	}
+
+	return filepath.Join(sh.dir, name)
+}
 
-	stages := buildPipelineStages(segments)
+// Меняет каталог шелла. Фоновая цепочка меняет только свой каталог, а
+// каталог процесса остается каталогом шелла
+func (sh *shellState) chdir(dir string) error {
+	sh.mu.Lock()
+	background := sh.dir != ""
+	sh.mu.Unlock()
+	if !background {
+		return os.Chdir(dir)
+	}
 
-	firstPGID, err := startPipelineStages(ctx, stages)
+	path := sh.path(dir)
+	info, err := os.Stat(path)
+	if err == nil && !info.IsDir() {
+		err = syscall.ENOTDIR
+	}
 	if err != nil {
-		cancel()
-		return err
+		var pathErr *os.PathError
+		if errors.As(err, &pathErr) {
+			err = pathErr.Err
+		}
+		return &os.PathError{Op: "chdir", Path: dir, Err: err}
 	}
 
-	current.state = runningState{cancel: cancel, pgid: firstPGID}
+	sh.mu.Lock()
+	sh.dir = filepath.Clean(path)
+	sh.mu.Unlock()
 
-	err = waitForPipelineStages(stages)
-	current.state = runningState{}
-	return err
+	return nil
+}
+
+func (sh *shellState) getwd() (string, error) {
+	sh.mu.Lock()
+	dir := sh.dir
+	sh.mu.Unlock()
+	if dir == "" {
+		return os.Getwd()
+	}
+
+	return dir, nil
+}
+
+// Ищет исполняемый файл в PATH шелла. Имя с / берется как есть, от
+// рабочего каталога команды
+func (sh *shellState) lookPath(name string) (string, error) {
+	if strings.Contains(name, "/") {
+		return name, nil
+	}
+
+	pathEnv, _ := sh.lookup("PATH")
+	for _, dir := range filepath.SplitList(pathEnv) {
+		if dir == "" {
+			dir = "."
+		}
+
+		// Путь без Join, чтобы ./name из пустого элемента PATH не стал
+		// снова именем без /. Stat идет по символическим ссылкам, как
+		// exec.LookPath
+		file := dir + "/" + name
+		if info, err := os.Stat(sh.path(file)); err == nil && !info.IsDir() && info.Mode()&0o111 != 0 {
+			return file, nil
+		}
+	}
+
+	return "", &exec.Error{Name: name, Err: exec.ErrNotFound}
 }
 
-func builtinCd(args []string, _ io.Reader, _ io.Writer) error {
+// Читает строки до строки-разделителя. Конец ввода тоже завершает тело, как
+// в sh, но с предупреждением. Ctrl+C отменяет всю команду
+func readHereDoc(reader lineReader, delimiter string) (string, error) {
+	var body strings.Builder
+	for {
+		line, err := reader.ReadLine("> ")
+		if interrupted(err) {
+			return "", err
+		}
+		if line == delimiter {
+			return body.String(), nil
+		}
+
+		if err != nil {
+			if line != "" {
+				body.WriteString(line + "\n")
+			}
+			fmt.Fprintf(os.Stderr, "warning: here-document delimited by end-of-file (wanted `%s')\n", delimiter)
+			return body.String(), nil
+		}
+		body.WriteString(line + "\n")
+	}
+}
+
+// Выполняет пайплайн и возвращает его код завершения. Без задания пайплайн
+// становится новым заданием на переднем плане, в фоновой цепочке он ждется
+// до конца в ее задании. Ошибки запуска выводятся здесь
+func runPipeline(sh *shellState, pipeline *parser.Pipeline, j *job) int {
+	foreground := j == nil
+	if foreground {
+		j = newJob(pipeline.Text, false)
+	}
+
+	stages, err := buildPipelineStages(sh, pipeline.Commands, !foreground)
+	if err == nil {
+		// Этапы, которые успели запуститься, задание дождется
+		err = startPipelineStages(j, stages, foreground)
+	} else if foreground {
+		j.cancel()
+	}
+
+	// $! фоновой цепочки известен после запуска ее первого пайплайна
+	if j.started != nil {
+		close(j.started)
+		j.started = nil
+	}
+
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		if stages == nil {
+			return exitStatus(err)
+		}
+	}
+
+	if !foreground {
+		return j.waitPipeline()
+	}
+
+	return waitForeground(j)
+}
+
+func builtinCd(_ context.Context, sh *shellState, args []string, _ io.Reader, _ io.Writer) error {
 	var dir string
 	if len(args) == 0 {
-		dir = os.Getenv("HOME")
+		dir, _ = sh.lookup("HOME")
 		if dir == "" {
 			dir = "/"
 		}
@@ -286,11 +828,11 @@ func builtinCd(args []string, _ io.Reader, _ io.Writer) error {
 		dir = args[0]
 	}
 
-	return os.Chdir(dir)
+	return sh.chdir(dir)
 }
 
-func builtinPwd(_ []string, _ io.Reader, w io.Writer) error {
-	wd, err := os.Getwd()
+func builtinPwd(_ context.Context, sh *shellState, _ []string, _ io.Reader, w io.Writer) error {
+	wd, err := sh.getwd()
 	if err != nil {
 		return err
 	}
@@ -299,12 +841,12 @@ func builtinPwd(_ []string, _ io.Reader, w io.Writer) error {
 	return err
 }
 
-func builtinEcho(args []string, _ io.Reader, w io.Writer) error {
+func builtinEcho(_ context.Context, _ *shellState, args []string, _ io.Reader, w io.Writer) error {
 	_, err := fmt.Fprintln(w, strings.Join(args, " "))
 	return err
 }
 
-func builtinKill(args []string, _ io.Reader, _ io.Writer) error {
+func builtinKill(_ context.Context, _ *shellState, args []string, _ io.Reader, _ io.Writer) error {
 	if len(args) < 1 {
 		return fmt.Errorf("kill: missing pid")
 	}
@@ -321,7 +863,7 @@ func builtinKill(args []string, _ io.Reader, _ io.Writer) error {
 	return nil
 }
 
-func builtinPs(_ []string, _ io.Reader, w io.Writer) error {
+func builtinPs(_ context.Context, _ *shellState, _ []string, _ io.Reader, w io.Writer) error {
 	entries, err := os.ReadDir("/proc")
 	if err != nil {
 		return err
@@ -364,5 +906,83 @@ func builtinPs(_ []string, _ io.Reader, w io.Writer) error {
 		}
 	}
 
+	return nil
+}
+
+// export NAME[=value]... переносит переменные в окружение, без аргументов
+// печатает окружение в виде, пригодном для ввода обратно
+func builtinExport(_ context.Context, sh *shellState, args []string, _ io.Reader, w io.Writer) error {
+	if len(args) == 0 {
+		for _, kv := range sh.environ() {
+			name, value, _ := strings.Cut(kv, "=")
+			if _, err := fmt.Fprintf(w, "export %s=%s\n", name, strconv.Quote(value)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	var invalid error
+	for _, arg := range args {
+		name, value, hasValue := strings.Cut(arg, "=")
+		if !parser.ValidName(name) {
+			invalid = fmt.Errorf("export: `%s': not a valid identifier", arg)
+			continue
+		}
+
+		sh.export(name, value, hasValue)
+	}
+
+	return invalid
+}
+
+func builtinUnset(_ context.Context, sh *shellState, args []string, _ io.Reader, _ io.Writer) error {
+	var invalid error
+	for _, name := range args {
+		if !parser.ValidName(name) {
+			invalid = fmt.Errorf("unset: `%s': not a valid identifier", name)
+			continue
+		}
+
+		sh.unset(name)
+	}
+
+	return invalid
+}
+
+// env [NAME=value]... печатает окружение с добавленными переменными, как
+// env(1) без команды
+func builtinEnv(_ context.Context, sh *shellState, args []string, _ io.Reader, w io.Writer) error {
+	env := sh.environ()
+	for _, arg := range args {
+		name, _, ok := strings.Cut(arg, "=")
+		if !ok || !parser.ValidName(name) {
+			return fmt.Errorf("env: %s: expected NAME=value", arg)
+		}
+		env = append(env, arg)
+	}
+
+	// Поздние присваивания заменяют ранние, порядок как у окружения
+	seen := make(map[string]int, len(env))
+	vars := make([]string, 0, len(env))
+	for _, kv := range env {
+		name, _, _ := strings.Cut(kv, "=")
+		if i, ok := seen[name]; ok {
+			vars[i] = kv
+			continue
+		}
+		seen[name] = len(vars)
+		vars = append(vars, kv)
+	}
+
+	bw := bufio.NewWriter(w)
+	defer bw.Flush()
+
+	for _, kv := range vars {
+		if _, err := fmt.Fprintln(bw, kv); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
\ No newline at end of file
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"wb_l2/15/parser"
+)
+
+// Применяет перенаправления единственной команды строки к новому этапу
+func redirectLine(t *testing.T, line string) (*pipelineStage, error) {
+	t.Helper()
+
+	list, err := parser.Parse(line)
+	if err != nil {
+		t.Fatalf("Parse(%q) error = %v", line, err)
+	}
+
+	s := &pipelineStage{sh: &shellState{}, out: &bytes.Buffer{}, errOut: &bytes.Buffer{}}
+	t.Cleanup(s.close)
+
+	return s, s.redirect(list.Items[0].Pipelines[0].Commands[0].Redirects)
+}
+
+func TestRedirectOrder(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "f")
+
+	// >f 2>&1 — оба потока в файл
+	s, err := redirectLine(t, "cmd >"+file+" 2>&1")
+	if err != nil {
+		t.Fatalf("redirect error = %v", err)
+	}
+	if _, ok := s.out.(*os.File); !ok || s.errOut != s.out {
+		t.Errorf("stdout %T, stderr %T, want both to the file", s.out, s.errOut)
+	}
+
+	// 2>&1 >f — stderr остается на прежнем stdout
+	s, err = redirectLine(t, "cmd 2>&1 >"+file)
+	if err != nil {
+		t.Fatalf("redirect error = %v", err)
+	}
+	if _, ok := s.errOut.(*bytes.Buffer); !ok {
+		t.Errorf("stderr %T, want the old stdout", s.errOut)
+	}
+	if _, ok := s.out.(*os.File); !ok {
+		t.Errorf("stdout %T, want the file", s.out)
+	}
+}
+
+func TestRedirectBadFd(t *testing.T) {
+	dir := t.TempDir()
+	kept := filepath.Join(dir, "kept")
+	if err := os.WriteFile(kept, []byte("data"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []string{
+		"cmd 3>" + filepath.Join(dir, "new"),
+		"cmd 3>" + kept,
+		"cmd 0>>" + kept,
+		"cmd 1<" + kept,
+		"cmd 3>&1",
+	}
+
+	for _, line := range tests {
+		if _, err := redirectLine(t, line); err == nil {
+			t.Errorf("redirect(%q) error = nil, want bad file descriptor", line)
+		}
+	}
+
+	// файл не создается и не обрезается
+	if _, err := os.Stat(filepath.Join(dir, "new")); !os.IsNotExist(err) {
+		t.Errorf("new file exists after a bad descriptor: %v", err)
+	}
+	if data, _ := os.ReadFile(kept); string(data) != "data" {
+		t.Errorf("kept file = %q after a bad descriptor, want %q", data, "data")
+	}
+}
+
+func TestSubshellState(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sh := &shellState{env: map[string]string{"HOME": "/home", "KEPT": "k"}}
+	sh.set("LOCAL", "l")
+	sub := sh.subshell()
+
+	// builtin фоновой цепочки меняют только ее копию
+	run := func(fn builtinFunc, args ...string) {
+		t.Helper()
+		if err := fn(context.Background(), sub, args, nil, &bytes.Buffer{}); err != nil {
+			t.Fatalf("builtin %q error = %v", args, err)
+		}
+	}
+	run(builtinExport, "NEW=1", "LOCAL")
+	run(builtinUnset, "KEPT")
+	run(builtinCd, "/")
+
+	if got, _ := sub.lookup("NEW"); got != "1" {
+		t.Errorf("subshell NEW = %q, want 1", got)
+	}
+	if got := sub.environ(); !reflect.DeepEqual(got, []string{"HOME=/home", "LOCAL=l", "NEW=1"}) {
+		t.Errorf("subshell environ() = %q", got)
+	}
+	if dir, _ := sub.getwd(); dir != "/" {
+		t.Errorf("subshell getwd() = %q, want /", dir)
+	}
+
+	if _, ok := sh.lookup("NEW"); ok {
+		t.Error("export in a subshell set NEW in the shell")
+	}
+	if got, _ := sh.lookup("KEPT"); got != "k" {
+		t.Errorf("shell KEPT = %q after unset in a subshell, want k", got)
+	}
+	if got := sh.environ(); !reflect.DeepEqual(got, []string{"HOME=/home", "KEPT=k"}) {
+		t.Errorf("shell environ() = %q", got)
+	}
+	if now, _ := os.Getwd(); now != wd {
+		t.Errorf("working directory = %q after cd in a subshell, want %q", now, wd)
+	}
+	if err := sub.chdir("missing"); err == nil {
+		t.Error("chdir(missing) error = nil")
+	}
+}
@@ -0,0 +1,160 @@
+package parser
+
+import "strings"
+
+// Kind — вид токена
+type Kind int
+
+const (
+	// Word — слово после снятия кавычек и экранирования
+	Word Kind = iota
+	// IONumber — номер дескриптора перед перенаправлением, как 2 в 2>file
+	IONumber
+	Pipe
+	OrIf
+	Amp
+	AndIf
+	Semi
+	Less
+	Great
+	DGreat
+	// DLess — here-document
+	DLess
+	// GreatAnd — копия дескриптора, как в 2>&1
+	GreatAnd
+	// AndGreat — stdout и stderr в один файл
+	AndGreat
+	LParen
+	RParen
+	EOF
+)
+
+type operator struct {
+	text string
+	kind Kind
+}
+
+// Операторы, более длинные идут первыми, чтобы && не разобрался как два &
+var operators = []operator{
+	{"&&", AndIf},
+	{"&>", AndGreat},
+	{"||", OrIf},
+	{">>", DGreat},
+	{">&", GreatAnd},
+	{"<<", DLess},
+	{"|", Pipe},
+	{"&", Amp},
+	{";", Semi},
+	{"<", Less},
+	{">", Great},
+	{"(", LParen},
+	{")", RParen},
+}
+
+// Token — слово или оператор строки
+type Token struct {
+	Kind Kind
+	// Для слова — текст без кавычек, где параметры записаны как в строке,
+	// для оператора — сам оператор
+	Value string
+	// Expr — части слова для раскрытия параметров
+	Expr Expr
+	// Смещение начала токена в строке
+	Pos int
+	// Quoted — в слове были кавычки или экранирование
+	Quoted bool
+}
+
+func (t Token) String() string {
+	if t.Kind == EOF {
+		return "newline"
+	}
+
+	return t.Value
+}
+
+// SyntaxError — ошибка разбора строки
+type SyntaxError struct {
+	Pos int
+	Msg string
+}
+
+func (e *SyntaxError) Error() string {
+	return "syntax error: " + e.Msg
+}
+
+// Символы, которые без кавычек разделяют слова
+const metachars = " \t\n|&;<>()"
+
+// Lex разбивает строку на слова и операторы по правилам POSIX: одинарные
+// кавычки сохраняют все символы как есть, в двойных кавычках обратный слеш
+// экранирует только $ ` " \ и перевод строки, вне кавычек — любой символ.
+// Последний токен всегда EOF, он стоит в конце строки или на начале
+// комментария
+func Lex(line string) ([]Token, error) {
+	var tokens []Token
+
+	end := len(line)
+	for i := 0; i < len(line); {
+		c := line[i]
+
+		// Пробелы разделяют токены
+		if c == ' ' || c == '\t' || c == '\n' {
+			i++
+			continue
+		}
+
+		// Комментарий до конца строки
+		if c == '#' {
+			end = i
+			break
+		}
+
+		// Цифры прямо перед < или > — номер дескриптора
+		if n := ioNumberAt(line, i); n > 0 {
+			tokens = append(tokens, Token{Kind: IONumber, Value: line[i : i+n], Pos: i})
+			i += n
+			continue
+		}
+
+		if op, ok := operatorAt(line, i); ok {
+			tokens = append(tokens, Token{Kind: op.kind, Value: op.text, Pos: i})
+			i += len(op.text)
+			continue
+		}
+
+		ws := &wordScanner{src: line, pos: i}
+		if err := ws.word(); err != nil {
+			return nil, err
+		}
+
+		tokens = append(tokens, Token{Kind: Word, Value: ws.value.String(), Pos: i, Quoted: ws.quoted, Expr: ws.parts})
+		i = ws.pos
+	}
+
+	return append(tokens, Token{Kind: EOF, Pos: end}), nil
+}
+
+// ioNumberAt возвращает длину номера дескриптора на позиции i или 0
+func ioNumberAt(line string, i int) int {
+	n := 0
+	for i+n < len(line) && line[i+n] >= '0' && line[i+n] <= '9' {
+		n++
+	}
+
+	if n == 0 || i+n == len(line) || (line[i+n] != '<' && line[i+n] != '>') {
+		return 0
+	}
+
+	return n
+}
+
+func operatorAt(line string, i int) (operator, bool) {
+	for _, op := range operators {
+		if strings.HasPrefix(line[i:], op.text) {
+			return op, true
+		}
+	}
+
+	return operator{}, false
+}
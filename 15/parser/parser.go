@@ -0,0 +1,316 @@
+// Package parser разбирает строку минишелла в дерево: список условных цепочек
+// из пайплайнов простых команд с присваиваниями, словами и
+// перенаправлениями. Параметры в словах
+// раскрываются перед запуском, см. Expr
+package parser
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// RedirectOp — вид перенаправления
+type RedirectOp int
+
+const (
+	// RedirectIn — < file
+	RedirectIn RedirectOp = iota
+	// RedirectOut — > file, файл создается или обрезается
+	RedirectOut
+	// RedirectAppend — >> file, запись в конец файла
+	RedirectAppend
+	// RedirectDup — n>&m, дескриптор n становится копией m
+	RedirectDup
+	// RedirectOutErr — &> file, stdout и stderr в один файл
+	RedirectOutErr
+	// RedirectHereDoc — << delim, stdin из следующих строк ввода
+	RedirectHereDoc
+)
+
+// Redirect — перенаправление дескриптора команды
+type Redirect struct {
+	Op RedirectOp
+	// Fd — перенаправляемый дескриптор
+	Fd int
+	// Target — файл или номер дескриптора для RedirectDup
+	Target Expr
+	// Delim — разделитель here-document без кавычек
+	Delim string
+	// Body — тело here-document, его заполняет вызывающий после разбора
+	// строки, см. Pipeline.HereDocs
+	Body string
+	// Quoted — разделитель here-document был в кавычках
+	Quoted bool
+}
+
+// Assign — присваивание NAME=value перед командой
+type Assign struct {
+	Name  string
+	Value Expr
+}
+
+// Command — простая команда: присваивания, имя с аргументами и
+// перенаправления в порядке записи. Команда может состоять из одних
+// присваиваний и перенаправлений
+type Command struct {
+	Assigns   []*Assign
+	Args      []Expr
+	Redirects []*Redirect
+}
+
+// Pipeline — команды, соединенные через |
+type Pipeline struct {
+	Commands []*Command
+	// Text — исходный текст пайплайна для списка заданий
+	Text string
+}
+
+// AndOr — пайплайны, соединенные через && и ||. Следующий пайплайн
+// выполняется, только если код предыдущего подходит оператору между ними
+type AndOr struct {
+	Pipelines []*Pipeline
+	// Ops[i] — AndIf или OrIf между Pipelines[i] и Pipelines[i+1]
+	Ops []Kind
+	// Background — цепочка завершена &, шелл не ждет ее
+	Background bool
+	// Text — исходный текст цепочки без & для списка заданий
+	Text string
+}
+
+// List — цепочки, выполняемые по очереди, как через ; или &
+type List struct {
+	Items []*AndOr
+}
+
+// HereDocs возвращает here-documents в порядке записи, их тела читаются из
+// строк после разобранной
+func (l *List) HereDocs() []*Redirect {
+	var docs []*Redirect
+	for _, item := range l.Items {
+		for _, pipeline := range item.Pipelines {
+			for _, command := range pipeline.Commands {
+				for _, redirect := range command.Redirects {
+					if redirect.Op == RedirectHereDoc {
+						docs = append(docs, redirect)
+					}
+				}
+			}
+		}
+	}
+
+	return docs
+}
+
+type parser struct {
+	line   string
+	tokens []Token
+	pos    int
+}
+
+// Parse разбирает строку в список команд. Для пустой строки или строки из
+// одного комментария возвращает nil
+func Parse(line string) (*List, error) {
+	tokens, err := Lex(line)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{line: line, tokens: tokens}
+	if p.peek().Kind == EOF {
+		return nil, nil
+	}
+
+	list, err := p.list()
+	if err != nil {
+		return nil, err
+	}
+
+	if p.peek().Kind != EOF {
+		return nil, p.unexpected()
+	}
+
+	return list, nil
+}
+
+func (p *parser) peek() Token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() Token {
+	t := p.tokens[p.pos]
+	if t.Kind != EOF {
+		p.pos++
+	}
+
+	return t
+}
+
+func (p *parser) unexpected() error {
+	t := p.peek()
+	return &SyntaxError{t.Pos, fmt.Sprintf("unexpected token `%s'", t)}
+}
+
+// text возвращает исходный текст от позиции start до текущего токена
+func (p *parser) text(start int) string {
+	return strings.TrimSpace(p.line[start:p.peek().Pos])
+}
+
+// list: and_or ((';' | '&') and_or)* [';' | '&']
+func (p *parser) list() (*List, error) {
+	list := &List{}
+	for {
+		item, err := p.andOr()
+		if err != nil {
+			return nil, err
+		}
+		list.Items = append(list.Items, item)
+
+		if kind := p.peek().Kind; kind != Semi && kind != Amp {
+			return list, nil
+		}
+		item.Background = p.next().Kind == Amp
+
+		if p.peek().Kind == EOF {
+			return list, nil
+		}
+	}
+}
+
+// and_or: pipeline (('&&' | '||') pipeline)*
+func (p *parser) andOr() (*AndOr, error) {
+	item := &AndOr{}
+	start := p.peek().Pos
+	for {
+		pipeline, err := p.pipeline()
+		if err != nil {
+			return nil, err
+		}
+		item.Pipelines = append(item.Pipelines, pipeline)
+
+		if kind := p.peek().Kind; kind != AndIf && kind != OrIf {
+			item.Text = p.text(start)
+			return item, nil
+		}
+		item.Ops = append(item.Ops, p.next().Kind)
+	}
+}
+
+// pipeline: command ('|' command)*
+func (p *parser) pipeline() (*Pipeline, error) {
+	pipeline := &Pipeline{}
+	start := p.peek().Pos
+	for {
+		command, err := p.command()
+		if err != nil {
+			return nil, err
+		}
+		pipeline.Commands = append(pipeline.Commands, command)
+
+		if p.peek().Kind != Pipe {
+			pipeline.Text = p.text(start)
+			return pipeline, nil
+		}
+		p.next()
+	}
+}
+
+// command: (ASSIGNMENT_WORD | redirect)* (WORD | redirect)*, хотя бы один
+// элемент
+func (p *parser) command() (*Command, error) {
+	command := &Command{}
+	for {
+		if p.peek().Kind == Word {
+			word := p.next().Expr
+			if assign, ok := assignment(word); ok && len(command.Args) == 0 {
+				command.Assigns = append(command.Assigns, assign)
+			} else {
+				command.Args = append(command.Args, word)
+			}
+			continue
+		}
+
+		if _, ok := redirectOps[p.peek().Kind]; !ok && p.peek().Kind != IONumber {
+			break
+		}
+
+		redirect, err := p.redirect()
+		if err != nil {
+			return nil, err
+		}
+		command.Redirects = append(command.Redirects, redirect)
+	}
+
+	if len(command.Assigns) == 0 && len(command.Args) == 0 && len(command.Redirects) == 0 {
+		return nil, p.unexpected()
+	}
+
+	return command, nil
+}
+
+// assignment распознает NAME=value: имя без кавычек и параметров перед
+// первым =
+func assignment(word Expr) (*Assign, bool) {
+	if len(word) == 0 || word[0].Param != "" || word[0].Quoted {
+		return nil, false
+	}
+
+	name, rest, ok := strings.Cut(word[0].Text, "=")
+	if !ok || !ValidName(name) {
+		return nil, false
+	}
+
+	value := Expr{}
+	if rest != "" {
+		value = append(value, Part{Text: rest})
+	}
+
+	return &Assign{Name: name, Value: append(value, word[1:]...)}, true
+}
+
+// Операторы перенаправлений и дескрипторы, которые они меняют по умолчанию
+var redirectOps = map[Kind]struct {
+	op RedirectOp
+	fd int
+}{
+	Less:     {RedirectIn, 0},
+	Great:    {RedirectOut, 1},
+	DGreat:   {RedirectAppend, 1},
+	GreatAnd: {RedirectDup, 1},
+	AndGreat: {RedirectOutErr, 1},
+	DLess:    {RedirectHereDoc, 0},
+}
+
+// redirect: [IO_NUMBER] op WORD
+func (p *parser) redirect() (*Redirect, error) {
+	fd := -1
+	if p.peek().Kind == IONumber {
+		n, err := strconv.Atoi(p.next().Value)
+		if err != nil {
+			return nil, &SyntaxError{p.peek().Pos, "bad file descriptor"}
+		}
+		fd = n
+	}
+
+	kind, ok := redirectOps[p.peek().Kind]
+	if !ok || (fd >= 0 && p.peek().Kind == AndGreat) {
+		return nil, p.unexpected()
+	}
+	p.next()
+
+	if p.peek().Kind != Word {
+		return nil, p.unexpected()
+	}
+	target := p.next()
+
+	if fd < 0 {
+		fd = kind.fd
+	}
+
+	redirect := &Redirect{Op: kind.op, Fd: fd, Target: target.Expr}
+	if kind.op == RedirectHereDoc {
+		redirect.Delim, redirect.Quoted = target.Value, target.Quoted
+	}
+
+	return redirect, nil
+}
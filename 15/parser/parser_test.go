@@ -0,0 +1,333 @@
+package parser
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// Переменные для раскрытия в тестах
+var testVars = map[string]string{
+	"X":     "x",
+	"EMPTY": "",
+	"SPACE": " a  b ",
+	"?":     "1",
+	"$":     "42",
+}
+
+func testLookup(name string) (string, bool) {
+	value, ok := testVars[name]
+	return value, ok
+}
+
+// Раскрывает слова единственной команды строки
+func parseArgs(t *testing.T, line string) []string {
+	t.Helper()
+
+	list, err := Parse(line)
+	if err != nil {
+		t.Fatalf("Parse(%q) error = %v", line, err)
+	}
+	if len(list.Items) != 1 || len(list.Items[0].Pipelines) != 1 || len(list.Items[0].Pipelines[0].Commands) != 1 {
+		t.Fatalf("Parse(%q) is not a single command", line)
+	}
+
+	args := []string{}
+	for _, arg := range list.Items[0].Pipelines[0].Commands[0].Args {
+		args = append(args, arg.Expand(testLookup)...)
+	}
+
+	return args
+}
+
+func TestParseWords(t *testing.T) {
+	tests := []struct {
+		line string
+		want []string
+	}{
+		// кавычки и экранирование
+		{`echo a   b`, []string{"echo", "a", "b"}},
+		{`echo 'a  b' "c  d"`, []string{"echo", "a  b", "c  d"}},
+		{`echo a\ b \'c\" \\`, []string{"echo", "a b", `'c"`, `\`}},
+		{`echo 'a\b' "a\b" "\$X \" \\"`, []string{"echo", `a\b`, `a\b`, `$X " \`}},
+		{`echo '$X' "$X" $X`, []string{"echo", "$X", "x", "x"}},
+		{`echo a"b"'c'd`, []string{"echo", "abcd"}},
+		{`echo "a|b;c&d" a\|b`, []string{"echo", "a|b;c&d", "a|b"}},
+		{"echo a # comment", []string{"echo", "a"}},
+		{"echo a#b", []string{"echo", "a#b"}},
+
+		// параметры
+		{`echo $? $$ ${X}y $Xy`, []string{"echo", "1", "42", "xy"}},
+		{`echo $ a$ "$"`, []string{"echo", "$", "a$", "$"}},
+		{`echo ${X:-d} ${NONE:-d} ${EMPTY:-d}`, []string{"echo", "x", "d", "d"}},
+		{`echo ${NONE:-$X} ${NONE:-${EMPTY:-z}}`, []string{"echo", "x", "z"}},
+		{`echo ${NONE:-a b}`, []string{"echo", "a", "b"}},
+		{`echo "${NONE:-a  b}"`, []string{"echo", "a  b"}},
+		// кавычки внутри значения по умолчанию сохраняются
+		{`echo ${NONE:-"a  b"}`, []string{"echo", "a  b"}},
+		{`echo ${NONE:-"a  b" c}`, []string{"echo", "a  b", "c"}},
+		{`echo ${NONE:-x"a  b"$SPACE}`, []string{"echo", "xa  b", "a", "b"}},
+		{`echo ${NONE:-""} ${NONE:-''}x`, []string{"echo", "", "x"}},
+		{`echo ${NONE:-"$SPACE"}`, []string{"echo", " a  b "}},
+
+		// пустые поля и разбиение
+		{`echo "" '' $EMPTY $NONE x`, []string{"echo", "", "", "x"}},
+		{`echo "$EMPTY"`, []string{"echo", ""}},
+		{`echo $SPACE`, []string{"echo", "a", "b"}},
+		{`echo "$SPACE"`, []string{"echo", " a  b "}},
+		{`echo p$SPACE.q`, []string{"echo", "p", "a", "b", ".q"}},
+	}
+
+	for _, tt := range tests {
+		if got := parseArgs(t, tt.line); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Parse(%q) args = %q, want %q", tt.line, got, tt.want)
+		}
+	}
+}
+
+func TestParseAssigns(t *testing.T) {
+	list, err := Parse(`A=1 B="$X y" C= env D=2`)
+	if err != nil {
+		t.Fatalf("Parse error = %v", err)
+	}
+
+	command := list.Items[0].Pipelines[0].Commands[0]
+	got := map[string]string{}
+	for _, assign := range command.Assigns {
+		got[assign.Name] = assign.Value.String(testLookup)
+	}
+
+	want := map[string]string{"A": "1", "B": "x y", "C": ""}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("assigns = %q, want %q", got, want)
+	}
+
+	// после имени команды присваивание — обычный аргумент
+	if len(command.Args) != 2 || command.Args[1].String(testLookup) != "D=2" {
+		t.Errorf("args = %v, want env D=2", command.Args)
+	}
+
+	// имя в кавычках или не имя — не присваивание
+	for _, line := range []string{`"A"=1`, `1A=1`, `$X=1`} {
+		list, err := Parse(line)
+		if err != nil {
+			t.Fatalf("Parse(%q) error = %v", line, err)
+		}
+		if command := list.Items[0].Pipelines[0].Commands[0]; len(command.Assigns) != 0 {
+			t.Errorf("Parse(%q) assigns = %v, want none", line, command.Assigns)
+		}
+	}
+}
+
+// Parse не раскрывает параметры: шелл раскрывает слова каждой команды перед
+// ее запуском, когда предыдущие команды строки уже выполнены
+func TestParseKeepsParams(t *testing.T) {
+	tests := []struct {
+		line  string
+		param string
+		// vars — состояние после первой команды строки
+		vars map[string]string
+		want string
+	}{
+		{"a=1; echo $a", "a", map[string]string{"a": "1"}, "1"},
+		{"false; echo $?", "?", map[string]string{"?": "1"}, "1"},
+		{"false || echo $?", "?", map[string]string{"?": "1"}, "1"},
+	}
+
+	for _, tt := range tests {
+		list, err := Parse(tt.line)
+		if err != nil {
+			t.Fatalf("Parse(%q) error = %v", tt.line, err)
+		}
+
+		last := list.Items[len(list.Items)-1]
+		args := last.Pipelines[len(last.Pipelines)-1].Commands[0].Args
+		want := Expr{{Param: tt.param}}
+		if len(args) != 2 || !reflect.DeepEqual(args[1], want) {
+			t.Errorf("Parse(%q) args = %v, want echo %v", tt.line, args, want)
+			continue
+		}
+
+		lookup := func(name string) (string, bool) {
+			value, ok := tt.vars[name]
+			return value, ok
+		}
+		if got := args[1].String(lookup); got != tt.want {
+			t.Errorf("Parse(%q) expands to %q after the first command, want %q", tt.line, got, tt.want)
+		}
+	}
+}
+
+// Краткая запись перенаправления для сравнения
+type redirectCase struct {
+	op     RedirectOp
+	fd     int
+	target string
+}
+
+func TestParseRedirects(t *testing.T) {
+	tests := []struct {
+		line string
+		want []redirectCase
+	}{
+		{"cat <in >out", []redirectCase{{RedirectIn, 0, "in"}, {RedirectOut, 1, "out"}}},
+		{"cat >>log 2>err", []redirectCase{{RedirectAppend, 1, "log"}, {RedirectOut, 2, "err"}}},
+		{"cat &>all", []redirectCase{{RedirectOutErr, 1, "all"}}},
+		// порядок важен: 2>&1 до >f оставляет stderr на старом stdout
+		{"cmd 2>&1 >f", []redirectCase{{RedirectDup, 2, "1"}, {RedirectOut, 1, "f"}}},
+		{"cmd >f 2>&1", []redirectCase{{RedirectOut, 1, "f"}, {RedirectDup, 2, "1"}}},
+		{"cmd >&2", []redirectCase{{RedirectDup, 1, "2"}}},
+		{"cmd 3<x", []redirectCase{{RedirectIn, 3, "x"}}},
+		// число не прямо перед оператором — аргумент
+		{"echo 2 >f", []redirectCase{{RedirectOut, 1, "f"}}},
+		{`cat >"$X y"`, []redirectCase{{RedirectOut, 1, "x y"}}},
+		{">f", []redirectCase{{RedirectOut, 1, "f"}}},
+	}
+
+	for _, tt := range tests {
+		list, err := Parse(tt.line)
+		if err != nil {
+			t.Errorf("Parse(%q) error = %v", tt.line, err)
+			continue
+		}
+
+		var got []redirectCase
+		for _, r := range list.Items[0].Pipelines[0].Commands[0].Redirects {
+			got = append(got, redirectCase{r.Op, r.Fd, r.Target.String(testLookup)})
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Parse(%q) redirects = %v, want %v", tt.line, got, tt.want)
+		}
+	}
+}
+
+func TestParseHereDocs(t *testing.T) {
+	list, err := Parse(`cat <<EOF | cat <<'E F' ; cat <<"$X"`)
+	if err != nil {
+		t.Fatalf("Parse error = %v", err)
+	}
+
+	docs := list.HereDocs()
+	want := []struct {
+		delim  string
+		quoted bool
+	}{{"EOF", false}, {"E F", true}, {"$X", true}}
+
+	if len(docs) != len(want) {
+		t.Fatalf("HereDocs() = %d docs, want %d", len(docs), len(want))
+	}
+	for i, doc := range docs {
+		if doc.Op != RedirectHereDoc || doc.Delim != want[i].delim || doc.Quoted != want[i].quoted {
+			t.Errorf("doc %d = %q quoted %v, want %q quoted %v", i, doc.Delim, doc.Quoted, want[i].delim, want[i].quoted)
+		}
+	}
+}
+
+func TestExpandHereDoc(t *testing.T) {
+	body := "a $X ${NONE:-d} 'q' \"$?\"\n\\$X \\\\ \\n\n"
+	got, err := ExpandHereDoc(body, testLookup)
+	if err != nil {
+		t.Fatalf("ExpandHereDoc error = %v", err)
+	}
+
+	want := "a x d 'q' \"1\"\n$X \\ \\n\n"
+	if got != want {
+		t.Errorf("ExpandHereDoc = %q, want %q", got, want)
+	}
+}
+
+// Запись списка: пайплайны цепочек через операторы, & в конце фоновых
+func listShape(list *List) string {
+	ops := map[Kind]string{AndIf: "&&", OrIf: "||"}
+
+	var items []string
+	for _, item := range list.Items {
+		var b strings.Builder
+		for i, pipeline := range item.Pipelines {
+			if i > 0 {
+				b.WriteString(" " + ops[item.Ops[i-1]] + " ")
+			}
+			b.WriteString(pipeline.Text)
+		}
+		if item.Background {
+			b.WriteString(" &")
+		}
+		items = append(items, b.String())
+	}
+
+	return strings.Join(items, "; ")
+}
+
+func TestParseLists(t *testing.T) {
+	tests := []struct {
+		line string
+		want string
+	}{
+		{"a", "a"},
+		{"a; b", "a; b"},
+		{"a;", "a"},
+		{"a && b || c", "a && b || c"},
+		{"a | b && c", "a | b && c"},
+		{"a && b & c", "a && b &; c"},
+		{"sleep 1 &", "sleep 1 &"},
+		{"a||b;c&&d&", "a || b; c && d &"},
+		{"a # b; c", "a"},
+	}
+
+	for _, tt := range tests {
+		list, err := Parse(tt.line)
+		if err != nil {
+			t.Errorf("Parse(%q) error = %v", tt.line, err)
+			continue
+		}
+		if got := listShape(list); got != tt.want {
+			t.Errorf("Parse(%q) = %q, want %q", tt.line, got, tt.want)
+		}
+	}
+
+	// текст цепочки для списка заданий без &
+	list, _ := Parse("sleep 1 && echo done  &")
+	if got := list.Items[0].Text; got != "sleep 1 && echo done" {
+		t.Errorf("AndOr.Text = %q", got)
+	}
+
+	for _, line := range []string{"", "   ", "# comment"} {
+		if list, err := Parse(line); list != nil || err != nil {
+			t.Errorf("Parse(%q) = %v, %v, want nil", line, list, err)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		line string
+		msg  string
+	}{
+		{"| a", "unexpected token `|'"},
+		{"a |", "unexpected token `newline'"},
+		{"a && || b", "unexpected token `||'"},
+		{"a ;; b", "unexpected token `;'"},
+		{"; a", "unexpected token `;'"},
+		{"& a", "unexpected token `&'"},
+		{"a >", "unexpected token `newline'"},
+		{"a > | b", "unexpected token `|'"},
+		{"a 2>", "unexpected token `newline'"},
+		{"(a)", "unexpected token `('"},
+		{"echo 'a", "unexpected end of line while looking for matching `''"},
+		{`echo "a`, "unexpected end of line while looking for matching `\"'"},
+		{"echo ${X", "unexpected end of line while looking for matching `}'"},
+		{"echo ${1X}", "${1X}: bad substitution"},
+		{"echo ${}", "${}: bad substitution"},
+	}
+
+	for _, tt := range tests {
+		_, err := Parse(tt.line)
+		if err == nil {
+			t.Errorf("Parse(%q) error = nil, want %q", tt.line, tt.msg)
+			continue
+		}
+		if got := err.Error(); got != "syntax error: "+tt.msg {
+			t.Errorf("Parse(%q) error = %q, want %q", tt.line, got, "syntax error: "+tt.msg)
+		}
+	}
+}
@@ -0,0 +1,353 @@
+package parser
+
+import "strings"
+
+// Part — часть слова: текст или ссылка на параметр
+type Part struct {
+	// Text — текст без кавычек
+	Text string
+	// Param — имя параметра для $NAME, ${NAME}, $?, $$ и $!, пустое для текста
+	Param string
+	// Default — значение для ${NAME:-default}, когда параметр не задан или
+	// пуст
+	Default    Expr
+	HasDefault bool
+	// Quoted — часть была в кавычках: раскрытый параметр не разбивается на
+	// поля
+	Quoted bool
+}
+
+// Expr — слово из частей в порядке записи, до раскрытия параметров
+type Expr []Part
+
+// Lookup возвращает значение параметра и задан ли он
+type Lookup func(name string) (string, bool)
+
+// Expand раскрывает параметры и возвращает поля слова. Значения параметров
+// вне кавычек разбиваются на поля по пробелам, так что пустое значение без
+// кавычек не дает ни одного поля, а "" дает одно пустое. Значение по
+// умолчанию разбивается по своим кавычкам: ${X:-"a  b"} дает одно поле
+func (w Expr) Expand(lookup Lookup) []string {
+	var b fieldBuilder
+	b.expand(w, lookup, false)
+
+	if b.started {
+		b.fields = append(b.fields, b.field.String())
+	}
+
+	return b.fields
+}
+
+// fieldBuilder собирает поля слова при раскрытии
+type fieldBuilder struct {
+	fields []string
+	field  strings.Builder
+	// started — текущее поле начато, даже если пока пустое, как у ""
+	started bool
+}
+
+// expand добавляет части слова. В значении по умолчанию текст без кавычек
+// тоже разбивается на поля
+func (b *fieldBuilder) expand(w Expr, lookup Lookup, inDefault bool) {
+	for _, part := range w {
+		switch {
+		case part.Param == "":
+			b.add(part.Text, part.Quoted, inDefault && !part.Quoted)
+		case part.usesDefault(lookup):
+			b.expand(part.Default, lookup, true)
+		default:
+			value, _ := lookup(part.Param)
+			b.add(value, part.Quoted, !part.Quoted)
+		}
+	}
+}
+
+func (b *fieldBuilder) add(text string, quoted, split bool) {
+	if !split {
+		b.field.WriteString(text)
+		b.started = b.started || quoted || text != ""
+		return
+	}
+
+	for _, c := range text {
+		if c == ' ' || c == '\t' || c == '\n' {
+			if b.started {
+				b.fields = append(b.fields, b.field.String())
+				b.field.Reset()
+				b.started = false
+			}
+			continue
+		}
+
+		b.field.WriteRune(c)
+		b.started = true
+	}
+}
+
+// String раскрывает параметры без разбиения на поля, как в присваиваниях и
+// перенаправлениях
+func (w Expr) String(lookup Lookup) string {
+	var b strings.Builder
+	for _, part := range w {
+		if part.Param == "" {
+			b.WriteString(part.Text)
+		} else {
+			b.WriteString(part.value(lookup))
+		}
+	}
+
+	return b.String()
+}
+
+func (p Part) value(lookup Lookup) string {
+	if p.usesDefault(lookup) {
+		return p.Default.String(lookup)
+	}
+
+	value, _ := lookup(p.Param)
+	return value
+}
+
+// usesDefault — параметр не задан или пуст и у него есть значение по
+// умолчанию
+func (p Part) usesDefault(lookup Lookup) bool {
+	value, ok := lookup(p.Param)
+	return p.HasDefault && (!ok || value == "")
+}
+
+// ExpandHereDoc раскрывает параметры в теле here-document с разделителем без
+// кавычек. Обратный слеш экранирует только $, ` и \
+func ExpandHereDoc(body string, lookup Lookup) (string, error) {
+	ws := &wordScanner{src: body}
+	for ws.pos < len(ws.src) {
+		c := ws.src[ws.pos]
+		switch {
+		case c == '\\' && ws.pos+1 < len(ws.src) && strings.IndexByte("$`\\", ws.src[ws.pos+1]) >= 0:
+			ws.text(ws.src[ws.pos+1:ws.pos+2], true)
+			ws.pos += 2
+		case c == '$':
+			if err := ws.param(true); err != nil {
+				return "", err
+			}
+		default:
+			ws.text(ws.src[ws.pos:ws.pos+1], true)
+			ws.pos++
+		}
+	}
+
+	return ws.parts.String(lookup), nil
+}
+
+// wordScanner собирает части слова из src начиная с pos
+type wordScanner struct {
+	src string
+	pos int
+
+	parts Expr
+	// value — текст слова без кавычек, где параметры записаны как в src
+	value  strings.Builder
+	quoted bool
+}
+
+func (ws *wordScanner) text(text string, quoted bool) {
+	ws.value.WriteString(text)
+	if n := len(ws.parts); n > 0 && ws.parts[n-1].Param == "" && ws.parts[n-1].Quoted == quoted {
+		ws.parts[n-1].Text += text
+		return
+	}
+
+	ws.parts = append(ws.parts, Part{Text: text, Quoted: quoted})
+}
+
+// word читает слово до пробела или оператора вне кавычек по правилам POSIX:
+// одинарные кавычки сохраняют все символы как есть, в двойных кавычках
+// обратный слеш экранирует только $ ` " \ и перевод строки, вне кавычек —
+// любой символ
+func (ws *wordScanner) word() error {
+	for ws.pos < len(ws.src) && !strings.ContainsRune(metachars, rune(ws.src[ws.pos])) {
+		switch ws.src[ws.pos] {
+		case '\\':
+			ws.quoted = true
+			// Обратный слеш в конце строки ничего не экранирует, перевод строки
+			// после него — продолжение строки
+			if ws.pos+1 < len(ws.src) && ws.src[ws.pos+1] != '\n' {
+				ws.text(ws.src[ws.pos+1:ws.pos+2], true)
+			}
+			ws.pos = min(ws.pos+2, len(ws.src))
+		case '\'':
+			end := strings.IndexByte(ws.src[ws.pos+1:], '\'')
+			if end < 0 {
+				return &SyntaxError{ws.pos, "unexpected end of line while looking for matching `''"}
+			}
+			ws.quoted = true
+
+			ws.text(ws.src[ws.pos+1:ws.pos+1+end], true)
+			ws.pos += end + 2
+		case '"':
+			ws.quoted = true
+			if err := ws.doubleQuoted(); err != nil {
+				return err
+			}
+		case '$':
+			if err := ws.param(false); err != nil {
+				return err
+			}
+		default:
+			ws.text(ws.src[ws.pos:ws.pos+1], false)
+			ws.pos++
+		}
+	}
+
+	return nil
+}
+
+// doubleQuoted читает двойные кавычки, открытые на pos
+func (ws *wordScanner) doubleQuoted() error {
+	start := ws.pos
+	// Пустые кавычки тоже дают слово
+	ws.text("", true)
+
+	for ws.pos++; ws.pos < len(ws.src); {
+		switch c := ws.src[ws.pos]; {
+		case c == '"':
+			ws.pos++
+			return nil
+		case c == '\\' && ws.pos+1 < len(ws.src) && strings.IndexByte("$`\"\\\n", ws.src[ws.pos+1]) >= 0:
+			if ws.src[ws.pos+1] != '\n' {
+				ws.text(ws.src[ws.pos+1:ws.pos+2], true)
+			}
+			ws.pos += 2
+		case c == '$':
+			if err := ws.param(true); err != nil {
+				return err
+			}
+		default:
+			ws.text(ws.src[ws.pos:ws.pos+1], true)
+			ws.pos++
+		}
+	}
+
+	return &SyntaxError{start, "unexpected end of line while looking for matching `\"'"}
+}
+
+func isNameStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isNameChar(c byte) bool {
+	return isNameStart(c) || (c >= '0' && c <= '9')
+}
+
+// param читает $NAME, ${NAME}, ${NAME:-default} или специальный параметр на
+// pos. $ без имени остается текстом
+func (ws *wordScanner) param(quoted bool) error {
+	start := ws.pos
+	rest := ws.src[start+1:]
+
+	switch {
+	case rest != "" && strings.IndexByte("?$!", rest[0]) >= 0:
+		ws.parts = append(ws.parts, Part{Param: rest[:1], Quoted: quoted})
+		ws.pos += 2
+	case rest != "" && isNameStart(rest[0]):
+		n := 1
+		for n < len(rest) && isNameChar(rest[n]) {
+			n++
+		}
+		ws.parts = append(ws.parts, Part{Param: rest[:n], Quoted: quoted})
+		ws.pos += 1 + n
+	case strings.HasPrefix(rest, "{"):
+		return ws.braced(quoted)
+	default:
+		ws.text("$", quoted)
+		ws.pos++
+		return nil
+	}
+
+	ws.value.WriteString(ws.src[start:ws.pos])
+	return nil
+}
+
+// braced читает ${NAME} или ${NAME:-default}, вложенные ${...} в default
+// разрешены
+func (ws *wordScanner) braced(quoted bool) error {
+	start := ws.pos
+	depth := 0
+	end := -1
+	for i := start + 2; i < len(ws.src) && end < 0; i++ {
+		switch {
+		case ws.src[i] == '\\':
+			i++
+		case strings.HasPrefix(ws.src[i:], "${"):
+			depth++
+			i++
+		case ws.src[i] == '}' && depth > 0:
+			depth--
+		case ws.src[i] == '}':
+			end = i
+		}
+	}
+	if end < 0 {
+		return &SyntaxError{start, "unexpected end of line while looking for matching `}'"}
+	}
+
+	inner := ws.src[start+2 : end]
+	name, def, hasDefault := strings.Cut(inner, ":-")
+	if name == "" || !(strings.IndexByte("?$!", name[0]) >= 0 && len(name) == 1) && !ValidName(name) {
+		return &SyntaxError{start, "${" + inner + "}: bad substitution"}
+	}
+
+	part := Part{Param: name, HasDefault: hasDefault, Quoted: quoted}
+	if hasDefault {
+		defaultScanner := &wordScanner{src: def}
+		if err := defaultScanner.fields(quoted); err != nil {
+			return err
+		}
+		part.Default = defaultScanner.parts
+	}
+
+	ws.parts = append(ws.parts, part)
+	ws.value.WriteString(ws.src[start : end+1])
+	ws.pos = end + 1
+	return nil
+}
+
+// fields читает весь src как значение по умолчанию: пробелы в нем не
+// разделяют слова, кавычки и параметры работают как в слове
+func (ws *wordScanner) fields(quoted bool) error {
+	for ws.pos < len(ws.src) {
+		c := ws.src[ws.pos]
+		switch {
+		case c == '"' || c == '\'' || c == '\\' || c == '$':
+			// Слово останавливается на пробелах, поэтому читаем по кускам
+			sub := &wordScanner{src: ws.src, pos: ws.pos}
+			if err := sub.word(); err != nil {
+				return err
+			}
+			for _, part := range sub.parts {
+				part.Quoted = part.Quoted || quoted
+				ws.parts = append(ws.parts, part)
+			}
+			ws.pos = max(sub.pos, ws.pos+1)
+		default:
+			ws.text(ws.src[ws.pos:ws.pos+1], quoted)
+			ws.pos++
+		}
+	}
+
+	return nil
+}
+
+// ValidName проверяет имя переменной: буквы, цифры и _, не с цифры
+func ValidName(name string) bool {
+	if name == "" || !isNameStart(name[0]) {
+		return false
+	}
+
+	for i := 1; i < len(name); i++ {
+		if !isNameChar(name[i]) {
+			return false
+		}
+	}
+
+	return true
+}
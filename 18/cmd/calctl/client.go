@@ -0,0 +1,99 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+	"wb_l2/18/internal/model"
+)
+
+type apiClient struct {
+	server string
+	http   *http.Client
+}
+
+func newAPIClient(server string) *apiClient {
+	return &apiClient{
+		server: strings.TrimRight(server, "/"),
+		http:   &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// envelope is the union of the success and the error responses of the API
+type envelope struct {
+	Message string             `json:"message"`
+	Data    json.RawMessage    `json:"data"`
+	Error   string             `json:"error"`
+	Fields  []model.FieldError `json:"fields"`
+}
+
+type apiError struct {
+	Status  int
+	Message string
+	Fields  []model.FieldError
+}
+
+func (e *apiError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s (%d)", e.Message, e.Status)
+	for _, field := range e.Fields {
+		b.WriteString("\n  " + field.String())
+	}
+
+	return b.String()
+}
+
+func (c *apiClient) get(path string, query url.Values, data any) (string, error) {
+	req, err := http.NewRequest("GET", c.server+path+"?"+query.Encode(), nil)
+	if err != nil {
+		return "", err
+	}
+
+	return c.do(req, data)
+}
+
+func (c *apiClient) post(path string, body any, data any) (string, error) {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequest("POST", c.server+path, bytes.NewReader(payload))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	return c.do(req, data)
+}
+
+// do sends the request and decodes the data of the response into data, the
+// message of the response is returned
+func (c *apiClient) do(req *http.Request, data any) (string, error) {
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("Unable to reach the server: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var env envelope
+	if err := json.NewDecoder(resp.Body).Decode(&env); err != nil {
+		return "", fmt.Errorf("Unexpected response from the server (%d): %w", resp.StatusCode, err)
+	}
+
+	if resp.StatusCode >= 400 {
+		return "", &apiError{Status: resp.StatusCode, Message: env.Error, Fields: env.Fields}
+	}
+
+	if data != nil && len(env.Data) > 0 {
+		if err := json.Unmarshal(env.Data, data); err != nil {
+			return "", fmt.Errorf("Unexpected data in the response: %w", err)
+		}
+	}
+
+	return env.Message, nil
+}
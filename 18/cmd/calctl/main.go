@@ -0,0 +1,301 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"wb_l2/18/internal/model"
+	"wb_l2/18/pkg/date"
+
+	"github.com/urfave/cli/v3"
+)
+
+func main() {
+	cmd := &cli.Command{
+		Name:  "calctl",
+		Usage: "command-line client for the calendar API",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:    "server",
+				Usage:   "base URL of the calendar server",
+				Value:   "http://localhost:8080",
+				Sources: cli.EnvVars("CALCTL_SERVER"),
+			},
+			&cli.IntFlag{
+				Name:    "user",
+				Usage:   "id of the user acting on the calendar",
+				Sources: cli.EnvVars("CALCTL_USER"),
+			},
+			&cli.StringFlag{
+				Name:    "output",
+				Aliases: []string{"o"},
+				Usage:   "output format: table, json or csv",
+				Value:   "table",
+			},
+		},
+		Commands: []*cli.Command{
+			{
+				Name:  "create",
+				Usage: "create an event",
+				Flags: []cli.Flag{
+					&cli.StringFlag{Name: "name", Usage: "event name", Required: true},
+					&cli.StringFlag{Name: "date", Usage: "event date, e.g. 2024-01-15, 15.01.2024, 2024-W03-1, today, next monday, +3d", Required: true},
+					&cli.IntFlag{Name: "calendar", Usage: "calendar id, the default calendar when omitted"},
+				},
+				Action: create,
+			},
+			{
+				Name:      "list",
+				Usage:     "list events for a day, week or month",
+				ArgsUsage: "day|week|month",
+				Flags: []cli.Flag{
+					&cli.StringFlag{Name: "date", Usage: "first day of the period", Value: "today"},
+					&cli.StringFlag{Name: "calendars", Usage: "comma-separated calendar ids, visible calendars when omitted"},
+					&cli.BoolFlag{Name: "shared", Usage: "include events shared with the user", Value: true},
+					&cli.StringFlag{Name: "mode", Usage: "rolling lists from --date on, calendar lists the week or month containing it"},
+					&cli.StringFlag{Name: "week-start", Usage: "first day of calendar weeks, monday by default"},
+					&cli.StringFlag{Name: "date-format", Usage: "layout of dates: date, rfc3339, dotted, week or ordinal"},
+				},
+				Action: list,
+			},
+			{
+				Name:  "update",
+				Usage: "change name, date or calendar of an event",
+				Flags: []cli.Flag{
+					&cli.IntFlag{Name: "id", Usage: "event id", Required: true},
+					&cli.StringFlag{Name: "name", Usage: "new name"},
+					&cli.StringFlag{Name: "date", Usage: "new date"},
+					&cli.IntFlag{Name: "calendar", Usage: "new calendar id"},
+				},
+				Action: update,
+			},
+			{
+				Name:  "delete",
+				Usage: "delete an event",
+				Flags: []cli.Flag{
+					&cli.IntFlag{Name: "id", Usage: "event id", Required: true},
+				},
+				Action: remove,
+			},
+			{
+				Name:  "export",
+				Usage: "export own events of a period, CSV unless --output is given",
+				Flags: []cli.Flag{
+					&cli.StringFlag{Name: "from", Usage: "first day", Value: "today"},
+					&cli.StringFlag{Name: "to", Usage: "last day", Value: "+1m"},
+				},
+				Action: export,
+			},
+		},
+	}
+
+	if err := cmd.Run(context.Background(), os.Args); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// parseDate turns human input into the YYYY-MM-DD the server expects
+func parseDate(cmd *cli.Command, flag string) (string, error) {
+	day, err := date.Parse(cmd.String(flag), date.Lenient, time.Now())
+	if err != nil {
+		return "", fmt.Errorf("--%s: %w", flag, err)
+	}
+
+	return date.StringFromTime(day), nil
+}
+
+func requireUser(cmd *cli.Command) (int, error) {
+	userID := cmd.Int("user")
+	if userID <= 0 {
+		return 0, fmt.Errorf("--user (or CALCTL_USER) must be a positive user id")
+	}
+
+	return userID, nil
+}
+
+func create(ctx context.Context, cmd *cli.Command) error {
+	userID, err := requireUser(cmd)
+	if err != nil {
+		return err
+	}
+
+	day, err := parseDate(cmd, "date")
+	if err != nil {
+		return err
+	}
+
+	event := &model.EventOut{
+		Name:       cmd.String("name"),
+		Date:       day,
+		UserID:     userID,
+		CalendarID: cmd.Int("calendar"),
+	}
+
+	var created struct {
+		ID int `json:"id"`
+	}
+	if _, err := newAPIClient(cmd.String("server")).post("/create_event", event, &created); err != nil {
+		return err
+	}
+	event.ID = created.ID
+
+	return printEvents(os.Stdout, cmd.String("output"), []*model.EventOut{event})
+}
+
+func list(ctx context.Context, cmd *cli.Command) error {
+	period := cmd.Args().First()
+	if !slices.Contains([]string{"day", "week", "month"}, period) || cmd.Args().Len() > 1 {
+		return fmt.Errorf("Usage: calctl list day|week|month")
+	}
+
+	userID, err := requireUser(cmd)
+	if err != nil {
+		return err
+	}
+
+	day, err := parseDate(cmd, "date")
+	if err != nil {
+		return err
+	}
+
+	query := url.Values{
+		"user_id": {strconv.Itoa(userID)},
+		"date":    {day},
+		"shared":  {strconv.FormatBool(cmd.Bool("shared"))},
+	}
+	for flag, param := range map[string]string{
+		"calendars":   "calendars",
+		"mode":        "mode",
+		"week-start":  "week_start",
+		"date-format": "date_format",
+	} {
+		if value := cmd.String(flag); value != "" {
+			query.Set(param, value)
+		}
+	}
+
+	events := make([]*model.EventOut, 0)
+	if _, err := newAPIClient(cmd.String("server")).get("/events_for_"+period, query, &events); err != nil {
+		return err
+	}
+
+	return printEvents(os.Stdout, cmd.String("output"), events)
+}
+
+func update(ctx context.Context, cmd *cli.Command) error {
+	userID, err := requireUser(cmd)
+	if err != nil {
+		return err
+	}
+
+	body := map[string]any{"id": cmd.Int("id"), "user_id": userID}
+
+	if cmd.IsSet("name") {
+		body["name"] = cmd.String("name")
+	}
+
+	if cmd.IsSet("date") {
+		day, err := parseDate(cmd, "date")
+		if err != nil {
+			return err
+		}
+		body["date"] = day
+	}
+
+	if cmd.IsSet("calendar") {
+		body["calendar_id"] = cmd.Int("calendar")
+	}
+
+	event := new(model.EventOut)
+	if _, err := newAPIClient(cmd.String("server")).post("/update_event", body, event); err != nil {
+		return err
+	}
+
+	return printEvents(os.Stdout, cmd.String("output"), []*model.EventOut{event})
+}
+
+func remove(ctx context.Context, cmd *cli.Command) error {
+	userID, err := requireUser(cmd)
+	if err != nil {
+		return err
+	}
+
+	body := map[string]any{"id": cmd.Int("id"), "user_id": userID}
+
+	message, err := newAPIClient(cmd.String("server")).post("/delete_event", body, nil)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println(message)
+	return nil
+}
+
+// export walks the calendar months of the period and keeps the events within
+// it
+func export(ctx context.Context, cmd *cli.Command) error {
+	userID, err := requireUser(cmd)
+	if err != nil {
+		return err
+	}
+
+	from, err := date.Parse(cmd.String("from"), date.Lenient, time.Now())
+	if err != nil {
+		return fmt.Errorf("--from: %w", err)
+	}
+
+	to, err := date.Parse(cmd.String("to"), date.Lenient, time.Now())
+	if err != nil {
+		return fmt.Errorf("--to: %w", err)
+	}
+
+	if to.Before(from) {
+		return fmt.Errorf("--to must not be before --from")
+	}
+
+	client := newAPIClient(cmd.String("server"))
+	events := make([]*model.EventOut, 0)
+
+	first := time.Date(from.Year(), from.Month(), 1, 0, 0, 0, 0, time.UTC)
+	for month := first; !month.After(to); month = month.AddDate(0, 1, 0) {
+		query := url.Values{
+			"user_id": {strconv.Itoa(userID)},
+			"date":    {date.StringFromTime(month)},
+			"mode":    {"calendar"},
+			"shared":  {"false"},
+		}
+
+		window := make([]*model.EventOut, 0)
+		if _, err := client.get("/events_for_month", query, &window); err != nil {
+			return err
+		}
+
+		for _, event := range window {
+			if event.Date < date.StringFromTime(from) || event.Date > date.StringFromTime(to) {
+				continue
+			}
+
+			events = append(events, event)
+		}
+	}
+
+	slices.SortFunc(events, func(a, b *model.EventOut) int {
+		if a.Date != b.Date {
+			return strings.Compare(a.Date, b.Date)
+		}
+		return a.ID - b.ID
+	})
+
+	format := "csv"
+	if cmd.IsSet("output") {
+		format = cmd.String("output")
+	}
+
+	return printEvents(os.Stdout, format, events)
+}
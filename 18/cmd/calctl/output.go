@@ -0,0 +1,60 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"text/tabwriter"
+	"wb_l2/18/internal/model"
+)
+
+var outputFormats = []string{"table", "json", "csv"}
+
+func printEvents(w io.Writer, format string, events []*model.EventOut) error {
+	switch format {
+	case "table":
+		return printTable(w, events)
+	case "json":
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(events)
+	case "csv":
+		return printCSV(w, events)
+	default:
+		return fmt.Errorf("Unknown output format %q, use one of %v", format, outputFormats)
+	}
+}
+
+func printTable(w io.Writer, events []*model.EventOut) error {
+	if len(events) == 0 {
+		_, err := fmt.Fprintln(w, "No events")
+		return err
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tDATE\tNAME\tUSER\tCALENDAR")
+	for _, event := range events {
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%d\t%d\n", event.ID, event.Date, event.Name, event.UserID, event.CalendarID)
+	}
+
+	return tw.Flush()
+}
+
+func printCSV(w io.Writer, events []*model.EventOut) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"id", "name", "date", "user_id", "calendar_id"})
+	for _, event := range events {
+		cw.Write([]string{
+			strconv.Itoa(event.ID),
+			event.Name,
+			event.Date,
+			strconv.Itoa(event.UserID),
+			strconv.Itoa(event.CalendarID),
+		})
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
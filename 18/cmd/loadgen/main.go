@@ -0,0 +1,147 @@
+// Command loadgen drives a mix of event requests against the calendar server
+// and reports throughput, latency percentiles and errors per operation
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math/rand/v2"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+	"wb_l2/18/internal/api/handler"
+	"wb_l2/18/internal/api/middleware"
+	"wb_l2/18/internal/api/openapi"
+	"wb_l2/18/internal/config"
+	"wb_l2/18/internal/repository"
+	"wb_l2/18/internal/service"
+
+	"github.com/urfave/cli/v3"
+)
+
+func main() {
+	cmd := &cli.Command{
+		Name:  "loadgen",
+		Usage: "load generator for the calendar server",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:  "url",
+				Usage: "base URL of the server, an in-process server with in-memory storage when omitted",
+			},
+			&cli.DurationFlag{Name: "duration", Usage: "how long to send requests", Value: 10 * time.Second},
+			&cli.IntFlag{Name: "requests", Usage: "stop after this many requests, 0 for no limit"},
+			&cli.IntFlag{Name: "concurrency", Aliases: []string{"c"}, Usage: "requests in flight", Value: 8},
+			&cli.StringFlag{
+				Name:  "mix",
+				Usage: "weights of operations: create, list, update and delete",
+				Value: "create=30,list=50,update=15,delete=5",
+			},
+			&cli.IntFlag{Name: "users", Usage: "events are spread over users 1..users", Value: 50},
+			&cli.IntFlag{Name: "preload", Usage: "events created before the measured run", Value: 1000},
+			&cli.Uint64Flag{Name: "seed", Usage: "seed of the request generator, random when 0"},
+		},
+		Action: run,
+	}
+
+	if err := cmd.Run(context.Background(), os.Args); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func run(ctx context.Context, cmd *cli.Command) error {
+	weights, err := parseMix(cmd.String("mix"))
+	if err != nil {
+		return fmt.Errorf("--mix: %w", err)
+	}
+
+	concurrency := int(cmd.Int("concurrency"))
+	users := int(cmd.Int("users"))
+	if concurrency <= 0 || users <= 0 {
+		return fmt.Errorf("--concurrency and --users must be positive")
+	}
+
+	server := strings.TrimRight(cmd.String("url"), "/")
+	if server == "" {
+		inProcess := httptest.NewServer(newInProcessHandler())
+		defer inProcess.Close()
+		server = inProcess.URL
+	}
+
+	client := &http.Client{
+		Timeout:   10 * time.Second,
+		Transport: &http.Transport{MaxIdleConnsPerHost: concurrency},
+	}
+
+	seed := cmd.Uint64("seed")
+	if seed == 0 {
+		seed = rand.Uint64()
+	}
+
+	events := &pool{}
+	newWorker := func(i int) *worker {
+		return &worker{
+			client: client,
+			server: server,
+			users:  users,
+			mix:    weights,
+			pool:   events,
+			rnd:    rand.New(rand.NewPCG(seed, uint64(i))),
+		}
+	}
+
+	loader := newWorker(-1)
+	loader.mix = &mix{ops: []operation{opCreate}, weights: []int{1}, total: 1}
+	for range cmd.Int("preload") {
+		if _, status, _, err := loader.next(); err != nil || status != http.StatusCreated {
+			return fmt.Errorf("preload failed with status %d: %v", status, err)
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt)
+	defer stop()
+	ctx, cancel := context.WithTimeout(ctx, cmd.Duration("duration"))
+	defer cancel()
+
+	limit := cmd.Int("requests")
+	var sent atomic.Int64
+	recorder := newRecorder()
+
+	fmt.Fprintf(os.Stderr, "sending requests to %s with %d workers, seed %d\n", server, concurrency, seed)
+
+	start := time.Now()
+	var wg sync.WaitGroup
+	for i := range concurrency {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			w := newWorker(i)
+			for ctx.Err() == nil {
+				if limit > 0 && sent.Add(1) > int64(limit) {
+					return
+				}
+
+				op, status, latency, err := w.next()
+				recorder.record(op, status, latency, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	recorder.report(os.Stdout, time.Since(start))
+	return nil
+}
+
+// newInProcessHandler serves the API like the app does, without request logs
+func newInProcessHandler() http.Handler {
+	cfg := config.Default()
+	svc := service.NewService(repository.NewRepository(repository.InMemory), cfg)
+
+	return middleware.Chain(handler.NewRouter(svc, cfg), middleware.Validate(openapi.Spec()))
+}
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+type operation string
+
+const (
+	opCreate operation = "create"
+	opList   operation = "list"
+	opUpdate operation = "update"
+	opDelete operation = "delete"
+)
+
+var operations = []operation{opCreate, opList, opUpdate, opDelete}
+
+// mix picks operations with the given weights
+type mix struct {
+	ops     []operation
+	weights []int
+	total   int
+}
+
+// parseMix reads weights like "create=30,list=50,update=15,delete=5", missing
+// operations get 0
+func parseMix(s string) (*mix, error) {
+	m := &mix{}
+	for _, part := range strings.Split(s, ",") {
+		name, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			return nil, fmt.Errorf("mix entry %q must look like create=30", part)
+		}
+
+		op := operation(name)
+		if !slices.Contains(operations, op) {
+			return nil, fmt.Errorf("unknown operation %q, expected one of %v", name, operations)
+		}
+		if slices.Contains(m.ops, op) {
+			return nil, fmt.Errorf("operation %q is given twice", name)
+		}
+
+		weight, err := strconv.Atoi(value)
+		if err != nil || weight < 0 {
+			return nil, fmt.Errorf("weight of %q must be a non-negative integer", name)
+		}
+
+		m.ops = append(m.ops, op)
+		m.weights = append(m.weights, weight)
+		m.total += weight
+	}
+
+	if m.total == 0 {
+		return nil, fmt.Errorf("mix must have a positive weight")
+	}
+
+	return m, nil
+}
+
+func (m *mix) pick(rnd *rand.Rand) operation {
+	n := rnd.IntN(m.total)
+	for i, weight := range m.weights {
+		if n < weight {
+			return m.ops[i]
+		}
+		n -= weight
+	}
+
+	panic("unreachable")
+}
+
+// event is a created event that updates and deletes can target
+type event struct {
+	id     int
+	userID int
+}
+
+// pool holds events created during the run. Deletes take events out of it,
+// so no two deletes go for the same event
+type pool struct {
+	events []event
+	mu     sync.Mutex
+}
+
+func (p *pool) add(e event) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.events = append(p.events, e)
+}
+
+func (p *pool) pick(rnd *rand.Rand, remove bool) (event, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if len(p.events) == 0 {
+		return event{}, false
+	}
+
+	i := rnd.IntN(len(p.events))
+	e := p.events[i]
+	if remove {
+		p.events[i] = p.events[len(p.events)-1]
+		p.events = p.events[:len(p.events)-1]
+	}
+
+	return e, true
+}
+
+// worker sends requests of the mix one at a time
+type worker struct {
+	client *http.Client
+	server string
+	users  int
+	mix    *mix
+	pool   *pool
+	rnd    *rand.Rand
+}
+
+var epoch = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func (w *worker) date() string {
+	return epoch.AddDate(0, 0, w.rnd.IntN(366)).Format(time.DateOnly)
+}
+
+// next sends one request and tells which operation it was, updates and
+// deletes turn into creates while there is nothing to change
+func (w *worker) next() (operation, int, time.Duration, error) {
+	op := w.mix.pick(w.rnd)
+
+	var target event
+	if op == opUpdate || op == opDelete {
+		var ok bool
+		if target, ok = w.pool.pick(w.rnd, op == opDelete); !ok {
+			op = opCreate
+		}
+	}
+
+	start := time.Now()
+	var status int
+	var err error
+	switch op {
+	case opCreate:
+		userID := 1 + w.rnd.IntN(w.users)
+		var created struct {
+			Data struct {
+				ID int `json:"id"`
+			} `json:"data"`
+		}
+		status, err = w.post("/create_event", map[string]any{
+			"name": "Load " + strconv.Itoa(w.rnd.IntN(1000)), "date": w.date(), "user_id": userID,
+		}, &created)
+		if err == nil && status == http.StatusCreated {
+			w.pool.add(event{created.Data.ID, userID})
+		}
+	case opList:
+		period := []string{"day", "week", "month"}[w.rnd.IntN(3)]
+		query := url.Values{"user_id": {strconv.Itoa(1 + w.rnd.IntN(w.users))}, "date": {w.date()}}
+		status, err = w.get("/events_for_" + period + "?" + query.Encode())
+	case opUpdate:
+		status, err = w.post("/update_event", map[string]any{
+			"id": target.id, "name": "Updated " + strconv.Itoa(w.rnd.IntN(1000)), "user_id": target.userID,
+		}, nil)
+	case opDelete:
+		status, err = w.post("/delete_event", map[string]any{"id": target.id, "user_id": target.userID}, nil)
+	}
+
+	return op, status, time.Since(start), err
+}
+
+func (w *worker) get(path string) (int, error) {
+	resp, err := w.client.Get(w.server + path)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	_, err = io.Copy(io.Discard, resp.Body)
+	return resp.StatusCode, err
+}
+
+func (w *worker) post(path string, body any, into any) (int, error) {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return 0, err
+	}
+
+	resp, err := w.client.Post(w.server+path, "application/json", bytes.NewReader(data))
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if into == nil {
+		_, err = io.Copy(io.Discard, resp.Body)
+		return resp.StatusCode, err
+	}
+
+	return resp.StatusCode, json.NewDecoder(resp.Body).Decode(into)
+}
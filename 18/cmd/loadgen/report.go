@@ -0,0 +1,120 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strconv"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+// stats collects latencies and outcomes of every request of an operation
+type stats struct {
+	latencies []time.Duration
+	statuses  map[string]int
+	errors    int
+}
+
+type recorder struct {
+	stats map[operation]*stats
+	mu    sync.Mutex
+}
+
+func newRecorder() *recorder {
+	return &recorder{stats: make(map[operation]*stats)}
+}
+
+// record counts transport errors and statuses other than 2xx and 304 as
+// errors
+func (r *recorder) record(op operation, status int, latency time.Duration, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	s, ok := r.stats[op]
+	if !ok {
+		s = &stats{statuses: make(map[string]int)}
+		r.stats[op] = s
+	}
+
+	s.latencies = append(s.latencies, latency)
+	switch {
+	case err != nil:
+		s.statuses["transport"]++
+		s.errors++
+	case status >= 200 && status < 300, status == 304:
+		s.statuses[strconv.Itoa(status)]++
+	default:
+		s.statuses[strconv.Itoa(status)]++
+		s.errors++
+	}
+}
+
+// percentile of sorted latencies, nearest rank
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+
+	rank := int(p/100*float64(len(sorted))+0.5) - 1
+	return sorted[max(0, min(rank, len(sorted)-1))]
+}
+
+func (r *recorder) report(out io.Writer, elapsed time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(w, "op\trequests\trps\terrors\tp50\tp90\tp99\tmax\tstatuses\t")
+
+	all := &stats{statuses: make(map[string]int)}
+	for _, op := range operations {
+		s, ok := r.stats[op]
+		if !ok {
+			continue
+		}
+		writeRow(w, string(op), s, elapsed)
+
+		all.latencies = append(all.latencies, s.latencies...)
+		all.errors += s.errors
+		for status, n := range s.statuses {
+			all.statuses[status] += n
+		}
+	}
+	writeRow(w, "total", all, elapsed)
+
+	w.Flush()
+}
+
+func writeRow(w io.Writer, name string, s *stats, elapsed time.Duration) {
+	sorted := slices.Clone(s.latencies)
+	slices.Sort(sorted)
+
+	statuses := ""
+	for _, status := range slices.Sorted(maps.Keys(s.statuses)) {
+		statuses += fmt.Sprintf(" %s:%d", status, s.statuses[status])
+	}
+
+	errorRate := 0.0
+	if len(sorted) > 0 {
+		errorRate = 100 * float64(s.errors) / float64(len(sorted))
+	}
+
+	fmt.Fprintf(w, "%s\t%d\t%.1f\t%.2f%%\t%s\t%s\t%s\t%s\t%s\t\n",
+		name, len(sorted), float64(len(sorted))/elapsed.Seconds(), errorRate,
+		round(percentile(sorted, 50)), round(percentile(sorted, 90)), round(percentile(sorted, 99)),
+		round(percentile(sorted, 100)), statuses)
+}
+
+func round(d time.Duration) time.Duration {
+	switch {
+	case d > time.Millisecond:
+		return d.Round(10 * time.Microsecond)
+	case d > time.Microsecond:
+		return d.Round(100 * time.Nanosecond)
+	default:
+		return d
+	}
+}
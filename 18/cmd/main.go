@@ -15,7 +15,11 @@ func main() {
 		os.Exit(1)
 	}
 
-	app := app.NewApp(config)
+	app, err := app.NewApp(config)
+	if err != nil {
+		slog.Error(err.Error())
+		os.Exit(1)
+	}
 
 	if err := app.Run(context.Background()); err != nil {
 		slog.Error(err.Error())
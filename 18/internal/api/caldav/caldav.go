@@ -0,0 +1,604 @@
+// Package caldav exposes events of every user as a CalDAV calendar collection
+// at /dav/{user_id}/ with one /dav/{user_id}/{name}.ics resource per event.
+// Events created over CalDAV keep the name the client chose, other events are
+// named by their id
+package caldav
+
+import (
+	"encoding/xml"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+	"wb_l2/18/internal/model"
+	"wb_l2/18/internal/repository/inmemory/event"
+	"wb_l2/18/internal/service"
+	"wb_l2/18/pkg/ics"
+)
+
+const (
+	Prefix = "/dav/"
+
+	syncTokenPrefix = "http://wb_l2/ns/sync/"
+	contentType     = "text/calendar; charset=utf-8"
+
+	// calendar objects are tiny, anything bigger is not an event of ours
+	maxBodySize = 1 << 20
+)
+
+const (
+	allowCollection = "OPTIONS, PROPFIND, REPORT"
+	allowResource   = "OPTIONS, GET, HEAD, PUT, DELETE, PROPFIND"
+)
+
+type Handler struct {
+	events *service.EventService
+}
+
+func NewHandler(events *service.EventService) *Handler {
+	return &Handler{events: events}
+}
+
+// ServeHTTP routes /dav/{user_id}/ to the collection and
+// /dav/{user_id}/{name}.ics to a resource
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	rest, ok := strings.CutPrefix(r.URL.Path, Prefix)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	user, name, _ := strings.Cut(rest, "/")
+	userID, err := strconv.Atoi(user)
+	if err != nil || userID <= 0 || strings.Contains(name, "/") {
+		http.NotFound(w, r)
+		return
+	}
+
+	w.Header().Set("DAV", "1, 3, calendar-access")
+
+	if name == "" {
+		h.serveCollection(w, r, userID)
+		return
+	}
+
+	if !strings.HasSuffix(name, ".ics") {
+		http.NotFound(w, r)
+		return
+	}
+
+	h.serveResource(w, r, userID, strings.TrimSuffix(name, ".ics"))
+}
+
+func (h *Handler) serveCollection(w http.ResponseWriter, r *http.Request, userID int) {
+	switch r.Method {
+	case "OPTIONS":
+		w.Header().Set("Allow", allowCollection)
+	case "PROPFIND":
+		h.propfindCollection(w, r, userID)
+	case "REPORT":
+		h.report(w, r, userID)
+	default:
+		w.Header().Set("Allow", allowCollection)
+		http.Error(w, "Method is not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (h *Handler) serveResource(w http.ResponseWriter, r *http.Request, userID int, name string) {
+	switch r.Method {
+	case "OPTIONS":
+		w.Header().Set("Allow", allowResource)
+	case "GET", "HEAD":
+		h.get(w, r, userID, name)
+	case "PUT":
+		h.put(w, r, userID, name)
+	case "DELETE":
+		h.delete(w, r, userID, name)
+	case "PROPFIND":
+		h.propfindResource(w, r, userID, name)
+	default:
+		w.Header().Set("Allow", allowResource)
+		http.Error(w, "Method is not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (h *Handler) propfindCollection(w http.ResponseWriter, r *http.Request, userID int) {
+	names, ok := readPropfind(w, r)
+	if !ok {
+		return
+	}
+
+	changes, err := h.events.Changes(userID, 0)
+	if err != nil {
+		internalError(w, err)
+		return
+	}
+
+	responses := []response{{
+		Href:      collectionHref(userID),
+		Propstats: properties(collectionProps(userID, changes.Version), names.or(collectionAllProps)),
+	}}
+
+	// Depth: infinity is served as 1, the collection has no nested ones
+	if r.Header.Get("Depth") != "0" {
+		for _, event := range changes.Changed {
+			responses = append(responses, eventResponse(event, names.or(resourceAllProps)))
+		}
+	}
+
+	writeMultistatus(w, responses, "")
+}
+
+func (h *Handler) propfindResource(w http.ResponseWriter, r *http.Request, userID int, name string) {
+	names, ok := readPropfind(w, r)
+	if !ok {
+		return
+	}
+
+	event, ok := h.find(w, userID, name)
+	if !ok {
+		return
+	}
+
+	writeMultistatus(w, []response{eventResponse(event, names.or(resourceAllProps))}, "")
+}
+
+func (h *Handler) report(w http.ResponseWriter, r *http.Request, userID int) {
+	decoder := xml.NewDecoder(io.LimitReader(r.Body, maxBodySize))
+
+	var start xml.StartElement
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			http.Error(w, "Invalid XML body", http.StatusBadRequest)
+			return
+		}
+
+		if element, ok := token.(xml.StartElement); ok {
+			start = element
+			break
+		}
+	}
+
+	switch start.Name {
+	case xml.Name{Space: nsCalDAV, Local: "calendar-query"}:
+		var query calendarQuery
+		if err := decoder.DecodeElement(&query, &start); err != nil {
+			http.Error(w, "Invalid calendar-query", http.StatusBadRequest)
+			return
+		}
+		h.calendarQuery(w, userID, &query)
+	case xml.Name{Space: nsCalDAV, Local: "calendar-multiget"}:
+		var multiget calendarMultiget
+		if err := decoder.DecodeElement(&multiget, &start); err != nil {
+			http.Error(w, "Invalid calendar-multiget", http.StatusBadRequest)
+			return
+		}
+		h.calendarMultiget(w, userID, &multiget)
+	case xml.Name{Space: nsDAV, Local: "sync-collection"}:
+		var sync syncCollection
+		if err := decoder.DecodeElement(&sync, &start); err != nil {
+			http.Error(w, "Invalid sync-collection", http.StatusBadRequest)
+			return
+		}
+		h.syncCollection(w, userID, &sync)
+	default:
+		writeError(w, http.StatusForbidden, xml.Name{Space: nsDAV, Local: "supported-report"})
+	}
+}
+
+// calendarQuery supports the VCALENDAR/VEVENT filter with an optional
+// time-range, which is the query clients use to fetch a visible period
+func (h *Handler) calendarQuery(w http.ResponseWriter, userID int, query *calendarQuery) {
+	filter := query.Filter.CompFilter
+	if filter.Name != "" && filter.Name != "VCALENDAR" {
+		writeError(w, http.StatusForbidden, xml.Name{Space: nsCalDAV, Local: "valid-filter"})
+		return
+	}
+
+	var from, to time.Time
+	for _, component := range filter.CompFilters {
+		if component.Name != "VEVENT" {
+			writeError(w, http.StatusForbidden, xml.Name{Space: nsCalDAV, Local: "supported-filter"})
+			return
+		}
+
+		if component.TimeRange == nil {
+			continue
+		}
+
+		var err error
+		from, to, err = parseTimeRange(component.TimeRange)
+		if err != nil {
+			writeError(w, http.StatusForbidden, xml.Name{Space: nsCalDAV, Local: "valid-filter"})
+			return
+		}
+	}
+
+	changes, err := h.events.Changes(userID, 0)
+	if err != nil {
+		internalError(w, err)
+		return
+	}
+
+	responses := make([]response, 0)
+	for _, event := range changes.Changed {
+		if !overlaps(event, from, to) {
+			continue
+		}
+
+		responses = append(responses, eventResponse(event, requested(query.Prop).or(reportProps)))
+	}
+
+	writeMultistatus(w, responses, "")
+}
+
+func (h *Handler) calendarMultiget(w http.ResponseWriter, userID int, multiget *calendarMultiget) {
+	responses := make([]response, 0, len(multiget.Hrefs))
+	for _, href := range multiget.Hrefs {
+		name := strings.TrimSuffix(strings.TrimPrefix(strings.TrimSpace(href), collectionHref(userID)), ".ics")
+		if unescaped, err := url.PathUnescape(name); err == nil {
+			name = unescaped
+		}
+
+		found, err := h.lookup(userID, name)
+		if errors.Is(err, event.ErrorEventNotFound) {
+			responses = append(responses, response{Href: href, Status: status(http.StatusNotFound)})
+			continue
+		}
+		if err != nil {
+			internalError(w, err)
+			return
+		}
+
+		responses = append(responses, eventResponse(found, requested(multiget.Prop).or(reportProps)))
+	}
+
+	writeMultistatus(w, responses, "")
+}
+
+// syncCollection reports events changed since the token along with the
+// deleted ones, an empty token lists the whole collection (RFC 6578)
+func (h *Handler) syncCollection(w http.ResponseWriter, userID int, sync *syncCollection) {
+	since := 0
+	if sync.SyncToken != "" {
+		version, err := strconv.Atoi(strings.TrimPrefix(sync.SyncToken, syncTokenPrefix))
+		if err != nil || !strings.HasPrefix(sync.SyncToken, syncTokenPrefix) || version < 0 {
+			writeError(w, http.StatusForbidden, xml.Name{Space: nsDAV, Local: "valid-sync-token"})
+			return
+		}
+		since = version
+	}
+
+	changes, err := h.events.Changes(userID, since)
+	if err != nil {
+		internalError(w, err)
+		return
+	}
+
+	if since > changes.Version {
+		writeError(w, http.StatusForbidden, xml.Name{Space: nsDAV, Local: "valid-sync-token"})
+		return
+	}
+
+	responses := make([]response, 0, len(changes.Changed)+len(changes.Deleted))
+	for _, event := range changes.Changed {
+		responses = append(responses, eventResponse(event, requested(sync.Prop).or(resourceAllProps)))
+	}
+
+	for _, deleted := range changes.Deleted {
+		responses = append(responses, response{Href: eventHref(deleted), Status: status(http.StatusNotFound)})
+	}
+
+	writeMultistatus(w, responses, syncToken(changes.Version))
+}
+
+func (h *Handler) get(w http.ResponseWriter, r *http.Request, userID int, name string) {
+	event, ok := h.find(w, userID, name)
+	if !ok {
+		return
+	}
+
+	etag := eTag(event)
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Last-Modified", httpDate(event.UpdatedAt))
+
+	if match := r.Header.Get("If-None-Match"); match == etag || match == "*" {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	data := encodeEvent(event)
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+	w.WriteHeader(http.StatusOK)
+
+	if r.Method == "GET" {
+		w.Write(data)
+	}
+}
+
+// put updates the event named by the resource or creates a new one under the
+// name. Numeric names of missing events are refused, they belong to events
+// created by other APIs
+func (h *Handler) put(w http.ResponseWriter, r *http.Request, userID int, name string) {
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxBodySize))
+	if err != nil {
+		http.Error(w, "Invalid body", http.StatusBadRequest)
+		return
+	}
+
+	parsed, err := decodeEvent(body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, xml.Name{Space: nsCalDAV, Local: "valid-calendar-data"})
+		return
+	}
+	parsed.UserID = userID
+
+	current, err := h.lookup(userID, name)
+	if err != nil && !errors.Is(err, event.ErrorEventNotFound) {
+		internalError(w, err)
+		return
+	}
+
+	if !preconditions(w, r, current) {
+		return
+	}
+
+	if err := parsed.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if current == nil {
+		if _, err := strconv.Atoi(name); err == nil {
+			http.Error(w, "Numeric resource names are reserved for event ids", http.StatusConflict)
+			return
+		}
+
+		parsed.Resource = name
+		if _, _, err := h.events.CreateEvent(parsed); err != nil {
+			serviceError(w, err)
+			return
+		}
+
+		w.Header().Set("ETag", eTag(parsed))
+		w.WriteHeader(http.StatusCreated)
+		return
+	}
+
+	updated, err := h.events.UpdateEvent(current.ID, userID, &model.EventUpdate{Name: parsed.Name, Date: parsed.Date})
+	if err != nil {
+		serviceError(w, err)
+		return
+	}
+
+	w.Header().Set("ETag", eTag(updated))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) delete(w http.ResponseWriter, r *http.Request, userID int, name string) {
+	event, ok := h.find(w, userID, name)
+	if !ok {
+		return
+	}
+
+	if !preconditions(w, r, event) {
+		return
+	}
+
+	if err := h.events.DeleteEvent(event.ID, userID); err != nil {
+		serviceError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// lookup resolves a resource name to an event of the user: the name a client
+// stored the event under or the id of an event without one. Events of other
+// users are reported as missing
+func (h *Handler) lookup(userID int, name string) (*model.Event, error) {
+	found, err := h.events.GetByResource(userID, name)
+	if !errors.Is(err, event.ErrorEventNotFound) {
+		return found, err
+	}
+
+	id, err := strconv.Atoi(name)
+	if err != nil || id <= 0 {
+		return nil, event.ErrorEventNotFound
+	}
+
+	found, err = h.events.Get(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if found.UserID != userID || found.Resource != "" {
+		return nil, event.ErrorEventNotFound
+	}
+
+	return found, nil
+}
+
+// find is lookup that answers 404 and 500 itself
+func (h *Handler) find(w http.ResponseWriter, userID int, name string) (*model.Event, bool) {
+	found, err := h.lookup(userID, name)
+	if errors.Is(err, event.ErrorEventNotFound) {
+		http.Error(w, "Event is not found", http.StatusNotFound)
+		return nil, false
+	}
+	if err != nil {
+		internalError(w, err)
+		return nil, false
+	}
+
+	return found, true
+}
+
+// preconditions checks If-Match and If-None-Match against the current event,
+// nil when the resource doesn't exist yet
+func preconditions(w http.ResponseWriter, r *http.Request, current *model.Event) bool {
+	etag := ""
+	if current != nil {
+		etag = eTag(current)
+	}
+
+	if match := r.Header.Get("If-Match"); match != "" && (etag == "" || (match != "*" && match != etag)) {
+		http.Error(w, "Precondition failed", http.StatusPreconditionFailed)
+		return false
+	}
+
+	if match := r.Header.Get("If-None-Match"); match != "" && etag != "" && (match == "*" || match == etag) {
+		http.Error(w, "Precondition failed", http.StatusPreconditionFailed)
+		return false
+	}
+
+	return true
+}
+
+func serviceError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, model.InvalidFormat):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, model.OutsideWorkingHours):
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+	case errors.Is(err, service.Forbidden):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case errors.Is(err, event.ErrorEventNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	default:
+		internalError(w, err)
+	}
+}
+
+func internalError(w http.ResponseWriter, err error) {
+	slog.Error(err.Error())
+	http.Error(w, "Something went wrong, try again later", http.StatusInternalServerError)
+}
+
+func readPropfind(w http.ResponseWriter, r *http.Request) (requested, bool) {
+	var propfind propfindRequest
+	if err := readXML(io.LimitReader(r.Body, maxBodySize), &propfind); err != nil {
+		http.Error(w, "Invalid XML body", http.StatusBadRequest)
+		return nil, false
+	}
+
+	// an empty body and allprop ask for the same
+	if propfind.AllProp != nil {
+		return nil, true
+	}
+
+	return requested(propfind.Prop), true
+}
+
+// requested is the list of properties asked by a client, nil means the
+// default ones
+type requested []xml.Name
+
+func (r requested) or(defaults []xml.Name) []xml.Name {
+	if r == nil {
+		return defaults
+	}
+
+	return r
+}
+
+var (
+	collectionAllProps = []xml.Name{propResourceType, propDisplayName, propCTag, propSyncToken, propComponentSet}
+	resourceAllProps   = []xml.Name{propResourceType, propETag, propContentType, propContentLength, propLastModified}
+	reportProps        = []xml.Name{propETag, propCalendarData}
+)
+
+func collectionProps(userID, version int) map[xml.Name]string {
+	return map[xml.Name]string{
+		propResourceType: element(xml.Name{Space: nsDAV, Local: "collection"}, "") + element(xml.Name{Space: nsCalDAV, Local: "calendar"}, ""),
+		propDisplayName:  escape("Events of user " + strconv.Itoa(userID)),
+		propCTag:         escape(syncToken(version)),
+		propSyncToken:    escape(syncToken(version)),
+		propComponentSet: `<C:comp name="VEVENT"/>`,
+	}
+}
+
+func eventResponse(event *model.Event, names []xml.Name) response {
+	data := encodeEvent(event)
+
+	props := map[xml.Name]string{
+		propResourceType:  "",
+		propETag:          escape(eTag(event)),
+		propContentType:   escape(contentType),
+		propContentLength: strconv.Itoa(len(data)),
+		propLastModified:  httpDate(event.UpdatedAt),
+		propCalendarData:  escape(string(data)),
+	}
+
+	return response{
+		Href:      eventHref(event),
+		Propstats: properties(props, names),
+	}
+}
+
+// eTag changes with every version of the event, versions are never reused
+func eTag(event *model.Event) string {
+	return `"` + strconv.Itoa(event.Version) + `"`
+}
+
+func syncToken(version int) string {
+	return syncTokenPrefix + strconv.Itoa(version)
+}
+
+func collectionHref(userID int) string {
+	return Prefix + strconv.Itoa(userID) + "/"
+}
+
+func resourceHref(userID, id int) string {
+	return collectionHref(userID) + strconv.Itoa(id) + ".ics"
+}
+
+func eventHref(event *model.Event) string {
+	if event.Resource == "" {
+		return resourceHref(event.UserID, event.ID)
+	}
+
+	return collectionHref(event.UserID) + url.PathEscape(event.Resource) + ".ics"
+}
+
+// parseTimeRange reads UTC date-times of a time-range, a missing bound is left
+// zero
+func parseTimeRange(tr *timeRange) (time.Time, time.Time, error) {
+	var from, to time.Time
+	var err error
+
+	if tr.Start != "" {
+		if from, err = time.Parse(ics.DateTimeLayout, tr.Start); err != nil {
+			return from, to, err
+		}
+	}
+
+	if tr.End != "" {
+		if to, err = time.Parse(ics.DateTimeLayout, tr.End); err != nil {
+			return from, to, err
+		}
+	}
+
+	return from, to, nil
+}
+
+// overlaps checks the all-day event [date, date+1d) against [from, to)
+func overlaps(event *model.Event, from, to time.Time) bool {
+	end := event.Date.AddDate(0, 0, 1)
+
+	if !from.IsZero() && !end.After(from) {
+		return false
+	}
+
+	if !to.IsZero() && !event.Date.Before(to) {
+		return false
+	}
+
+	return true
+}
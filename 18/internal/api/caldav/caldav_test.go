@@ -0,0 +1,348 @@
+package caldav
+
+import (
+	"encoding/xml"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+	"wb_l2/18/internal/config"
+	"wb_l2/18/internal/model"
+	"wb_l2/18/internal/repository"
+	"wb_l2/18/internal/service"
+	"wb_l2/18/pkg/ics"
+)
+
+// davClient is a minimal WebDAV client speaking to the handler over HTTP
+type davClient struct {
+	t    *testing.T
+	base string
+}
+
+type davResult struct {
+	Responses []struct {
+		Href      string `xml:"DAV: href"`
+		Status    string `xml:"DAV: status"`
+		Propstats []struct {
+			Status string `xml:"DAV: status"`
+			Prop   struct {
+				ETag         string `xml:"DAV: getetag"`
+				SyncToken    string `xml:"DAV: sync-token"`
+				CalendarData string `xml:"urn:ietf:params:xml:ns:caldav calendar-data"`
+			} `xml:"DAV: prop"`
+		} `xml:"DAV: propstat"`
+	} `xml:"DAV: response"`
+	SyncToken string `xml:"DAV: sync-token"`
+}
+
+func setupDAV(t *testing.T) (*davClient, *service.Service) {
+	repo := repository.NewRepository(repository.InMemory)
+	svc := service.NewService(repo, config.Default())
+
+	server := httptest.NewServer(NewHandler(svc.Event))
+	t.Cleanup(server.Close)
+
+	return &davClient{t: t, base: server.URL}, svc
+}
+
+func (c *davClient) do(method, path string, headers map[string]string, body string) (*http.Response, string) {
+	req, err := http.NewRequest(method, c.base+path, strings.NewReader(body))
+	if err != nil {
+		c.t.Fatalf("Failed to build request: %v", err)
+	}
+
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		c.t.Fatalf("%s %s failed: %v", method, path, err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		c.t.Fatalf("Failed to read response: %v", err)
+	}
+
+	return resp, string(data)
+}
+
+func (c *davClient) multistatus(method, path, depth, body string) *davResult {
+	resp, data := c.do(method, path, map[string]string{"Depth": depth, "Content-Type": "application/xml"}, body)
+	if resp.StatusCode != http.StatusMultiStatus {
+		c.t.Fatalf("%s %s: expected status %d, got %d: %s", method, path, http.StatusMultiStatus, resp.StatusCode, data)
+	}
+
+	var result davResult
+	if err := xml.Unmarshal([]byte(data), &result); err != nil {
+		c.t.Fatalf("Failed to parse multistatus: %v\n%s", err, data)
+	}
+
+	return &result
+}
+
+func (c *davClient) put(path, name, date string, headers map[string]string) *http.Response {
+	if headers == nil {
+		headers = map[string]string{}
+	}
+	headers["Content-Type"] = "text/calendar"
+
+	body := "BEGIN:VCALENDAR\r\nVERSION:2.0\r\nPRODID:-//test//EN\r\nBEGIN:VEVENT\r\nUID:client-uid\r\n" +
+		"DTSTART;VALUE=DATE:" + date + "\r\nSUMMARY:" + name + "\r\nEND:VEVENT\r\nEND:VCALENDAR\r\n"
+
+	resp, _ := c.do("PUT", path, headers, body)
+	return resp
+}
+
+func createEvent(t *testing.T, svc *service.Service, name, date string, userID int) int {
+	day, _ := time.Parse("2006-01-02", date)
+
+	id, _, err := svc.Event.CreateEvent(&model.Event{Name: name, Date: day, UserID: userID})
+	if err != nil {
+		t.Fatalf("Failed to create event: %v", err)
+	}
+
+	return id
+}
+
+func TestCalDAV_PutGetDelete(t *testing.T) {
+	client, svc := setupDAV(t)
+
+	location := "/dav/1/new-event.ics"
+	resp := client.put(location, "Planning, Q1", "20240115", map[string]string{"If-None-Match": "*"})
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("Expected status %d, got %d", http.StatusCreated, resp.StatusCode)
+	}
+	etag := resp.Header.Get("ETag")
+
+	resp, body := client.do("GET", location, nil, "")
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("ETag") != etag {
+		t.Fatalf("Expected event with ETag %s, got %d %s", etag, resp.StatusCode, resp.Header.Get("ETag"))
+	}
+	if !strings.Contains(body, `SUMMARY:Planning\, Q1`) || !strings.Contains(body, "DTSTART;VALUE=DATE:20240115") {
+		t.Errorf("Unexpected calendar data:\n%s", body)
+	}
+
+	// the event is the same one the JSON API serves
+	events, err := svc.Event.List(url.Values{"user_id": {"1"}, "date": {"2024-01-15"}}, service.Day)
+	if err != nil || len(events) != 1 || events[0].Name != "Planning, Q1" {
+		t.Fatalf("Expected the event in the list, got %v, %v", events, err)
+	}
+
+	resp = client.put(location, "Stale", "20240116", map[string]string{"If-Match": `"999"`})
+	if resp.StatusCode != http.StatusPreconditionFailed {
+		t.Errorf("Expected status %d for a stale ETag, got %d", http.StatusPreconditionFailed, resp.StatusCode)
+	}
+
+	resp = client.put(location, "Review", "20240116", map[string]string{"If-Match": etag})
+	if resp.StatusCode != http.StatusNoContent {
+		t.Fatalf("Expected status %d, got %d", http.StatusNoContent, resp.StatusCode)
+	}
+	if resp.Header.Get("ETag") == etag {
+		t.Error("Expected the ETag to change after an update")
+	}
+
+	// other users don't see the resource, and the event has no second name
+	if resp, _ := client.do("GET", "/dav/2/new-event.ics", nil, ""); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected status %d for a foreign event, got %d", http.StatusNotFound, resp.StatusCode)
+	}
+	if resp, _ := client.do("GET", "/dav/1/1.ics", nil, ""); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected status %d for the id of a named event, got %d", http.StatusNotFound, resp.StatusCode)
+	}
+
+	if resp, _ := client.do("DELETE", location, nil, ""); resp.StatusCode != http.StatusNoContent {
+		t.Fatalf("Expected status %d, got %d", http.StatusNoContent, resp.StatusCode)
+	}
+
+	if resp, _ := client.do("GET", location, nil, ""); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected status %d after delete, got %d", http.StatusNotFound, resp.StatusCode)
+	}
+}
+
+func TestCalDAV_PutSameNameTwice(t *testing.T) {
+	client, svc := setupDAV(t)
+
+	if resp := client.put("/dav/1/abc.ics", "Planning", "20240115", nil); resp.StatusCode != http.StatusCreated {
+		t.Fatalf("Expected status %d, got %d", http.StatusCreated, resp.StatusCode)
+	}
+	if resp := client.put("/dav/1/abc.ics", "Review", "20240115", nil); resp.StatusCode != http.StatusNoContent {
+		t.Fatalf("Expected status %d for the second put, got %d", http.StatusNoContent, resp.StatusCode)
+	}
+
+	events, err := svc.Event.List(url.Values{"user_id": {"1"}, "date": {"2024-01-15"}}, service.Day)
+	if err != nil || len(events) != 1 || events[0].Name != "Review" {
+		t.Fatalf("Expected one updated event, got %v, %v", events, err)
+	}
+
+	resp, body := client.do("GET", "/dav/1/abc.ics", nil, "")
+	if resp.StatusCode != http.StatusOK || !strings.Contains(body, "SUMMARY:Review") {
+		t.Errorf("Expected the event at the name it was put to, got %d:\n%s", resp.StatusCode, body)
+	}
+
+	result := client.multistatus("PROPFIND", "/dav/1/", "1", "")
+	if len(result.Responses) != 2 || result.Responses[1].Href != "/dav/1/abc.ics" {
+		t.Errorf("Expected the event listed under its name, got %+v", result.Responses)
+	}
+
+	if resp := client.put("/dav/1/42.ics", "Numeric", "20240115", nil); resp.StatusCode != http.StatusConflict {
+		t.Errorf("Expected status %d for a numeric name, got %d", http.StatusConflict, resp.StatusCode)
+	}
+}
+
+func TestCalDAV_InvalidCalendarData(t *testing.T) {
+	client, _ := setupDAV(t)
+
+	resp, _ := client.do("PUT", "/dav/1/bad.ics", map[string]string{"Content-Type": "text/calendar"}, "BEGIN:VCALENDAR\r\nEND:VCALENDAR\r\n")
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected status %d, got %d", http.StatusBadRequest, resp.StatusCode)
+	}
+
+	resp, _ = client.do("POST", "/dav/1/", nil, "")
+	if resp.StatusCode != http.StatusMethodNotAllowed || resp.Header.Get("Allow") != allowCollection {
+		t.Errorf("Expected status %d with Allow, got %d %q", http.StatusMethodNotAllowed, resp.StatusCode, resp.Header.Get("Allow"))
+	}
+}
+
+func TestCalDAV_PropfindAndCalendarQuery(t *testing.T) {
+	client, svc := setupDAV(t)
+
+	createEvent(t, svc, "New Year", "2024-01-01", 1)
+	createEvent(t, svc, "Standup", "2024-01-15", 1)
+	createEvent(t, svc, "Vacation", "2024-02-10", 1)
+	createEvent(t, svc, "Foreign", "2024-01-15", 2)
+
+	result := client.multistatus("PROPFIND", "/dav/1/", "1", `<?xml version="1.0"?>
+<D:propfind xmlns:D="DAV:"><D:prop><D:getetag/><D:sync-token/><D:unknown/></D:prop></D:propfind>`)
+	if len(result.Responses) != 4 {
+		t.Fatalf("Expected the collection and 3 events, got %d responses", len(result.Responses))
+	}
+
+	collection := result.Responses[0]
+	if collection.Href != "/dav/1/" || len(collection.Propstats) != 2 {
+		t.Fatalf("Expected found and missing propstats for the collection, got %+v", collection)
+	}
+	if !strings.HasPrefix(collection.Propstats[0].Prop.SyncToken, syncTokenPrefix) {
+		t.Errorf("Expected a sync token, got %q", collection.Propstats[0].Prop.SyncToken)
+	}
+
+	result = client.multistatus("PROPFIND", "/dav/1/", "0", "")
+	if len(result.Responses) != 1 {
+		t.Errorf("Expected only the collection with Depth 0, got %d responses", len(result.Responses))
+	}
+
+	result = client.multistatus("REPORT", "/dav/1/", "1", `<?xml version="1.0"?>
+<C:calendar-query xmlns:D="DAV:" xmlns:C="urn:ietf:params:xml:ns:caldav">
+  <D:prop><D:getetag/><C:calendar-data/></D:prop>
+  <C:filter>
+    <C:comp-filter name="VCALENDAR">
+      <C:comp-filter name="VEVENT">
+        <C:time-range start="20240101T120000Z" end="20240201T000000Z"/>
+      </C:comp-filter>
+    </C:comp-filter>
+  </C:filter>
+</C:calendar-query>`)
+	if len(result.Responses) != 2 {
+		t.Fatalf("Expected 2 events in January, got %d", len(result.Responses))
+	}
+
+	for _, resp := range result.Responses {
+		prop := resp.Propstats[0].Prop
+		if prop.ETag == "" || !strings.Contains(prop.CalendarData, "BEGIN:VEVENT") {
+			t.Errorf("Expected ETag and calendar data for %s, got %+v", resp.Href, prop)
+		}
+
+		event, err := decodeEvent([]byte(prop.CalendarData))
+		if err != nil || (event.Name != "New Year" && event.Name != "Standup") {
+			t.Errorf("Unexpected event in the range: %+v, %v", event, err)
+		}
+	}
+
+	result = client.multistatus("REPORT", "/dav/1/", "1", `<?xml version="1.0"?>
+<C:calendar-multiget xmlns:D="DAV:" xmlns:C="urn:ietf:params:xml:ns:caldav">
+  <D:prop><C:calendar-data/></D:prop>
+  <D:href>/dav/1/3.ics</D:href>
+  <D:href>/dav/1/4.ics</D:href>
+</C:calendar-multiget>`)
+	if len(result.Responses) != 2 || result.Responses[1].Status != "HTTP/1.1 404 Not Found" {
+		t.Errorf("Expected the foreign event to be missing, got %+v", result.Responses)
+	}
+}
+
+func TestCalDAV_SyncCollection(t *testing.T) {
+	client, svc := setupDAV(t)
+
+	first := createEvent(t, svc, "First", "2024-01-15", 1)
+	second := createEvent(t, svc, "Second", "2024-01-16", 1)
+
+	syncReport := func(token string) *davResult {
+		return client.multistatus("REPORT", "/dav/1/", "0", `<?xml version="1.0"?>
+<D:sync-collection xmlns:D="DAV:">
+  <D:sync-token>`+token+`</D:sync-token>
+  <D:sync-level>1</D:sync-level>
+  <D:prop><D:getetag/></D:prop>
+</D:sync-collection>`)
+	}
+
+	result := syncReport("")
+	if len(result.Responses) != 2 || result.SyncToken == "" {
+		t.Fatalf("Expected initial sync of 2 events with a token, got %+v", result)
+	}
+	token := result.SyncToken
+
+	if result := syncReport(token); len(result.Responses) != 0 || result.SyncToken != token {
+		t.Errorf("Expected no changes and the same token, got %+v", result)
+	}
+
+	createEvent(t, svc, "Third", "2024-01-17", 1)
+	createEvent(t, svc, "Foreign", "2024-01-17", 2)
+	if _, err := svc.Event.UpdateEvent(first, 1, &model.EventUpdate{Name: "First, renamed"}); err != nil {
+		t.Fatalf("Failed to update event: %v", err)
+	}
+	if err := svc.Event.DeleteEvent(second, 1); err != nil {
+		t.Fatalf("Failed to delete event: %v", err)
+	}
+
+	result = syncReport(token)
+	if len(result.Responses) != 3 || result.SyncToken == token {
+		t.Fatalf("Expected 2 changed and 1 deleted event with a new token, got %+v", result)
+	}
+
+	deleted := result.Responses[2]
+	if deleted.Href != resourceHref(1, second) || deleted.Status != "HTTP/1.1 404 Not Found" {
+		t.Errorf("Expected the deleted event reported as 404, got %+v", deleted)
+	}
+
+	resp, _ := client.do("REPORT", "/dav/1/", nil, `<D:sync-collection xmlns:D="DAV:"><D:sync-token>bogus</D:sync-token></D:sync-collection>`)
+	if resp.StatusCode != http.StatusForbidden {
+		t.Errorf("Expected status %d for an invalid token, got %d", http.StatusForbidden, resp.StatusCode)
+	}
+}
+
+func TestICS_RoundTrip(t *testing.T) {
+	event := &model.Event{
+		ID:   7,
+		Name: strings.Repeat("Long; name, with \\ escapes ", 5),
+		Date: time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC),
+	}
+
+	data := encodeEvent(event)
+	for _, line := range strings.Split(string(data), "\r\n") {
+		if len(line) > ics.MaxLineLength {
+			t.Errorf("Line is not folded: %q", line)
+		}
+	}
+
+	decoded, err := decodeEvent(data)
+	if err != nil {
+		t.Fatalf("Failed to decode: %v", err)
+	}
+
+	if decoded.Name != event.Name || !decoded.Date.Equal(event.Date) {
+		t.Errorf("Expected %q on %s, got %q on %s", event.Name, event.Date, decoded.Name, decoded.Date)
+	}
+}
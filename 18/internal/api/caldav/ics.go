@@ -0,0 +1,72 @@
+package caldav
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"wb_l2/18/internal/model"
+	"wb_l2/18/pkg/ics"
+)
+
+const prodID = "-//wb_l2//calendar//EN"
+
+var invalidCalendar = fmt.Errorf("Invalid iCalendar object")
+
+// uid names an event in iCalendar, it is stable across updates
+func uid(id int) string {
+	return strconv.Itoa(id) + "@wb_l2"
+}
+
+// encodeEvent renders an event as a VCALENDAR with a single all-day VEVENT
+func encodeEvent(event *model.Event) []byte {
+	var w ics.Writer
+
+	w.Line("BEGIN:VCALENDAR")
+	w.Line("VERSION:2.0")
+	w.Line("PRODID:" + prodID)
+	w.Line("BEGIN:VEVENT")
+	w.Line("UID:" + uid(event.ID))
+	w.Line("DTSTAMP:" + event.UpdatedAt.UTC().Format(ics.DateTimeLayout))
+	w.Line("DTSTART;VALUE=DATE:" + event.Date.Format(ics.DateLayout))
+	w.Line("DTEND;VALUE=DATE:" + event.Date.AddDate(0, 0, 1).Format(ics.DateLayout))
+	w.Line("SUMMARY:" + ics.EscapeText(event.Name))
+	w.Line("END:VEVENT")
+	w.Line("END:VCALENDAR")
+
+	return w.Bytes()
+}
+
+// decodeEvent reads the name and the start date of the first VEVENT. Events
+// are date-only, so the time part of DTSTART is dropped
+func decodeEvent(data []byte) (*model.Event, error) {
+	lines, ok := ics.Parse(string(data))
+	if !ok {
+		return nil, invalidCalendar
+	}
+
+	event := new(model.Event)
+	inEvent, seen := false, false
+	for _, line := range lines {
+		switch {
+		case line.Name == "BEGIN" && strings.EqualFold(line.Value, "VEVENT"):
+			inEvent = !seen
+		case line.Name == "END" && strings.EqualFold(line.Value, "VEVENT"):
+			if inEvent {
+				seen, inEvent = true, false
+			}
+		case !inEvent:
+		case line.Name == "SUMMARY":
+			event.Name = ics.UnescapeText(line.Value)
+		case line.Name == "DTSTART":
+			if event.Date, ok = line.Date(); !ok {
+				return nil, invalidCalendar
+			}
+		}
+	}
+
+	if !seen || event.Name == "" || event.Date.IsZero() {
+		return nil, invalidCalendar
+	}
+
+	return event, nil
+}
@@ -0,0 +1,206 @@
+package caldav
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	nsDAV    = "DAV:"
+	nsCalDAV = "urn:ietf:params:xml:ns:caldav"
+	nsCS     = "http://calendarserver.org/ns/"
+)
+
+// prefixes used in responses, other namespaces get generated ones
+var prefixes = map[string]string{
+	nsDAV:    "D",
+	nsCalDAV: "C",
+	nsCS:     "CS",
+}
+
+var (
+	propResourceType  = xml.Name{Space: nsDAV, Local: "resourcetype"}
+	propDisplayName   = xml.Name{Space: nsDAV, Local: "displayname"}
+	propETag          = xml.Name{Space: nsDAV, Local: "getetag"}
+	propContentType   = xml.Name{Space: nsDAV, Local: "getcontenttype"}
+	propContentLength = xml.Name{Space: nsDAV, Local: "getcontentlength"}
+	propLastModified  = xml.Name{Space: nsDAV, Local: "getlastmodified"}
+	propSyncToken     = xml.Name{Space: nsDAV, Local: "sync-token"}
+	propCTag          = xml.Name{Space: nsCS, Local: "getctag"}
+	propComponentSet  = xml.Name{Space: nsCalDAV, Local: "supported-calendar-component-set"}
+	propCalendarData  = xml.Name{Space: nsCalDAV, Local: "calendar-data"}
+)
+
+// propNames collects the names of the requested properties, their content is
+// ignored
+type propNames []xml.Name
+
+func (p *propNames) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	for {
+		token, err := d.Token()
+		if err != nil {
+			return err
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			*p = append(*p, t.Name)
+			if err := d.Skip(); err != nil {
+				return err
+			}
+		case xml.EndElement:
+			return nil
+		}
+	}
+}
+
+type propfindRequest struct {
+	XMLName xml.Name  `xml:"DAV: propfind"`
+	AllProp *struct{} `xml:"DAV: allprop"`
+	Prop    propNames `xml:"DAV: prop"`
+}
+
+type timeRange struct {
+	Start string `xml:"start,attr"`
+	End   string `xml:"end,attr"`
+}
+
+type compFilter struct {
+	Name        string       `xml:"name,attr"`
+	TimeRange   *timeRange   `xml:"urn:ietf:params:xml:ns:caldav time-range"`
+	CompFilters []compFilter `xml:"urn:ietf:params:xml:ns:caldav comp-filter"`
+}
+
+type calendarQuery struct {
+	Prop   propNames `xml:"DAV: prop"`
+	Filter struct {
+		CompFilter compFilter `xml:"urn:ietf:params:xml:ns:caldav comp-filter"`
+	} `xml:"urn:ietf:params:xml:ns:caldav filter"`
+}
+
+type calendarMultiget struct {
+	Prop  propNames `xml:"DAV: prop"`
+	Hrefs []string  `xml:"DAV: href"`
+}
+
+type syncCollection struct {
+	SyncToken string    `xml:"DAV: sync-token"`
+	Prop      propNames `xml:"DAV: prop"`
+}
+
+// readXML decodes the request body into v, an empty body leaves v untouched
+func readXML(r io.Reader, v any) error {
+	err := xml.NewDecoder(r).Decode(v)
+	if err == io.EOF {
+		return nil
+	}
+
+	return err
+}
+
+type multistatus struct {
+	XMLName   xml.Name   `xml:"D:multistatus"`
+	NsDAV     string     `xml:"xmlns:D,attr"`
+	NsCalDAV  string     `xml:"xmlns:C,attr"`
+	NsCS      string     `xml:"xmlns:CS,attr"`
+	Responses []response `xml:"D:response"`
+	SyncToken string     `xml:"D:sync-token,omitempty"`
+}
+
+type response struct {
+	Href      string     `xml:"D:href"`
+	Propstats []propstat `xml:"D:propstat,omitempty"`
+	Status    string     `xml:"D:status,omitempty"`
+}
+
+type propstat struct {
+	Prop   innerXML `xml:"D:prop"`
+	Status string   `xml:"D:status"`
+}
+
+type innerXML struct {
+	Inner string `xml:",innerxml"`
+}
+
+func status(code int) string {
+	return fmt.Sprintf("HTTP/1.1 %d %s", code, http.StatusText(code))
+}
+
+// properties answers a request for names, known properties go to the 200
+// propstat and unknown ones are reported as 404
+func properties(known map[xml.Name]string, names []xml.Name) []propstat {
+	var found, missing strings.Builder
+	for _, name := range names {
+		value, ok := known[name]
+		if !ok {
+			missing.WriteString(element(name, ""))
+			continue
+		}
+
+		found.WriteString(element(name, value))
+	}
+
+	res := make([]propstat, 0, 2)
+	if found.Len() > 0 {
+		res = append(res, propstat{innerXML{found.String()}, status(http.StatusOK)})
+	}
+	if missing.Len() > 0 {
+		res = append(res, propstat{innerXML{missing.String()}, status(http.StatusNotFound)})
+	}
+
+	return res
+}
+
+// element renders <prefix:local>inner</prefix:local>, inner is raw XML
+func element(name xml.Name, inner string) string {
+	prefix, ok := prefixes[name.Space]
+	open := ""
+	if !ok {
+		prefix = "X"
+		open = ` xmlns:X="` + escape(name.Space) + `"`
+	}
+
+	tag := prefix + ":" + name.Local
+	if inner == "" {
+		return "<" + tag + open + "/>"
+	}
+
+	return "<" + tag + open + ">" + inner + "</" + tag + ">"
+}
+
+func escape(text string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(text))
+	return b.String()
+}
+
+func writeMultistatus(w http.ResponseWriter, responses []response, syncToken string) {
+	ms := multistatus{
+		NsDAV:     nsDAV,
+		NsCalDAV:  nsCalDAV,
+		NsCS:      nsCS,
+		Responses: responses,
+		SyncToken: syncToken,
+	}
+
+	w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+	w.WriteHeader(http.StatusMultiStatus)
+	io.WriteString(w, xml.Header)
+	xml.NewEncoder(w).Encode(ms)
+}
+
+// writeError reports a failed precondition as a DAV:error body
+func writeError(w http.ResponseWriter, code int, condition xml.Name) {
+	w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+	w.WriteHeader(code)
+	io.WriteString(w, xml.Header)
+	io.WriteString(w, `<D:error xmlns:D="DAV:" xmlns:C="urn:ietf:params:xml:ns:caldav">`+element(condition, "")+`</D:error>`)
+}
+
+func httpDate(t time.Time) string {
+	return t.UTC().Format(http.TimeFormat)
+}
@@ -0,0 +1,29 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"wb_l2/18/internal/model"
+	"wb_l2/18/internal/service"
+	"wb_l2/18/pkg/http/response"
+)
+
+func (h *Handler) Agenda(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		response.MethodNotAllowed(w, "GET")
+		return
+	}
+
+	body, format, err := h.service.Agenda.Agenda(r.URL.Query())
+	if err != nil {
+		switch {
+		case errors.Is(err, service.InvalidQuery):
+			response.Response(w, http.StatusBadRequest, model.ErrorRespFromError(err))
+		default:
+			response.InternalServerError(w)
+		}
+		return
+	}
+
+	response.Content(w, http.StatusOK, format.ContentType(), body)
+}
@@ -0,0 +1,199 @@
+package handler
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+	"wb_l2/18/internal/config"
+	"wb_l2/18/internal/repository"
+	"wb_l2/18/internal/service"
+	"wb_l2/18/pkg/date"
+)
+
+// seedAgenda creates events of user 1 tomorrow and in three days, and a shared
+// event of user 2 tomorrow
+func seedAgenda(t *testing.T, h *Handler) {
+	tomorrow := time.Now().UTC().AddDate(0, 0, 1)
+
+	events := []map[string]interface{}{
+		{"name": "Standup & <b>Review</b>", "date": date.StringFromTime(tomorrow), "user_id": 1},
+		{"name": "Retro", "date": date.StringFromTime(tomorrow.AddDate(0, 0, 3)), "user_id": 1},
+		{"name": "Planning", "date": date.StringFromTime(tomorrow), "user_id": 2},
+		{"name": "Too late", "date": date.StringFromTime(tomorrow.AddDate(0, 0, 7)), "user_id": 1},
+	}
+	for _, event := range events {
+		if w := postJSON(h, "/create_event", event); w.Code != http.StatusCreated {
+			t.Fatalf("Expected status %d, got %d: %s", http.StatusCreated, w.Code, w.Body.String())
+		}
+	}
+
+	w := postJSON(h, "/share_events", map[string]interface{}{"owner_id": 2, "grantee_id": 1, "permission": "read"})
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+}
+
+func getAgenda(t *testing.T, h *Handler, query string) (string, string) {
+	req := httptest.NewRequest("GET", "/agenda?"+query, nil)
+	w := httptest.NewRecorder()
+	h.mux.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+
+	return w.Header().Get("Content-Type"), w.Body.String()
+}
+
+func TestAgenda_Formats(t *testing.T) {
+	h := setupTestHandler()
+	seedAgenda(t, h)
+
+	contentType, body := getAgenda(t, h, "user_id=1&range=week&format=md")
+	if !strings.HasPrefix(contentType, "text/markdown") {
+		t.Errorf("Expected a markdown content type, got %q", contentType)
+	}
+	for _, want := range []string{"# Agenda of user 1", `- Standup & \<b\>Review\</b\>`, "- Retro", "- Planning _(shared by user 2)_"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("Expected %q in the markdown digest:\n%s", want, body)
+		}
+	}
+	if strings.Contains(body, "Too late") {
+		t.Errorf("Expected only 7 days in the weekly digest:\n%s", body)
+	}
+
+	contentType, body = getAgenda(t, h, "user_id=1&range=week&format=html")
+	if !strings.HasPrefix(contentType, "text/html") {
+		t.Errorf("Expected an HTML content type, got %q", contentType)
+	}
+	if !strings.Contains(body, "<li>Standup &amp; &lt;b&gt;Review&lt;/b&gt;</li>") {
+		t.Errorf("Expected the event name to be escaped in HTML:\n%s", body)
+	}
+
+	contentType, body = getAgenda(t, h, "user_id=1")
+	if !strings.HasPrefix(contentType, "text/plain") {
+		t.Errorf("Expected a plain text content type, got %q", contentType)
+	}
+	if !strings.Contains(body, "  - Standup & <b>Review</b>") || strings.Contains(body, "Retro") {
+		t.Errorf("Expected only tomorrow's events in the daily digest:\n%s", body)
+	}
+
+	_, body = getAgenda(t, h, "user_id=3")
+	if !strings.Contains(body, "No events.") {
+		t.Errorf("Expected an empty digest, got:\n%s", body)
+	}
+}
+
+func TestAgenda_InvalidQuery(t *testing.T) {
+	h := setupTestHandler()
+
+	req := httptest.NewRequest("GET", "/agenda?user_id=1&range=year&format=pdf", nil)
+	w := httptest.NewRecorder()
+	h.mux.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("Expected status %d, got %d", http.StatusBadRequest, w.Code)
+	}
+
+	codes := fieldCodes(responseFields(t, w))
+	if codes["range"] != "unknown_value" || codes["format"] != "unknown_value" {
+		t.Errorf("Expected errors for range and format, got %v", codes)
+	}
+}
+
+// smtpStandIn accepts one message and hands its data over the channel
+func smtpStandIn(t *testing.T) (string, <-chan string) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	messages := make(chan string, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		reader := bufio.NewReader(conn)
+		reply := func(line string) { conn.Write([]byte(line + "\r\n")) }
+
+		reply("220 localhost ready")
+		var data strings.Builder
+		inData := false
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				return
+			}
+
+			switch {
+			case inData && line == ".\r\n":
+				inData = false
+				messages <- data.String()
+				reply("250 OK")
+			case inData:
+				data.WriteString(line)
+			case strings.HasPrefix(line, "DATA"):
+				inData = true
+				reply("354 Go ahead")
+			case strings.HasPrefix(line, "QUIT"):
+				reply("221 Bye")
+				return
+			default:
+				reply("250 OK")
+			}
+		}
+	}()
+
+	return listener.Addr().String(), messages
+}
+
+func TestAgenda_Deliver(t *testing.T) {
+	addr, messages := smtpStandIn(t)
+
+	cfg := config.Default()
+	cfg.Agenda.Users = []int{1}
+	cfg.Agenda.Range = "week"
+	cfg.Agenda.Format = "html"
+	cfg.Agenda.OutputDir = t.TempDir()
+	cfg.Agenda.SMTP.Addr = addr
+
+	repo := repository.NewRepository(repository.InMemory)
+	svc := service.NewService(repo, cfg)
+	h := NewHandler(svc)
+	RegisterHandlers(h)
+	seedAgenda(t, h)
+
+	if err := svc.Agenda.Deliver(t.Context()); err != nil {
+		t.Fatalf("Failed to deliver agendas: %v", err)
+	}
+
+	tomorrow := date.StringFromTime(time.Now().UTC().AddDate(0, 0, 1))
+	file, err := os.ReadFile(filepath.Join(cfg.Agenda.OutputDir, "agenda-1-week-"+tomorrow+".html"))
+	if err != nil {
+		t.Fatalf("Expected the digest file: %v", err)
+	}
+	if !strings.Contains(string(file), "<li>Retro</li>") {
+		t.Errorf("Unexpected digest file:\n%s", file)
+	}
+
+	select {
+	case message := <-messages:
+		for _, want := range []string{"To: user1@localhost", "Content-Type: text/html", "<li>Retro</li>"} {
+			if !strings.Contains(message, want) {
+				t.Errorf("Expected %q in the mail:\n%s", want, message)
+			}
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected the digest to be mailed")
+	}
+}
@@ -0,0 +1,87 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"wb_l2/18/internal/model"
+	"wb_l2/18/internal/service"
+	"wb_l2/18/pkg/http/request"
+	"wb_l2/18/pkg/http/response"
+)
+
+func (h *Handler) SetSchedule(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		response.MethodNotAllowed(w, "POST")
+		return
+	}
+
+	body, err := request.ReadBody(w, r)
+	if err != nil {
+		return
+	}
+
+	schedule, err := h.service.Availability.SetSchedule(body)
+	if err != nil {
+		switch {
+		case errors.Is(err, model.InvalidFormat):
+			response.Response(w, http.StatusBadRequest, model.ErrorRespFromError(err))
+		default:
+			response.InternalServerError(w)
+		}
+		return
+	}
+
+	response.Response(
+		w,
+		http.StatusOK,
+		model.ResultWithDataResp("Schedule saved successfully", schedule),
+	)
+}
+
+func (h *Handler) Schedule(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		response.MethodNotAllowed(w, "GET")
+		return
+	}
+
+	schedule, err := h.service.Availability.Schedule(r.URL.Query())
+	if err != nil {
+		switch {
+		case errors.Is(err, service.InvalidQuery):
+			response.Response(w, http.StatusBadRequest, model.ErrorRespFromError(err))
+		default:
+			response.InternalServerError(w)
+		}
+		return
+	}
+
+	response.Response(
+		w,
+		http.StatusOK,
+		model.ResultWithDataResp("Schedule of the user", schedule),
+	)
+}
+
+func (h *Handler) Availability(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		response.MethodNotAllowed(w, "GET")
+		return
+	}
+
+	days, err := h.service.Availability.Availability(r.URL.Query())
+	if err != nil {
+		switch {
+		case errors.Is(err, service.InvalidQuery):
+			response.Response(w, http.StatusBadRequest, model.ErrorRespFromError(err))
+		default:
+			response.InternalServerError(w)
+		}
+		return
+	}
+
+	response.Response(
+		w,
+		http.StatusOK,
+		model.ResultWithDataResp("Availability of the user", days),
+	)
+}
@@ -0,0 +1,244 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"wb_l2/18/internal/config"
+	"wb_l2/18/internal/repository"
+	"wb_l2/18/internal/service"
+)
+
+const holidaysYAML = `
+- date: 2024-01-01
+  name: New Year
+  yearly: true
+`
+
+// the break lasts from January 2nd to 3rd, DTEND is exclusive
+const holidaysICS = "BEGIN:VCALENDAR\r\nVERSION:2.0\r\nPRODID:-//test//EN\r\n" +
+	"BEGIN:VEVENT\r\nUID:break@test\r\nDTSTART;VALUE=DATE:20240102\r\nDTEND;VALUE=DATE:20240104\r\n" +
+	"SUMMARY:Winter break\r\nEND:VEVENT\r\nEND:VCALENDAR\r\n"
+
+func setupAvailabilityTestHandler(t *testing.T) *Handler {
+	dir := t.TempDir()
+	files := map[string]string{"national.yaml": holidaysYAML, "company.ics": holidaysICS}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatalf("Failed to write holiday calendar: %v", err)
+		}
+	}
+
+	repo := repository.NewRepository(repository.InMemory)
+	svc := service.NewService(repo, config.Default())
+	err := svc.Availability.LoadHolidayCalendars(map[string]string{
+		"national": filepath.Join(dir, "national.yaml"),
+		"company":  filepath.Join(dir, "company.ics"),
+	})
+	if err != nil {
+		t.Fatalf("Failed to load holiday calendars: %v", err)
+	}
+
+	h := NewHandler(svc)
+	RegisterHandlers(h)
+	return h
+}
+
+func setSchedule(t *testing.T, h *Handler, outsideHours string) {
+	w := postJSON(h, "/set_schedule", map[string]interface{}{
+		"user_id":   1,
+		"time_zone": "Europe/Moscow",
+		"working_hours": map[string]interface{}{
+			"monday":    []map[string]string{{"start": "09:00", "end": "13:00"}, {"start": "14:00", "end": "18:00"}},
+			"tuesday":   []map[string]string{{"start": "10:00", "end": "16:00"}},
+			"wednesday": []map[string]string{{"start": "10:00", "end": "16:00"}},
+			"thursday":  []map[string]string{{"start": "10:00", "end": "16:00"}},
+			"friday":    []map[string]string{{"start": "10:00", "end": "16:00"}},
+		},
+		"holiday_calendars": []string{"national", "company"},
+		"outside_hours":     outsideHours,
+	})
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+}
+
+func TestAvailability_CombinesHoursHolidaysAndEvents(t *testing.T) {
+	h := setupAvailabilityTestHandler(t)
+	setSchedule(t, h, "allow")
+
+	w := postJSON(h, "/create_event", map[string]interface{}{"name": "Review", "date": "2024-01-05", "user_id": 1})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusCreated, w.Code, w.Body.String())
+	}
+
+	response := getJSON(t, h, "/availability?user_id=1&from=2024-01-01&to=2024-01-07")
+
+	var days []struct {
+		Date    string                   `json:"date"`
+		Working bool                     `json:"working"`
+		Holiday string                   `json:"holiday"`
+		Hours   []map[string]string      `json:"hours"`
+		Events  []map[string]interface{} `json:"events"`
+		Free    bool                     `json:"free"`
+	}
+	data, _ := json.Marshal(response["data"])
+	if err := json.Unmarshal(data, &days); err != nil {
+		t.Fatalf("Failed to unmarshal days: %v", err)
+	}
+
+	if len(days) != 7 {
+		t.Fatalf("Expected 7 days, got %d", len(days))
+	}
+
+	tests := []struct {
+		index   int
+		working bool
+		holiday string
+		free    bool
+	}{
+		{0, false, "New Year", false},
+		{1, false, "Winter break", false},
+		{2, false, "Winter break", false},
+		{3, true, "", true},
+		{4, true, "", false},
+		{5, false, "", false},
+	}
+	for _, tt := range tests {
+		day := days[tt.index]
+		if day.Working != tt.working || day.Holiday != tt.holiday || day.Free != tt.free {
+			t.Errorf("%s: expected working=%v holiday=%q free=%v, got %+v", day.Date, tt.working, tt.holiday, tt.free, day)
+		}
+	}
+
+	if len(days[3].Hours) != 1 || days[3].Hours[0]["start"] != "2024-01-04T10:00:00+03:00" {
+		t.Errorf("Expected working hours in the user's time zone, got %v", days[3].Hours)
+	}
+	if len(days[4].Events) != 1 || days[4].Events[0]["name"] != "Review" {
+		t.Errorf("Expected the event on %s, got %v", days[4].Date, days[4].Events)
+	}
+	if len(days[0].Hours) != 0 {
+		t.Errorf("Expected no working hours on a holiday, got %v", days[0].Hours)
+	}
+}
+
+func TestAvailability_DefaultSchedule(t *testing.T) {
+	h := setupTestHandler()
+
+	response := getJSON(t, h, "/schedule?user_id=7")
+	schedule := response["data"].(map[string]interface{})
+	if schedule["time_zone"] != "UTC" || schedule["outside_hours"] != "allow" {
+		t.Errorf("Expected the default schedule, got %v", schedule)
+	}
+
+	response = getJSON(t, h, "/availability?user_id=7&from=2024-01-13&to=2024-01-15")
+	days := response["data"].([]interface{})
+	saturday, monday := days[0].(map[string]interface{}), days[2].(map[string]interface{})
+	if saturday["working"] != false || monday["working"] != true {
+		t.Errorf("Expected Monday to Friday working days, got %v", days)
+	}
+}
+
+func TestAvailability_InvalidRange(t *testing.T) {
+	h := setupTestHandler()
+
+	tests := map[string]string{
+		"user_id=1&from=2024-01-15&to=2024-01-14": "to",
+		"user_id=1&from=2024-01-01&to=2025-06-01": "to",
+		"user_id=1&to=2024-01-01":                 "from",
+	}
+	for query, field := range tests {
+		req := httptest.NewRequest("GET", "/availability?"+query, nil)
+		w := httptest.NewRecorder()
+		h.mux.ServeHTTP(w, req)
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status %d, got %d", query, http.StatusBadRequest, w.Code)
+			continue
+		}
+
+		if _, ok := fieldCodes(responseFields(t, w))[field]; !ok {
+			t.Errorf("%s: expected an error for %s, got %s", query, field, w.Body.String())
+		}
+	}
+}
+
+func TestCreateEvent_OutsideWorkingHours(t *testing.T) {
+	h := setupAvailabilityTestHandler(t)
+
+	setSchedule(t, h, "warn")
+	w := postJSON(h, "/create_event", map[string]interface{}{"name": "Party", "date": "2024-01-06", "user_id": 1})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusCreated, w.Code, w.Body.String())
+	}
+
+	var created struct {
+		Data struct {
+			Warnings []string `json:"warnings"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &created); err != nil {
+		t.Fatalf("Failed to unmarshal response: %v", err)
+	}
+	if len(created.Data.Warnings) != 1 {
+		t.Errorf("Expected a warning for a Saturday event, got %s", w.Body.String())
+	}
+
+	setSchedule(t, h, "reject")
+	w = postJSON(h, "/create_event", map[string]interface{}{"name": "Party", "date": "2024-01-01", "user_id": 1})
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusUnprocessableEntity, w.Code, w.Body.String())
+	}
+	if code := fieldCodes(responseFields(t, w))["date"]; code != "outside_working_hours" {
+		t.Errorf("Expected code outside_working_hours for date, got %q", code)
+	}
+
+	w = postJSON(h, "/create_event", map[string]interface{}{"name": "Standup", "date": "2024-01-08", "user_id": 1})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status %d for a working day, got %d: %s", http.StatusCreated, w.Code, w.Body.String())
+	}
+	if strings.Contains(w.Body.String(), "warnings") {
+		t.Errorf("Expected no warnings on a working day, got %s", w.Body.String())
+	}
+}
+
+func TestSetSchedule_FieldErrors(t *testing.T) {
+	h := setupAvailabilityTestHandler(t)
+
+	w := postJSON(h, "/set_schedule", map[string]interface{}{
+		"user_id":   1,
+		"time_zone": "Mars/Olympus",
+		"working_hours": map[string]interface{}{
+			"funday":  []map[string]string{{"start": "09:00", "end": "18:00"}},
+			"monday":  []map[string]string{{"start": "18:00", "end": "09:00"}},
+			"tuesday": []map[string]string{{"start": "09:00", "end": "13:00"}, {"start": "12:00", "end": "18:00"}},
+		},
+		"outside_hours": "sometimes",
+	})
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusBadRequest, w.Code, w.Body.String())
+	}
+
+	codes := fieldCodes(responseFields(t, w))
+	expected := map[string]string{
+		"time_zone":               "unknown_value",
+		"working_hours.funday":    "unknown_value",
+		"working_hours.monday[0]": "invalid_time",
+		"working_hours.tuesday":   "invalid_time",
+		"outside_hours":           "unknown_value",
+	}
+	for field, code := range expected {
+		if codes[field] != code {
+			t.Errorf("Expected code %s for %s, got %q", code, field, codes[field])
+		}
+	}
+
+	w = postJSON(h, "/set_schedule", map[string]interface{}{"user_id": 1, "holiday_calendars": []string{"unknown"}})
+	if code := fieldCodes(responseFields(t, w))["holiday_calendars[0]"]; w.Code != http.StatusBadRequest || code != "unknown_value" {
+		t.Errorf("Expected unknown_value for an unknown holiday calendar, got %d %s", w.Code, w.Body.String())
+	}
+}
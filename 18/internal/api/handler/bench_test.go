@@ -0,0 +1,70 @@
+package handler
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// benchHandler has 100 events of user 1 spread over 2024
+func benchHandler(b *testing.B, cacheSize int) *Handler {
+	h := setupTestHandler()
+	h.cache = newListCache(cacheSize)
+
+	for i := range 100 {
+		event := map[string]any{"name": "Event", "date": fmt.Sprintf("2024-%02d-%02d", 1+i%12, 1+i%28), "user_id": 1}
+		if w := postJSON(h, "/create_event", event); w.Code != http.StatusCreated {
+			b.Fatalf("Expected status %d, got %d: %s", http.StatusCreated, w.Code, w.Body.String())
+		}
+	}
+
+	return h
+}
+
+func BenchmarkCreateEvent(b *testing.B) {
+	h := setupTestHandler()
+	body := []byte(`{"name": "Event", "date": "2024-01-15", "user_id": 1}`)
+
+	for b.Loop() {
+		req := httptest.NewRequest("POST", "/create_event", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+
+		h.mux.ServeHTTP(w, req)
+		if w.Code != http.StatusCreated {
+			b.Fatalf("Expected status %d, got %d", http.StatusCreated, w.Code)
+		}
+	}
+}
+
+func BenchmarkListEventsForMonth(b *testing.B) {
+	cases := []struct {
+		name      string
+		cacheSize int
+		etag      bool
+	}{
+		{"uncached", 0, false},
+		{"cached", 16, false},
+		{"not_modified", 0, true},
+	}
+
+	for _, c := range cases {
+		b.Run(c.name, func(b *testing.B) {
+			h := benchHandler(b, c.cacheSize)
+			path := "/events_for_month?user_id=1&date=2024-03-01"
+			etag := ""
+			if c.etag {
+				etag = getWithETag(h, path, "").Header().Get("ETag")
+			}
+
+			for b.Loop() {
+				w := getWithETag(h, path, etag)
+				if w.Code != http.StatusOK && w.Code != http.StatusNotModified {
+					b.Fatalf("Unexpected status %d", w.Code)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,102 @@
+package handler
+
+import (
+	"container/list"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// listCache keeps serialized list responses by URL and drops the least
+// recently used ones. An entry is served only for the list version it was
+// built for, so it is dropped once a write moves the user to a new version.
+// A nil listCache caches nothing
+type listCache struct {
+	size    int
+	entries map[string]*list.Element
+	order   *list.List
+
+	mu sync.Mutex
+}
+
+type cachedList struct {
+	key  string
+	etag string
+	body []byte
+}
+
+func newListCache(size int) *listCache {
+	if size <= 0 {
+		return nil
+	}
+
+	return &listCache{
+		size:    size,
+		entries: make(map[string]*list.Element),
+		order:   list.New(),
+	}
+}
+
+func (c *listCache) get(key, etag string) ([]byte, bool) {
+	if c == nil {
+		return nil, false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+
+	cached := element.Value.(*cachedList)
+	if cached.etag != etag {
+		c.order.Remove(element)
+		delete(c.entries, key)
+		return nil, false
+	}
+
+	c.order.MoveToFront(element)
+	return cached.body, true
+}
+
+func (c *listCache) put(key, etag string, body []byte) {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if element, ok := c.entries[key]; ok {
+		element.Value = &cachedList{key, etag, body}
+		c.order.MoveToFront(element)
+		return
+	}
+
+	c.entries[key] = c.order.PushFront(&cachedList{key, etag, body})
+	if c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cachedList).key)
+	}
+}
+
+// noneMatch tells whether If-None-Match of the request names the etag, weak
+// tags match too as the header is only used for GET
+func noneMatch(r *http.Request, etag string) bool {
+	header := r.Header.Get("If-None-Match")
+	if header == "" {
+		return false
+	}
+
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+		if tag == "*" || tag == etag {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,133 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"wb_l2/18/internal/model"
+	"wb_l2/18/internal/repository/inmemory/calendar"
+	"wb_l2/18/internal/service"
+	"wb_l2/18/pkg/http/request"
+	"wb_l2/18/pkg/http/response"
+)
+
+func (h *Handler) CreateCalendar(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		response.MethodNotAllowed(w, "POST")
+		return
+	}
+
+	body, err := request.ReadBody(w, r)
+	if err != nil {
+		return
+	}
+
+	calendarOut, err := h.service.Calendar.Create(body)
+	if err != nil {
+		switch {
+		case errors.Is(err, model.InvalidFormat):
+			response.Response(w, http.StatusBadRequest, model.ErrorRespFromError(err))
+		case errors.Is(err, calendar.ErrorCalendarExists):
+			response.Response(w, http.StatusConflict, model.ErrorResp(err.Error()))
+		default:
+			response.InternalServerError(w)
+		}
+		return
+	}
+
+	response.Response(
+		w,
+		http.StatusCreated,
+		model.ResultWithDataResp("Calendar created successfully", calendarOut),
+	)
+}
+
+func (h *Handler) ListCalendars(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		response.MethodNotAllowed(w, "GET")
+		return
+	}
+
+	calendars, err := h.service.Calendar.List(r.URL.Query())
+	if err != nil {
+		switch {
+		case errors.Is(err, service.InvalidQuery):
+			response.Response(w, http.StatusBadRequest, model.ErrorRespFromError(err))
+		default:
+			response.InternalServerError(w)
+		}
+		return
+	}
+
+	response.Response(
+		w,
+		http.StatusOK,
+		model.ResultWithDataResp("List of calendars", calendars),
+	)
+}
+
+func (h *Handler) UpdateCalendar(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		response.MethodNotAllowed(w, "POST")
+		return
+	}
+
+	body, err := request.ReadBody(w, r)
+	if err != nil {
+		return
+	}
+
+	calendarOut, err := h.service.Calendar.Update(body)
+	if err != nil {
+		switch {
+		case errors.Is(err, model.InvalidFormat):
+			response.Response(w, http.StatusBadRequest, model.ErrorRespFromError(err))
+		case errors.Is(err, service.Forbidden):
+			response.Response(w, http.StatusForbidden, model.ErrorResp(err.Error()))
+		case errors.Is(err, calendar.ErrorCalendarNotFound):
+			response.Response(w, http.StatusNotFound, model.ErrorResp(err.Error()))
+		case errors.Is(err, calendar.ErrorCalendarExists):
+			response.Response(w, http.StatusConflict, model.ErrorResp(err.Error()))
+		default:
+			response.InternalServerError(w)
+		}
+		return
+	}
+
+	response.Response(
+		w,
+		http.StatusOK,
+		model.ResultWithDataResp("Calendar updated successfully", calendarOut),
+	)
+}
+
+func (h *Handler) DeleteCalendar(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		response.MethodNotAllowed(w, "POST")
+		return
+	}
+
+	body, err := request.ReadBody(w, r)
+	if err != nil {
+		return
+	}
+
+	if err := h.service.Calendar.Delete(body); err != nil {
+		switch {
+		case errors.Is(err, model.InvalidFormat):
+			response.Response(w, http.StatusBadRequest, model.ErrorRespFromError(err))
+		case errors.Is(err, service.Forbidden):
+			response.Response(w, http.StatusForbidden, model.ErrorResp(err.Error()))
+		case errors.Is(err, calendar.ErrorCalendarNotFound):
+			response.Response(w, http.StatusNotFound, model.ErrorResp(err.Error()))
+		default:
+			response.InternalServerError(w)
+		}
+		return
+	}
+
+	response.Response(
+		w,
+		http.StatusOK,
+		model.ResultResp("Calendar deleted successfully"),
+	)
+}
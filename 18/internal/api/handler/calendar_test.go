@@ -0,0 +1,244 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+)
+
+func createCalendar(t *testing.T, h *Handler, name string, userID int) int {
+	w := postJSON(h, "/create_calendar", map[string]interface{}{"name": name, "user_id": userID})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusCreated, w.Code, w.Body.String())
+	}
+
+	var response map[string]interface{}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to unmarshal response: %v", err)
+	}
+
+	return int(response["data"].(map[string]interface{})["id"].(float64))
+}
+
+func createEventIn(t *testing.T, h *Handler, name string, calendarID int) {
+	w := postJSON(h, "/create_event", map[string]interface{}{
+		"name":        name,
+		"date":        "2024-01-15",
+		"user_id":     1,
+		"calendar_id": calendarID,
+	})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusCreated, w.Code, w.Body.String())
+	}
+}
+
+// eventNames lists the day of the seeded events, keyed by name with calendar id
+func eventNames(t *testing.T, h *Handler, query string) map[string]int {
+	response := getJSON(t, h, "/events_for_day?user_id=1&date=2024-01-15"+query)
+
+	names := make(map[string]int)
+	for _, e := range response["data"].([]interface{}) {
+		event := e.(map[string]interface{})
+		calendarID, _ := event["calendar_id"].(float64)
+		names[event["name"].(string)] = int(calendarID)
+	}
+
+	return names
+}
+
+func TestCreateCalendar_Success(t *testing.T) {
+	handler := setupTestHandler()
+
+	createCalendar(t, handler, "Work", 1)
+	createCalendar(t, handler, "Personal", 1)
+	createCalendar(t, handler, "Work", 2)
+
+	response := getJSON(t, handler, "/calendars?user_id=1")
+	calendars := response["data"].([]interface{})
+	if len(calendars) != 2 {
+		t.Fatalf("Expected 2 calendars, got %d", len(calendars))
+	}
+
+	first := calendars[0].(map[string]interface{})
+	if first["name"] != "Work" || first["visible"] != true {
+		t.Errorf("Expected visible Work calendar, got %v", first)
+	}
+}
+
+func TestCreateCalendar_DuplicateName(t *testing.T) {
+	handler := setupTestHandler()
+
+	createCalendar(t, handler, "Work", 1)
+
+	w := postJSON(handler, "/create_calendar", map[string]interface{}{"name": "work", "user_id": 1})
+	if w.Code != http.StatusConflict {
+		t.Errorf("Expected status %d, got %d", http.StatusConflict, w.Code)
+	}
+}
+
+func TestCreateEvent_ForeignCalendar(t *testing.T) {
+	handler := setupTestHandler()
+
+	foreign := createCalendar(t, handler, "On-call", 2)
+
+	w := postJSON(handler, "/create_event", map[string]interface{}{
+		"name":        "Test Event",
+		"date":        "2024-01-15",
+		"user_id":     1,
+		"calendar_id": foreign,
+	})
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("Expected status %d, got %d", http.StatusBadRequest, w.Code)
+	}
+
+	if got := fieldCodes(responseFields(t, w)); got["calendar_id"] != "unknown_value" {
+		t.Errorf("Expected calendar_id field error, got %v", got)
+	}
+}
+
+func TestListEvents_CalendarVisibility(t *testing.T) {
+	handler := setupTestHandler()
+
+	work := createCalendar(t, handler, "Work", 1)
+	personal := createCalendar(t, handler, "Personal", 1)
+
+	createEventIn(t, handler, "Default", 0)
+	createEventIn(t, handler, "Standup", work)
+	createEventIn(t, handler, "Gym", personal)
+
+	if names := eventNames(t, handler, ""); len(names) != 3 {
+		t.Errorf("Expected 3 events, got %v", names)
+	}
+
+	w := postJSON(handler, "/update_calendar", map[string]interface{}{"id": personal, "visible": false, "user_id": 1})
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, w.Code)
+	}
+
+	names := eventNames(t, handler, "")
+	if _, ok := names["Gym"]; ok || len(names) != 2 {
+		t.Errorf("Expected hidden calendar to be excluded, got %v", names)
+	}
+
+	// explicit selection shows hidden calendars too
+	names = eventNames(t, handler, "&calendars=0,2")
+	if _, ok := names["Standup"]; ok || len(names) != 2 {
+		t.Errorf("Expected Default and Gym only, got %v", names)
+	}
+}
+
+func TestUpdateEvent_MovesBetweenCalendars(t *testing.T) {
+	handler := setupTestHandler()
+
+	work := createCalendar(t, handler, "Work", 1)
+	createEventIn(t, handler, "Standup", work)
+
+	w := postJSON(handler, "/update_event", map[string]interface{}{"id": 1, "name": "Daily", "user_id": 1})
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+
+	if names := eventNames(t, handler, ""); names["Daily"] != work {
+		t.Errorf("Expected an omitted calendar_id to keep Work, got %v", names)
+	}
+
+	w = postJSON(handler, "/update_event", map[string]interface{}{"id": 1, "calendar_id": 0, "user_id": 1})
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+
+	names := eventNames(t, handler, "")
+	if calendarID, ok := names["Daily"]; !ok || calendarID != 0 {
+		t.Errorf("Expected Daily moved to the default calendar, got %v", names)
+	}
+}
+
+func TestDeleteCalendar_MovesEvents(t *testing.T) {
+	handler := setupTestHandler()
+
+	work := createCalendar(t, handler, "Work", 1)
+	onCall := createCalendar(t, handler, "On-call", 1)
+
+	createEventIn(t, handler, "Standup", work)
+	createEventIn(t, handler, "Pager", onCall)
+
+	w := postJSON(handler, "/delete_calendar", map[string]interface{}{"id": work, "user_id": 1})
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, w.Code)
+	}
+
+	w = postJSON(handler, "/delete_calendar", map[string]interface{}{"id": onCall, "events": "move", "move_to": onCall, "user_id": 1})
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d for moving into itself, got %d", http.StatusBadRequest, w.Code)
+	}
+
+	names := eventNames(t, handler, "")
+	if calendarID, ok := names["Standup"]; !ok || calendarID != 0 {
+		t.Errorf("Expected Standup moved to the default calendar, got %v", names)
+	}
+
+	if names["Pager"] != onCall {
+		t.Errorf("Expected Pager to stay in On-call, got %v", names)
+	}
+}
+
+func TestDeleteCalendar_DeletesEvents(t *testing.T) {
+	handler := setupTestHandler()
+
+	work := createCalendar(t, handler, "Work", 1)
+	personal := createCalendar(t, handler, "Personal", 1)
+
+	createEventIn(t, handler, "Standup", work)
+	createEventIn(t, handler, "Gym", personal)
+
+	w := postJSON(handler, "/delete_calendar", map[string]interface{}{"id": work, "events": "delete", "user_id": 1})
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, w.Code)
+	}
+
+	names := eventNames(t, handler, "")
+	if _, ok := names["Standup"]; ok || len(names) != 1 {
+		t.Errorf("Expected only Gym to remain, got %v", names)
+	}
+
+	response := getJSON(t, handler, "/calendars?user_id=1")
+	if calendars := response["data"].([]interface{}); len(calendars) != 1 {
+		t.Errorf("Expected 1 calendar left, got %d", len(calendars))
+	}
+}
+
+func TestDeleteCalendar_NotFound(t *testing.T) {
+	handler := setupTestHandler()
+
+	w := postJSON(handler, "/delete_calendar", map[string]interface{}{"id": 999, "user_id": 1})
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status %d, got %d", http.StatusNotFound, w.Code)
+	}
+}
+
+func TestCalendar_OnlyOwnerChanges(t *testing.T) {
+	handler := setupTestHandler()
+
+	work := createCalendar(t, handler, "Work", 1)
+
+	w := postJSON(handler, "/update_calendar", map[string]interface{}{"id": work, "name": "Mine", "user_id": 2})
+	if w.Code != http.StatusForbidden {
+		t.Errorf("Expected status %d for another user's update, got %d", http.StatusForbidden, w.Code)
+	}
+
+	w = postJSON(handler, "/delete_calendar", map[string]interface{}{"id": work, "user_id": 2})
+	if w.Code != http.StatusForbidden {
+		t.Errorf("Expected status %d for another user's delete, got %d", http.StatusForbidden, w.Code)
+	}
+
+	w = postJSON(handler, "/delete_calendar", map[string]interface{}{"id": work})
+	if _, ok := fieldCodes(responseFields(t, w))["user_id"]; w.Code != http.StatusBadRequest || !ok {
+		t.Errorf("Expected a user_id error without an actor, got %d %s", w.Code, w.Body.String())
+	}
+
+	response := getJSON(t, handler, "/calendars?user_id=1")
+	calendars := response["data"].([]interface{})
+	if len(calendars) != 1 || calendars[0].(map[string]interface{})["name"] != "Work" {
+		t.Errorf("Expected Work to stay unchanged, got %v", calendars)
+	}
+}
@@ -0,0 +1,107 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+	"wb_l2/18/internal/config"
+	"wb_l2/18/internal/model"
+	"wb_l2/18/internal/repository"
+	"wb_l2/18/internal/service"
+)
+
+func getWithETag(h *Handler, path, etag string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("GET", path, nil)
+	if etag != "" {
+		req.Header.Set("If-None-Match", etag)
+	}
+	w := httptest.NewRecorder()
+
+	h.mux.ServeHTTP(w, req)
+	return w
+}
+
+func TestListEvents_ConditionalGet(t *testing.T) {
+	h := setupTestHandler()
+	path := "/events_for_week?user_id=2&date=2024-01-15"
+
+	first := getWithETag(h, path, "")
+	etag := first.Header().Get("ETag")
+	if first.Code != http.StatusOK || etag == "" {
+		t.Fatalf("Expected status %d with an ETag, got %d %v", http.StatusOK, first.Code, first.Header())
+	}
+
+	w := getWithETag(h, path, etag)
+	if w.Code != http.StatusNotModified || w.Body.Len() != 0 || w.Header().Get("ETag") != etag {
+		t.Errorf("Expected status %d without a body, got %d %q", http.StatusNotModified, w.Code, w.Body.String())
+	}
+
+	// every write that changes the list moves it to a new version
+	writes := []struct {
+		name string
+		path string
+		body map[string]any
+	}{
+		{"own event", "/create_event", map[string]any{"name": "Standup", "date": "2024-01-15", "user_id": 2}},
+		{"share with the user", "/share_events", map[string]any{"owner_id": 1, "grantee_id": 2, "permission": "read"}},
+		{"event of the owner", "/create_event", map[string]any{"name": "Retro", "date": "2024-01-16", "user_id": 1}},
+		{"calendar", "/create_calendar", map[string]any{"name": "Work", "user_id": 2}},
+		{"hidden calendar", "/update_calendar", map[string]any{"id": 1, "visible": false, "user_id": 2}},
+		{"revoked share", "/revoke_share", map[string]any{"owner_id": 1, "grantee_id": 2}},
+	}
+	for _, write := range writes {
+		if w := postJSON(h, write.path, write.body); w.Code >= 300 {
+			t.Fatalf("%s: expected success, got %d: %s", write.name, w.Code, w.Body.String())
+		}
+
+		w := getWithETag(h, path, etag)
+		if w.Code != http.StatusOK || w.Header().Get("ETag") == etag {
+			t.Errorf("%s: expected a new version, got %d %v", write.name, w.Code, w.Header())
+		}
+		if _, err := time.Parse(http.TimeFormat, w.Header().Get("Last-Modified")); err != nil {
+			t.Errorf("%s: expected Last-Modified, got %v", write.name, w.Header())
+		}
+		etag = w.Header().Get("ETag")
+	}
+
+	// events of other users don't change the list
+	postJSON(h, "/create_event", map[string]any{"name": "Other", "date": "2024-01-15", "user_id": 3})
+	if w := getWithETag(h, path, `"other", W/`+etag); w.Code != http.StatusNotModified {
+		t.Errorf("Expected status %d after a change of another user, got %d", http.StatusNotModified, w.Code)
+	}
+
+	if w := getWithETag(h, "/events_for_week?user_id=2&date=2024-13-45", etag); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d for an invalid query, got %d", http.StatusBadRequest, w.Code)
+	}
+}
+
+func TestListEvents_Cache(t *testing.T) {
+	repo := repository.NewRepository(repository.InMemory)
+	h := NewHandler(service.NewService(repo, config.Default()))
+	h.cache = newListCache(1)
+	RegisterHandlers(h)
+
+	day := "/events_for_day?user_id=1&date=2024-01-15"
+	postJSON(h, "/create_event", map[string]any{"name": "Standup", "date": "2024-01-15", "user_id": 1})
+	first := getWithETag(h, day, "")
+
+	// a write behind the services' back keeps the version, so the cached
+	// response is served
+	repo.Event.Create(&model.Event{Name: "Hidden", Date: time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC), UserID: 1})
+	if w := getWithETag(h, day, ""); w.Body.String() != first.Body.String() {
+		t.Errorf("Expected the cached response %s, got %s", first.Body.String(), w.Body.String())
+	}
+
+	// the only entry gives way to another list
+	getWithETag(h, "/events_for_month?user_id=1&date=2024-01-01", "")
+	if w := getWithETag(h, day, ""); !strings.Contains(w.Body.String(), "Hidden") {
+		t.Errorf("Expected the evicted list built again, got %s", w.Body.String())
+	}
+
+	postJSON(h, "/create_event", map[string]any{"name": "Retro", "date": "2024-01-15", "user_id": 1})
+	if w := getWithETag(h, day, ""); !strings.Contains(w.Body.String(), "Retro") {
+		t.Errorf("Expected the cached list dropped after a write, got %s", w.Body.String())
+	}
+}
@@ -1,6 +1,8 @@
 package handler
 
 import (
+	"encoding/json"
+	"errors"
 	"net/http"
 	"wb_l2/18/internal/model"
 	"wb_l2/18/internal/repository/inmemory/event"
@@ -10,7 +12,8 @@ import (
 )
 
 type withId struct {
-	Id int `json:"id"`
+	Id       int      `json:"id"`
+	Warnings []string `json:"warnings,omitempty"`
 }
 
 func (h *Handler) CreateEvent(w http.ResponseWriter, r *http.Request) {
@@ -24,11 +27,15 @@ func (h *Handler) CreateEvent(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	id, err := h.service.Event.Create(body)
+	id, warnings, err := h.service.Event.Create(body, r.Header.Get("Idempotency-Key"))
 	if err != nil {
-		switch err {
-		case model.InvalidFormat:
-			response.Response(w, http.StatusBadRequest, model.ErrorResp(err.Error()))
+		switch {
+		case errors.Is(err, model.InvalidFormat):
+			response.Response(w, http.StatusBadRequest, model.ErrorRespFromError(err))
+		case errors.Is(err, model.OutsideWorkingHours), errors.Is(err, model.IdempotencyKeyReused):
+			response.Response(w, http.StatusUnprocessableEntity, model.ErrorRespFromError(err))
+		case errors.Is(err, model.IdempotencyKeyInProgress):
+			response.Response(w, http.StatusConflict, model.ErrorRespFromError(err))
 		default:
 			response.InternalServerError(w)
 		}
@@ -38,80 +45,78 @@ func (h *Handler) CreateEvent(w http.ResponseWriter, r *http.Request) {
 	response.Response(
 		w,
 		http.StatusCreated,
-		model.ResultWithDataResp("Event created successfully", withId{Id: id}),
+		model.ResultWithDataResp("Event created successfully", withId{Id: id, Warnings: warnings}),
 	)
 }
 
 func (h *Handler) ListEventsForDay(w http.ResponseWriter, r *http.Request) {
-	if r.Method != "GET" {
-		response.MethodNotAllowed(w, "GET")
-		return
-	}
+	h.listEvents(w, r, service.Day, "List of events for a day")
+}
 
-	events, err := h.service.Event.List(r.URL.Query(), service.Day)
-	if err != nil {
-		switch err {
-		case service.InvalidQuery:
-			response.Response(w, http.StatusBadRequest, model.ErrorResp(err.Error()))
-		default:
-			response.InternalServerError(w)
-		}
-		return
-	}
+func (h *Handler) ListEventsForWeek(w http.ResponseWriter, r *http.Request) {
+	h.listEvents(w, r, service.Week, "List of events for a week")
+}
 
-	response.Response(
-		w,
-		http.StatusOK,
-		model.ResultWithDataResp("List of events for a day", events),
-	)
+func (h *Handler) ListEventsForMonth(w http.ResponseWriter, r *http.Request) {
+	h.listEvents(w, r, service.Month, "List of events for a month")
 }
 
-func (h *Handler) ListEventsForWeek(w http.ResponseWriter, r *http.Request) {
+// listEvents tags the list with the version of the users it shows, answers
+// 304 to clients holding that version and serves it from the cache if enabled
+func (h *Handler) listEvents(w http.ResponseWriter, r *http.Request, by service.ListFor, message string) {
 	if r.Method != "GET" {
 		response.MethodNotAllowed(w, "GET")
 		return
 	}
 
-	events, err := h.service.Event.List(r.URL.Query(), service.Week)
+	// the version is taken before the list, so a write in between at worst
+	// makes the client fetch the list once more
+	version, err := h.service.Event.ListVersion(r.URL.Query())
 	if err != nil {
-		switch err {
-		case service.InvalidQuery:
-			response.Response(w, http.StatusBadRequest, model.ErrorResp(err.Error()))
+		switch {
+		case errors.Is(err, service.InvalidQuery):
+			response.Response(w, http.StatusBadRequest, model.ErrorRespFromError(err))
 		default:
 			response.InternalServerError(w)
 		}
 		return
 	}
 
-	response.Response(
-		w,
-		http.StatusOK,
-		model.ResultWithDataResp("List of events for a week", events),
-	)
-}
+	w.Header().Set("ETag", version.ETag)
+	if !version.ModifiedAt.IsZero() {
+		w.Header().Set("Last-Modified", version.ModifiedAt.Format(http.TimeFormat))
+	}
 
-func (h *Handler) ListEventsForMonth(w http.ResponseWriter, r *http.Request) {
-	if r.Method != "GET" {
-		response.MethodNotAllowed(w, "GET")
+	if noneMatch(r, version.ETag) {
+		w.WriteHeader(http.StatusNotModified)
 		return
 	}
 
-	events, err := h.service.Event.List(r.URL.Query(), service.Month)
+	key := r.URL.RequestURI()
+	if body, ok := h.cache.get(key, version.ETag); ok {
+		response.JSON(w, http.StatusOK, body)
+		return
+	}
+
+	events, err := h.service.Event.List(r.URL.Query(), by)
 	if err != nil {
-		switch err {
-		case service.InvalidQuery:
-			response.Response(w, http.StatusBadRequest, model.ErrorResp(err.Error()))
+		switch {
+		case errors.Is(err, service.InvalidQuery):
+			response.Response(w, http.StatusBadRequest, model.ErrorRespFromError(err))
 		default:
 			response.InternalServerError(w)
 		}
 		return
 	}
 
-	response.Response(
-		w,
-		http.StatusOK,
-		model.ResultWithDataResp("List of events for a month", events),
-	)
+	body, err := json.Marshal(model.ResultWithDataResp(message, events))
+	if err != nil {
+		response.InternalServerError(w)
+		return
+	}
+	h.cache.put(key, version.ETag, body)
+
+	response.JSON(w, http.StatusOK, body)
 }
 
 func (h *Handler) Update(w http.ResponseWriter, r *http.Request) {
@@ -127,11 +132,13 @@ func (h *Handler) Update(w http.ResponseWriter, r *http.Request) {
 
 	events, err := h.service.Event.Update(body)
 	if err != nil {
-		switch err {
-		case model.InvalidFormat:
-			response.Response(w, http.StatusBadRequest, model.ErrorResp(err.Error()))
-		case event.ErrorEventNotFound:
+		switch {
+		case errors.Is(err, model.InvalidFormat):
+			response.Response(w, http.StatusBadRequest, model.ErrorRespFromError(err))
+		case errors.Is(err, event.ErrorEventNotFound):
 			response.Response(w, http.StatusNotFound, model.ErrorResp(err.Error()))
+		case errors.Is(err, service.Forbidden):
+			response.Response(w, http.StatusForbidden, model.ErrorResp(err.Error()))
 		default:
 			response.InternalServerError(w)
 		}
@@ -157,11 +164,13 @@ func (h *Handler) Delete(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := h.service.Event.Delete(body); err != nil {
-		switch err {
-		case model.InvalidFormat:
-			response.Response(w, http.StatusBadRequest, model.ErrorResp(err.Error()))
-		case event.ErrorEventNotFound:
+		switch {
+		case errors.Is(err, model.InvalidFormat):
+			response.Response(w, http.StatusBadRequest, model.ErrorRespFromError(err))
+		case errors.Is(err, event.ErrorEventNotFound):
 			response.Response(w, http.StatusNotFound, model.ErrorResp(err.Error()))
+		case errors.Is(err, service.Forbidden):
+			response.Response(w, http.StatusForbidden, model.ErrorResp(err.Error()))
 		default:
 			response.InternalServerError(w)
 		}
@@ -2,6 +2,8 @@ package handler
 
 import (
 	"net/http"
+	"wb_l2/18/internal/api/caldav"
+	"wb_l2/18/internal/api/openapi"
 	"wb_l2/18/internal/model"
 	"wb_l2/18/internal/service"
 	"wb_l2/18/pkg/http/response"
@@ -10,6 +12,9 @@ import (
 type Handler struct {
 	mux     *http.ServeMux
 	service *service.Service
+
+	// cache of list responses, nil unless enabled in the config
+	cache *listCache
 }
 
 func NewHandler(service *service.Service) *Handler {
@@ -23,26 +28,101 @@ func (h *Handler) HTTPHandler() http.Handler {
 	return h.mux
 }
 
-func RegisterHandlers(h *Handler) {
-	h.mux.HandleFunc("/ping", h.Ping)
+type Route struct {
+	Pattern string
+	Method  string
+	Handler http.HandlerFunc
+}
+
+// Routes is the single list of API endpoints, the OpenAPI document is checked
+// against it in tests
+func (h *Handler) Routes() []Route {
+	routes := []Route{
+		{"/ping", "GET", h.Ping},
+		{"/openapi.json", "GET", h.OpenAPI},
+
+		{"/healthz", "GET", h.Healthz},
+		{"/readyz", "GET", h.Readyz},
+		{"/version", "GET", h.Version},
+
+		{"/create_event", "POST", h.CreateEvent},
+
+		{"/events_for_day", "GET", h.ListEventsForDay},
+		{"/events_for_week", "GET", h.ListEventsForWeek},
+		{"/events_for_month", "GET", h.ListEventsForMonth},
+
+		{"/update_event", "POST", h.Update},
+
+		{"/delete_event", "POST", h.Delete},
+
+		{"/events/export", "GET", h.ExportEvents},
+		{"/events/import", "POST", h.ImportEvents},
+
+		{"/create_calendar", "POST", h.CreateCalendar},
+		{"/calendars", "GET", h.ListCalendars},
+		{"/update_calendar", "POST", h.UpdateCalendar},
+		{"/delete_calendar", "POST", h.DeleteCalendar},
+
+		{"/share_events", "POST", h.ShareEvents},
+		{"/shares", "GET", h.ListShares},
+		{"/revoke_share", "POST", h.RevokeShare},
+
+		{"/create_webhook", "POST", h.CreateWebhook},
+		{"/webhooks", "GET", h.ListWebhooks},
+		{"/delete_webhook", "POST", h.DeleteWebhook},
+		{"/webhook_deliveries", "GET", h.ListWebhookDeliveries},
+		{"/webhook_dead_letters", "GET", h.ListWebhookDeadLetters},
 
-	h.mux.HandleFunc("/create_event", h.CreateEvent)
+		{"/set_schedule", "POST", h.SetSchedule},
+		{"/schedule", "GET", h.Schedule},
+		{"/availability", "GET", h.Availability},
 
-	h.mux.HandleFunc("/events_for_day", h.ListEventsForDay)
-	h.mux.HandleFunc("/events_for_week", h.ListEventsForWeek)
-	h.mux.HandleFunc("/events_for_month", h.ListEventsForMonth)
+		{"/agenda", "GET", h.Agenda},
+	}
+
+	// tenants are managed from the default tenant only, other tenants must
+	// not learn about each other
+	if h.service.Tenants.Default() == h.service {
+		routes = append(routes,
+			Route{"/admin/create_tenant", "POST", h.CreateTenant},
+			Route{"/admin/tenants", "GET", h.ListTenants},
+			Route{"/admin/tenant_usage", "GET", h.TenantUsage},
+		)
+	}
 
-	h.mux.HandleFunc("/update_event", h.Update)
+	return routes
+}
+
+func RegisterHandlers(h *Handler) {
+	for _, route := range h.Routes() {
+		h.mux.HandleFunc(route.Pattern, route.Handler)
+	}
 
-	h.mux.HandleFunc("/delete_event", h.Delete)
+	// CalDAV has its own methods and bodies, so it stays out of Routes and the
+	// OpenAPI document
+	h.mux.Handle(caldav.Prefix, caldav.NewHandler(h.service.Event))
 
 	h.mux.HandleFunc("/", h.NotFound)
 }
 
 func (h *Handler) Ping(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		response.MethodNotAllowed(w, "GET")
+		return
+	}
+
 	response.Response(w, http.StatusOK, model.ResultResp("Pong!"))
 }
 
+func (h *Handler) OpenAPI(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		response.MethodNotAllowed(w, "GET")
+		return
+	}
+
+	response.Response(w, http.StatusOK, openapi.Spec())
+}
+
 func (h *Handler) NotFound(w http.ResponseWriter, r *http.Request) {
 	response.Response(w, http.StatusNotFound, model.ErrorResp("Unknown endpoint"))
 }
@@ -5,14 +5,16 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"wb_l2/18/internal/config"
 	"wb_l2/18/internal/repository"
 	"wb_l2/18/internal/service"
 )
 
 func setupTestHandler() *Handler {
 	repo := repository.NewRepository(repository.InMemory)
-	svc := service.NewService(repo)
+	svc := service.NewService(repo, config.Default())
 	h := NewHandler(svc)
 	RegisterHandlers(h)
 	return h
@@ -322,6 +324,96 @@ func TestListEventsForMonth_Success(t *testing.T) {
 	}
 }
 
+func TestListEvents_Windows(t *testing.T) {
+	handler := setupTestHandler()
+
+	// events are named after their dates, 2024-01-17 is a Wednesday
+	dates := []string{
+		"2024-01-13", "2024-01-14", "2024-01-15", "2024-01-17", "2024-01-21",
+		"2024-01-22", "2024-01-24", "2024-01-31", "2024-02-01", "2024-02-16", "2024-02-17",
+	}
+	for _, date := range dates {
+		postJSON(handler, "/create_event", map[string]interface{}{"name": date, "date": date, "user_id": 1})
+	}
+
+	cases := []struct {
+		target string
+		from   int
+		to     int
+	}{
+		{"/events_for_day?date=2024-01-17", 3, 4},
+		{"/events_for_week?date=2024-01-17", 3, 6},
+		{"/events_for_week?date=2024-01-17&mode=rolling", 3, 6},
+		{"/events_for_week?date=2024-01-17&mode=calendar", 2, 5},
+		{"/events_for_week?date=2024-01-17&mode=calendar&week_start=sunday", 1, 4},
+		{"/events_for_week?date=2024-01-21&mode=calendar&week_start=sunday", 4, 7},
+		{"/events_for_month?date=2024-01-17", 3, 10},
+		{"/events_for_month?date=2024-01-17&mode=calendar", 0, 8},
+		{"/events_for_month?date=2024-02-29&mode=calendar", 8, 11},
+	}
+
+	for _, tc := range cases {
+		response := getJSON(t, handler, tc.target+"&user_id=1")
+
+		got := make([]string, 0)
+		for _, event := range response["data"].([]interface{}) {
+			got = append(got, event.(map[string]interface{})["name"].(string))
+		}
+
+		want := dates[tc.from:tc.to]
+		if strings.Join(got, ",") != strings.Join(want, ",") {
+			t.Errorf("%s: expected %v, got %v", tc.target, want, got)
+		}
+	}
+}
+
+func TestListEvents_DateFormats(t *testing.T) {
+	handler := setupTestHandler()
+
+	for _, date := range []string{"2024-W03-1", "2024-016", "2024-01-17T23:30:00-05:00"} {
+		w := postJSON(handler, "/create_event", map[string]interface{}{"name": date, "date": date, "user_id": 1})
+		if w.Code != http.StatusCreated {
+			t.Fatalf("%s: expected status %d, got %d: %s", date, http.StatusCreated, w.Code, w.Body.String())
+		}
+	}
+
+	cases := map[string][]string{
+		"":                    {"2024-01-15", "2024-01-16", "2024-01-17"},
+		"&date_format=dotted": {"15.01.2024", "16.01.2024", "17.01.2024"},
+		"&date_format=week":   {"2024-W03-1", "2024-W03-2", "2024-W03-3"},
+	}
+
+	for query, want := range cases {
+		response := getJSON(t, handler, "/events_for_week?user_id=1&date=2024-015"+query)
+
+		got := make([]string, 0)
+		for _, event := range response["data"].([]interface{}) {
+			got = append(got, event.(map[string]interface{})["date"].(string))
+		}
+
+		if strings.Join(got, ",") != strings.Join(want, ",") {
+			t.Errorf("%q: expected %v, got %v", query, want, got)
+		}
+	}
+}
+
+func TestListEvents_InvalidMode(t *testing.T) {
+	handler := setupTestHandler()
+
+	req := httptest.NewRequest("GET", "/events_for_week?user_id=1&date=2024-01-17&mode=fiscal&week_start=someday&date_format=iso", nil)
+	w := httptest.NewRecorder()
+	handler.mux.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("Expected status %d, got %d", http.StatusBadRequest, w.Code)
+	}
+
+	got := fieldCodes(responseFields(t, w))
+	if got["mode"] != "unknown_value" || got["week_start"] != "unknown_value" || got["date_format"] != "unknown_value" {
+		t.Errorf("Expected mode, week_start and date_format field errors, got %v", got)
+	}
+}
+
 func TestUpdateEvent_Success(t *testing.T) {
 	handler := setupTestHandler()
 
@@ -346,9 +438,10 @@ func TestUpdateEvent_Success(t *testing.T) {
 
 	// Now update the event
 	updateData := map[string]interface{}{
-		"id":   eventID,
-		"name": "Updated Event",
-		"date": "2024-01-16",
+		"id":      eventID,
+		"name":    "Updated Event",
+		"date":    "2024-01-16",
+		"user_id": 1,
 	}
 
 	jsonData, _ = json.Marshal(updateData)
@@ -376,9 +469,10 @@ func TestUpdateEvent_NotFound(t *testing.T) {
 	handler := setupTestHandler()
 
 	updateData := map[string]interface{}{
-		"id":   999, // Non-existent ID
-		"name": "Updated Event",
-		"date": "2024-01-16",
+		"id":      999, // Non-existent ID
+		"name":    "Updated Event",
+		"date":    "2024-01-16",
+		"user_id": 1,
 	}
 
 	jsonData, _ := json.Marshal(updateData)
@@ -441,7 +535,8 @@ func TestDeleteEvent_Success(t *testing.T) {
 
 	// Now delete the event
 	deleteData := map[string]interface{}{
-		"id": eventID,
+		"id":      eventID,
+		"user_id": 1,
 	}
 
 	jsonData, _ = json.Marshal(deleteData)
@@ -469,7 +564,8 @@ func TestDeleteEvent_NotFound(t *testing.T) {
 	handler := setupTestHandler()
 
 	deleteData := map[string]interface{}{
-		"id": 999, // Non-existent ID
+		"id":      999, // Non-existent ID
+		"user_id": 1,
 	}
 
 	jsonData, _ := json.Marshal(deleteData)
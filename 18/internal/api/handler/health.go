@@ -0,0 +1,52 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"wb_l2/18/internal/model"
+	"wb_l2/18/internal/service"
+	"wb_l2/18/pkg/http/response"
+)
+
+// Healthz answers as long as the process serves requests
+func (h *Handler) Healthz(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		response.MethodNotAllowed(w, "GET")
+		return
+	}
+
+	response.Response(w, http.StatusOK, model.ResultResp("OK"))
+}
+
+// Readyz tells whether the server should get traffic
+func (h *Handler) Readyz(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		response.MethodNotAllowed(w, "GET")
+		return
+	}
+
+	if err := h.service.Health.Ready(r.Context()); err != nil {
+		switch {
+		case errors.Is(err, service.ShuttingDown), errors.Is(err, service.StorageUnavailable):
+			response.Response(w, http.StatusServiceUnavailable, model.ErrorResp(err.Error()))
+		default:
+			response.InternalServerError(w)
+		}
+		return
+	}
+
+	response.Response(w, http.StatusOK, model.ResultResp("Ready"))
+}
+
+func (h *Handler) Version(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		response.MethodNotAllowed(w, "GET")
+		return
+	}
+
+	response.Response(
+		w,
+		http.StatusOK,
+		model.ResultWithDataResp("Build info", h.service.Health.BuildInfo()),
+	)
+}
@@ -0,0 +1,55 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHealthz(t *testing.T) {
+	handler := setupTestHandler()
+
+	response := getJSON(t, handler, "/healthz")
+	if response["message"] != "OK" {
+		t.Errorf("Expected message 'OK', got %v", response["message"])
+	}
+}
+
+func TestReadyz_FailsWhileDraining(t *testing.T) {
+	handler := setupTestHandler()
+
+	response := getJSON(t, handler, "/readyz")
+	if response["message"] != "Ready" {
+		t.Fatalf("Expected message 'Ready', got %v", response["message"])
+	}
+
+	handler.service.Health.Drain()
+
+	req := httptest.NewRequest("GET", "/readyz", nil)
+	w := httptest.NewRecorder()
+	handler.mux.ServeHTTP(w, req)
+
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected status %d, got %d", http.StatusServiceUnavailable, w.Code)
+	}
+
+	// liveness is not affected by draining
+	req = httptest.NewRequest("GET", "/healthz", nil)
+	w = httptest.NewRecorder()
+	handler.mux.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status %d, got %d", http.StatusOK, w.Code)
+	}
+}
+
+func TestVersion(t *testing.T) {
+	handler := setupTestHandler()
+
+	response := getJSON(t, handler, "/version")
+	data := response["data"].(map[string]interface{})
+
+	if goVersion, _ := data["go_version"].(string); goVersion == "" {
+		t.Errorf("Expected go_version in build info, got %v", data)
+	}
+}
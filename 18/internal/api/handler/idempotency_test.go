@@ -0,0 +1,186 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+	"wb_l2/18/internal/config"
+	"wb_l2/18/internal/model"
+	"wb_l2/18/internal/repository"
+	"wb_l2/18/internal/repository/inmemory/event"
+	"wb_l2/18/internal/service"
+)
+
+func postWithKey(h *Handler, key, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("POST", "/create_event", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Idempotency-Key", key)
+	w := httptest.NewRecorder()
+
+	h.mux.ServeHTTP(w, req)
+	return w
+}
+
+func countEvents(t *testing.T, h *Handler) int {
+	response := getJSON(t, h, "/events_for_day?user_id=1&date=2024-01-15")
+	events, _ := response["data"].([]interface{})
+	return len(events)
+}
+
+func TestCreateEvent_IdempotencyKey(t *testing.T) {
+	h := setupTestHandler()
+
+	first := postWithKey(h, "retry-1", `{"name": "Standup", "date": "2024-01-15", "user_id": 1}`)
+	if first.Code != http.StatusCreated {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusCreated, first.Code, first.Body.String())
+	}
+
+	// the same body with other key order and spacing is a retry
+	retry := postWithKey(h, "retry-1", `{"user_id":1,"date":"2024-01-15","name":"Standup"}`)
+	if retry.Code != http.StatusCreated || retry.Body.String() != first.Body.String() {
+		t.Errorf("Expected the first response %s, got %d %s", first.Body.String(), retry.Code, retry.Body.String())
+	}
+
+	if n := countEvents(t, h); n != 1 {
+		t.Errorf("Expected 1 event after a retry, got %d", n)
+	}
+
+	reused := postWithKey(h, "retry-1", `{"name": "Retro", "date": "2024-01-15", "user_id": 1}`)
+	if reused.Code != http.StatusUnprocessableEntity {
+		t.Errorf("Expected status %d for a reused key, got %d: %s", http.StatusUnprocessableEntity, reused.Code, reused.Body.String())
+	}
+
+	other := postWithKey(h, "retry-2", `{"name": "Standup", "date": "2024-01-15", "user_id": 1}`)
+	if other.Code != http.StatusCreated || other.Body.String() == first.Body.String() {
+		t.Errorf("Expected a new event for another key, got %d %s", other.Code, other.Body.String())
+	}
+
+	if n := countEvents(t, h); n != 2 {
+		t.Errorf("Expected 2 events, got %d", n)
+	}
+}
+
+func TestCreateEvent_IdempotencyKeyPerUser(t *testing.T) {
+	h := setupTestHandler()
+
+	first := postWithKey(h, "1", `{"name": "Standup", "date": "2024-01-15", "user_id": 1}`)
+	if first.Code != http.StatusCreated {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusCreated, first.Code, first.Body.String())
+	}
+
+	// another client picking the same key is not a reuse
+	second := postWithKey(h, "1", `{"name": "Retro", "date": "2024-01-15", "user_id": 2}`)
+	if second.Code != http.StatusCreated || second.Body.String() == first.Body.String() {
+		t.Errorf("Expected a new event for another user, got %d %s", second.Code, second.Body.String())
+	}
+
+	response := getJSON(t, h, "/events_for_day?user_id=2&date=2024-01-15&shared=false")
+	if events, _ := response["data"].([]interface{}); len(events) != 1 {
+		t.Errorf("Expected the event of the second user, got %d", len(events))
+	}
+}
+
+func TestCreateEvent_IdempotencyKeyAfterFailure(t *testing.T) {
+	h := setupTestHandler()
+
+	w := postWithKey(h, "retry-1", `{"name": "Standup", "date": "2024-13-45", "user_id": 1}`)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusBadRequest, w.Code, w.Body.String())
+	}
+
+	// failed requests don't hold the key
+	w = postWithKey(h, "retry-1", `{"name": "Standup", "date": "2024-01-15", "user_id": 1}`)
+	if w.Code != http.StatusCreated {
+		t.Errorf("Expected status %d after a failed request, got %d: %s", http.StatusCreated, w.Code, w.Body.String())
+	}
+
+	w = postWithKey(h, strings.Repeat("k", 256), `{"name": "Standup", "date": "2024-01-15", "user_id": 1}`)
+	if code := fieldCodes(responseFields(t, w))["Idempotency-Key"]; w.Code != http.StatusBadRequest || code != "too_long" {
+		t.Errorf("Expected too_long for a long key, got %d %s", w.Code, w.Body.String())
+	}
+}
+
+func TestCreateEvent_IdempotencyKeyExpires(t *testing.T) {
+	cfg := config.Default()
+	cfg.Idempotency.TTL = 50 * time.Millisecond
+
+	h := NewHandler(service.NewService(repository.NewRepository(repository.InMemory), cfg))
+	RegisterHandlers(h)
+
+	body := `{"name": "Standup", "date": "2024-01-15", "user_id": 1}`
+	first := postWithKey(h, "retry-1", body)
+	time.Sleep(100 * time.Millisecond)
+	second := postWithKey(h, "retry-1", body)
+
+	var ids [2]struct {
+		Data struct {
+			ID int `json:"id"`
+		} `json:"data"`
+	}
+	for i, w := range []*httptest.ResponseRecorder{first, second} {
+		if err := json.Unmarshal(w.Body.Bytes(), &ids[i]); err != nil || w.Code != http.StatusCreated {
+			t.Fatalf("Expected status %d, got %d: %s", http.StatusCreated, w.Code, w.Body.String())
+		}
+	}
+
+	if ids[0].Data.ID == ids[1].Data.ID {
+		t.Errorf("Expected a new event after the key expired, got id %d twice", ids[0].Data.ID)
+	}
+}
+
+// stalledEvents runs stall on the first Create and fails it afterwards
+type stalledEvents struct {
+	*event.EventRepository
+	stall func()
+}
+
+func (r *stalledEvents) Create(e *model.Event) (int, error) {
+	if stall := r.stall; stall != nil {
+		r.stall = nil
+		stall()
+		return 0, errors.New("storage timed out")
+	}
+
+	return r.EventRepository.Create(e)
+}
+
+func TestCreateEvent_IdempotencyKeyReleaseAfterExpiry(t *testing.T) {
+	cfg := config.Default()
+	cfg.Idempotency.TTL = 50 * time.Millisecond
+
+	repo := repository.NewRepository(repository.InMemory)
+	events := &stalledEvents{EventRepository: repo.Event.(*event.EventRepository)}
+	repo.Event = events
+	h := NewHandler(service.NewService(repo, cfg))
+	RegisterHandlers(h)
+
+	body := `{"name": "Standup", "date": "2024-01-15", "user_id": 1}`
+
+	// the first request outlives its key, a retry claims it again and succeeds
+	var second *httptest.ResponseRecorder
+	events.stall = func() {
+		time.Sleep(100 * time.Millisecond)
+		second = postWithKey(h, "retry-1", body)
+	}
+	if w := postWithKey(h, "retry-1", body); w.Code != http.StatusInternalServerError {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusInternalServerError, w.Code, w.Body.String())
+	}
+	if second.Code != http.StatusCreated {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusCreated, second.Code, second.Body.String())
+	}
+
+	// the failure of the first request keeps the claim of the retry
+	third := postWithKey(h, "retry-1", body)
+	if third.Code != http.StatusCreated || third.Body.String() != second.Body.String() {
+		t.Errorf("Expected the stored response %s, got %d %s", second.Body.String(), third.Code, third.Body.String())
+	}
+
+	if n := countEvents(t, h); n != 1 {
+		t.Errorf("Expected 1 event, got %d", n)
+	}
+}
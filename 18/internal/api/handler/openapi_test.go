@@ -0,0 +1,236 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"wb_l2/18/internal/api/middleware"
+	"wb_l2/18/internal/api/openapi"
+)
+
+func setupValidatedHandler() (*Handler, http.Handler) {
+	h := setupTestHandler()
+	return h, middleware.Chain(h.HTTPHandler(), middleware.Validate(openapi.Spec()))
+}
+
+// exampleRequest builds a request to the operation from the examples in the spec
+func exampleRequest(t *testing.T, path, method string, op *openapi.Operation) *http.Request {
+	query := url.Values{}
+	for _, param := range op.Parameters {
+		if param.Example != nil {
+			query.Set(param.Name, strings.Trim(mustJSON(t, param.Example), `"`))
+		}
+	}
+
+	target := path
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+
+	if op.RequestBody == nil {
+		return httptest.NewRequest(method, target, nil)
+	}
+
+	// bodies that are not JSON, like imported files, have a string example
+	contentType := openapi.JSON
+	if _, ok := op.RequestBody.Content[openapi.JSON]; !ok {
+		contentType = slices.Sorted(maps.Keys(op.RequestBody.Content))[0]
+	}
+
+	media := op.RequestBody.Content[contentType]
+	if media.Example == nil {
+		t.Fatalf("%s %s: request body has no %s example", method, path, contentType)
+	}
+
+	body, ok := media.Example.(string)
+	if !ok {
+		body = mustJSON(t, media.Example)
+	}
+
+	req := httptest.NewRequest(method, target, bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", contentType)
+	return req
+}
+
+func mustJSON(t *testing.T, value any) string {
+	data, err := json.Marshal(value)
+	if err != nil {
+		t.Fatalf("Failed to marshal %v: %v", value, err)
+	}
+
+	return string(data)
+}
+
+func TestOpenAPI_RoutesMatchSpec(t *testing.T) {
+	handler := setupTestHandler()
+	spec := openapi.Spec()
+
+	registered := make(map[string]bool)
+	for _, route := range handler.Routes() {
+		key := route.Method + " " + route.Pattern
+		registered[key] = true
+
+		item, ok := spec.Paths[route.Pattern]
+		if !ok {
+			t.Errorf("Route %s is missing from the OpenAPI spec", key)
+			continue
+		}
+
+		if item.Operation(route.Method) == nil {
+			t.Errorf("Route %s has no operation in the OpenAPI spec", key)
+		}
+	}
+
+	for path, item := range spec.Paths {
+		for method, op := range item.Operations() {
+			if !registered[method+" "+path] {
+				t.Errorf("Spec operation %s %s has no registered handler", method, path)
+			}
+
+			for status, resp := range op.Responses {
+				for _, media := range resp.Content {
+					if spec.Resolve(media.Schema) == nil {
+						t.Errorf("%s %s: response %s references unknown schema", method, path, status)
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestOpenAPI_HandlersRejectUndocumentedMethods(t *testing.T) {
+	handler := setupTestHandler()
+
+	for path, item := range openapi.Spec().Paths {
+		for _, method := range []string{"GET", "POST", "PUT", "DELETE"} {
+			if item.Operation(method) != nil {
+				continue
+			}
+
+			req := httptest.NewRequest(method, path, nil)
+			w := httptest.NewRecorder()
+			handler.mux.ServeHTTP(w, req)
+
+			if w.Code != http.StatusMethodNotAllowed {
+				t.Errorf("%s %s: expected status %d, got %d", method, path, http.StatusMethodNotAllowed, w.Code)
+				continue
+			}
+
+			if allow := w.Header().Get("Allow"); allow != item.Allow() {
+				t.Errorf("%s %s: expected Allow %q, got %q", method, path, item.Allow(), allow)
+			}
+		}
+	}
+}
+
+func TestOpenAPI_ExamplesMatchResponses(t *testing.T) {
+	handler, validated := setupValidatedHandler()
+	spec := openapi.Spec()
+
+	// seed data so that examples referring to id 1 hit existing entities
+	postJSON(handler, "/create_event", map[string]interface{}{"name": "Seed", "date": "2024-01-15", "user_id": 1})
+	postJSON(handler, "/create_webhook", map[string]interface{}{"url": "http://localhost/hook", "events": []string{"event.created"}, "user_id": 1})
+
+	paths := make([]string, 0, len(spec.Paths))
+	for path := range spec.Paths {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		for method, op := range spec.Paths[path].Operations() {
+			req := exampleRequest(t, path, method, op)
+			w := httptest.NewRecorder()
+			validated.ServeHTTP(w, req)
+
+			resp, ok := op.Responses[strconv.Itoa(w.Code)]
+			if !ok {
+				t.Errorf("%s %s: undocumented status %d: %s", method, path, w.Code, w.Body.String())
+				continue
+			}
+
+			media := resp.Content[openapi.JSON]
+			if media == nil {
+				continue
+			}
+
+			var body any
+			if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+				t.Errorf("%s %s: response is not JSON: %v", method, path, err)
+				continue
+			}
+
+			if violations := spec.Validate(media.Schema, body); len(violations) > 0 {
+				t.Errorf("%s %s: response %d does not match the spec: %s", method, path, w.Code, violations)
+			}
+		}
+	}
+}
+
+func TestValidate_RejectsInvalidRequests(t *testing.T) {
+	_, validated := setupValidatedHandler()
+
+	cases := []struct {
+		name   string
+		method string
+		target string
+		body   string
+		status int
+		field  string
+		code   string
+	}{
+		{"missing query", "GET", "/events_for_day?date=2024-01-15", "", http.StatusBadRequest, "user_id", "required"},
+		{"query type", "GET", "/events_for_week?user_id=abc&date=2024-01-15", "", http.StatusBadRequest, "user_id", "invalid_type"},
+		{"query format", "GET", "/events_for_month?user_id=1&date=15.01.2024", "", http.StatusBadRequest, "date", "invalid_date"},
+		{"body type", "POST", "/create_event", `{"name":"A","date":"2024-01-15","user_id":"1"}`, http.StatusBadRequest, "user_id", "invalid_type"},
+		{"body minimum", "POST", "/create_event", `{"name":"A","date":"2024-01-15","user_id":0}`, http.StatusBadRequest, "user_id", "not_positive"},
+		{"body required", "POST", "/update_event", `{"name":"A","user_id":1}`, http.StatusBadRequest, "id", "required"},
+		{"body enum", "POST", "/create_webhook", `{"url":"http://a/b","events":["nope"],"user_id":1}`, http.StatusBadRequest, "events[0]", "unknown_value"},
+		{"body json", "POST", "/delete_event", `{`, http.StatusBadRequest, "", "invalid_json"},
+		{"method", "DELETE", "/create_event", "", http.StatusMethodNotAllowed, "", ""},
+	}
+
+	for _, tc := range cases {
+		req := httptest.NewRequest(tc.method, tc.target, bytes.NewBufferString(tc.body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+
+		validated.ServeHTTP(w, req)
+
+		if w.Code != tc.status {
+			t.Errorf("%s: expected status %d, got %d", tc.name, tc.status, w.Code)
+		}
+
+		if tc.code == "" {
+			continue
+		}
+
+		fields := responseFields(t, w)
+		if len(fields) != 1 || fields[0]["field"] != tc.field || fields[0]["code"] != tc.code {
+			t.Errorf("%s: expected %s/%s field error, got %v", tc.name, tc.field, tc.code, fields)
+		}
+	}
+}
+
+func TestOpenAPI_Served(t *testing.T) {
+	handler := setupTestHandler()
+
+	response := getJSON(t, handler, "/openapi.json")
+
+	if response["openapi"] != "3.0.3" {
+		t.Errorf("Expected openapi version 3.0.3, got %v", response["openapi"])
+	}
+
+	paths, ok := response["paths"].(map[string]interface{})
+	if !ok || paths["/create_event"] == nil {
+		t.Error("Expected /create_event in served paths")
+	}
+}
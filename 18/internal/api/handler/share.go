@@ -0,0 +1,94 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"wb_l2/18/internal/model"
+	"wb_l2/18/internal/repository/inmemory/share"
+	"wb_l2/18/internal/service"
+	"wb_l2/18/pkg/http/request"
+	"wb_l2/18/pkg/http/response"
+)
+
+func (h *Handler) ShareEvents(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		response.MethodNotAllowed(w, "POST")
+		return
+	}
+
+	body, err := request.ReadBody(w, r)
+	if err != nil {
+		return
+	}
+
+	shareOut, err := h.service.Share.Share(body)
+	if err != nil {
+		switch {
+		case errors.Is(err, model.InvalidFormat):
+			response.Response(w, http.StatusBadRequest, model.ErrorRespFromError(err))
+		default:
+			response.InternalServerError(w)
+		}
+		return
+	}
+
+	response.Response(
+		w,
+		http.StatusOK,
+		model.ResultWithDataResp("Events shared successfully", shareOut),
+	)
+}
+
+func (h *Handler) ListShares(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		response.MethodNotAllowed(w, "GET")
+		return
+	}
+
+	shares, err := h.service.Share.List(r.URL.Query())
+	if err != nil {
+		switch {
+		case errors.Is(err, service.InvalidQuery):
+			response.Response(w, http.StatusBadRequest, model.ErrorRespFromError(err))
+		default:
+			response.InternalServerError(w)
+		}
+		return
+	}
+
+	response.Response(
+		w,
+		http.StatusOK,
+		model.ResultWithDataResp("List of shares", shares),
+	)
+}
+
+func (h *Handler) RevokeShare(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		response.MethodNotAllowed(w, "POST")
+		return
+	}
+
+	body, err := request.ReadBody(w, r)
+	if err != nil {
+		return
+	}
+
+	if err := h.service.Share.Revoke(body); err != nil {
+		switch {
+		case errors.Is(err, model.InvalidFormat):
+			response.Response(w, http.StatusBadRequest, model.ErrorRespFromError(err))
+		case errors.Is(err, share.ErrorShareNotFound):
+			response.Response(w, http.StatusNotFound, model.ErrorResp(err.Error()))
+		default:
+			response.InternalServerError(w)
+		}
+		return
+	}
+
+	response.Response(
+		w,
+		http.StatusOK,
+		model.ResultResp("Share revoked successfully"),
+	)
+}
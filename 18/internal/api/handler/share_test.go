@@ -0,0 +1,179 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func seedSharedEvent(t *testing.T, h *Handler, permission string) {
+	w := postJSON(h, "/create_event", map[string]interface{}{"name": "Owner Event", "date": "2024-01-15", "user_id": 1})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status %d, got %d", http.StatusCreated, w.Code)
+	}
+
+	if permission == "" {
+		return
+	}
+
+	w = postJSON(h, "/share_events", map[string]interface{}{"owner_id": 1, "grantee_id": 2, "permission": permission})
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+}
+
+func TestShare_ListIncludesSharedEvents(t *testing.T) {
+	handler := setupTestHandler()
+	seedSharedEvent(t, handler, "read")
+
+	postJSON(handler, "/create_event", map[string]interface{}{"name": "Own Event", "date": "2024-01-15", "user_id": 2})
+
+	response := getJSON(t, handler, "/events_for_week?user_id=2&date=2024-01-15")
+	if events := response["data"].([]interface{}); len(events) != 2 {
+		t.Errorf("Expected own and shared events, got %d", len(events))
+	}
+
+	response = getJSON(t, handler, "/events_for_week?user_id=2&date=2024-01-15&shared=false")
+	if events := response["data"].([]interface{}); len(events) != 1 {
+		t.Errorf("Expected only own events with shared=false, got %d", len(events))
+	}
+
+	// sharing is one way
+	response = getJSON(t, handler, "/events_for_week?user_id=1&date=2024-01-15")
+	if events := response["data"].([]interface{}); len(events) != 1 {
+		t.Errorf("Expected owner to see only own events, got %d", len(events))
+	}
+}
+
+func TestShare_ListOrderAndCalendars(t *testing.T) {
+	handler := setupTestHandler()
+	seedSharedEvent(t, handler, "read")
+
+	postJSON(handler, "/create_event", map[string]interface{}{"name": "Own Event", "date": "2024-01-14", "user_id": 2})
+	postJSON(handler, "/create_event", map[string]interface{}{"name": "Own Later", "date": "2024-01-16", "user_id": 2})
+
+	calendarID := createCalendar(t, handler, "Private", 1)
+	createEventIn(t, handler, "Hidden", calendarID)
+	postJSON(handler, "/update_calendar", map[string]interface{}{"id": calendarID, "visible": false, "user_id": 1})
+
+	names := func(path string) []string {
+		var names []string
+		for _, event := range getJSON(t, handler, path)["data"].([]interface{}) {
+			names = append(names, event.(map[string]interface{})["name"].(string))
+		}
+		return names
+	}
+
+	// shared events are merged by date, hidden calendars of the owner stay hidden
+	got := names("/events_for_week?user_id=2&date=2024-01-14")
+	if want := []string{"Own Event", "Owner Event", "Own Later"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+
+	got = names(fmt.Sprintf("/events_for_week?user_id=2&date=2024-01-14&calendars=%d", calendarID))
+	if want := []string{"Hidden"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v for the selected calendar, got %v", want, got)
+	}
+}
+
+func TestShare_PermissionChecks(t *testing.T) {
+	cases := []struct {
+		permission string
+		status     int
+	}{
+		{"", http.StatusNotFound},
+		{"read", http.StatusForbidden},
+		{"write", http.StatusOK},
+	}
+
+	for _, tc := range cases {
+		handler := setupTestHandler()
+		seedSharedEvent(t, handler, tc.permission)
+
+		w := postJSON(handler, "/update_event", map[string]interface{}{"id": 1, "name": "Changed", "user_id": 2})
+		if w.Code != tc.status {
+			t.Errorf("update with %q share: expected status %d, got %d", tc.permission, tc.status, w.Code)
+		}
+
+		w = postJSON(handler, "/delete_event", map[string]interface{}{"id": 1, "user_id": 2})
+		if w.Code != tc.status {
+			t.Errorf("delete with %q share: expected status %d, got %d", tc.permission, tc.status, w.Code)
+		}
+	}
+}
+
+func TestShare_ActorRequired(t *testing.T) {
+	handler := setupTestHandler()
+	seedSharedEvent(t, handler, "")
+
+	w := postJSON(handler, "/update_event", map[string]interface{}{"id": 1, "name": "Changed"})
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("update without user_id: expected status %d, got %d", http.StatusBadRequest, w.Code)
+	}
+	if got := fieldCodes(responseFields(t, w)); got["user_id"] == "" {
+		t.Errorf("update without user_id: expected user_id field error, got %v", got)
+	}
+
+	w = postJSON(handler, "/delete_event", map[string]interface{}{"id": 1})
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("delete without user_id: expected status %d, got %d", http.StatusBadRequest, w.Code)
+	}
+	if got := fieldCodes(responseFields(t, w)); got["user_id"] == "" {
+		t.Errorf("delete without user_id: expected user_id field error, got %v", got)
+	}
+
+	response := getJSON(t, handler, "/events_for_week?user_id=1&date=2024-01-15")
+	events := response["data"].([]interface{})
+	if len(events) != 1 || events[0].(map[string]interface{})["name"] != "Owner Event" {
+		t.Errorf("Expected the event to stay unchanged, got %v", events)
+	}
+}
+
+func TestShare_ListAndRevoke(t *testing.T) {
+	handler := setupTestHandler()
+	seedSharedEvent(t, handler, "read")
+
+	// sharing again replaces the permission
+	postJSON(handler, "/share_events", map[string]interface{}{"owner_id": 1, "grantee_id": 2, "permission": "write"})
+
+	response := getJSON(t, handler, "/shares?user_id=2")
+	data := response["data"].(map[string]interface{})
+	received := data["received"].([]interface{})
+	if len(received) != 1 || len(data["given"].([]interface{})) != 0 {
+		t.Fatalf("Expected 1 received share, got %v", data)
+	}
+
+	if permission := received[0].(map[string]interface{})["permission"]; permission != "write" {
+		t.Errorf("Expected write permission, got %v", permission)
+	}
+
+	w := postJSON(handler, "/revoke_share", map[string]interface{}{"owner_id": 1, "grantee_id": 2})
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, w.Code)
+	}
+
+	response = getJSON(t, handler, "/events_for_day?user_id=2&date=2024-01-15")
+	if events := response["data"].([]interface{}); len(events) != 0 {
+		t.Errorf("Expected no events after revoke, got %d", len(events))
+	}
+
+	w = postJSON(handler, "/revoke_share", map[string]interface{}{"owner_id": 1, "grantee_id": 2})
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status %d, got %d", http.StatusNotFound, w.Code)
+	}
+}
+
+func TestShare_InvalidFormat(t *testing.T) {
+	handler := setupTestHandler()
+
+	w := postJSON(handler, "/share_events", map[string]interface{}{"owner_id": 1, "grantee_id": 1, "permission": "admin"})
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("Expected status %d, got %d", http.StatusBadRequest, w.Code)
+	}
+
+	got := fieldCodes(responseFields(t, w))
+	if got["grantee_id"] != "unknown_value" || got["permission"] != "unknown_value" {
+		t.Errorf("Expected grantee_id and permission field errors, got %v", got)
+	}
+}
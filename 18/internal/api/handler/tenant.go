@@ -0,0 +1,134 @@
+package handler
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"wb_l2/18/internal/config"
+	"wb_l2/18/internal/model"
+	"wb_l2/18/internal/repository/inmemory/tenant"
+	"wb_l2/18/internal/service"
+	"wb_l2/18/pkg/http/request"
+	"wb_l2/18/pkg/http/response"
+)
+
+// Router serves every request with the handler of its tenant: the one named
+// in the tenant header, the one owning the host or the default one
+type Router struct {
+	service   *service.Service
+	header    string
+	cacheSize int
+
+	handlers map[*service.Service]*Handler
+	mu       sync.Mutex
+}
+
+func NewRouter(root *service.Service, config *config.Config) *Router {
+	return &Router{
+		service:   root,
+		header:    config.Tenancy.Header,
+		cacheSize: config.ListCache.Size,
+		handlers:  make(map[*service.Service]*Handler),
+	}
+}
+
+func (rt *Router) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	host, _, err := net.SplitHostPort(r.Host)
+	if err != nil {
+		host = r.Host
+	}
+
+	services, err := rt.service.Tenants.Resolve(r.Header.Get(rt.header), strings.ToLower(host))
+	if err != nil {
+		switch {
+		case errors.Is(err, tenant.ErrorTenantNotFound):
+			response.Response(w, http.StatusNotFound, model.ErrorResp(err.Error()))
+		default:
+			response.InternalServerError(w)
+		}
+		return
+	}
+
+	rt.handler(services).mux.ServeHTTP(w, r)
+}
+
+func (rt *Router) handler(services *service.Service) *Handler {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+
+	h, ok := rt.handlers[services]
+	if !ok {
+		h = NewHandler(services)
+		h.cache = newListCache(rt.cacheSize)
+		RegisterHandlers(h)
+		rt.handlers[services] = h
+	}
+
+	return h
+}
+
+func (h *Handler) CreateTenant(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		response.MethodNotAllowed(w, "POST")
+		return
+	}
+
+	body, err := request.ReadBody(w, r)
+	if err != nil {
+		return
+	}
+
+	created, err := h.service.Tenants.Create(body)
+	if err != nil {
+		switch {
+		case errors.Is(err, model.InvalidFormat):
+			response.Response(w, http.StatusBadRequest, model.ErrorRespFromError(err))
+		case errors.Is(err, model.TenantExists):
+			response.Response(w, http.StatusConflict, model.ErrorResp(err.Error()))
+		default:
+			response.InternalServerError(w)
+		}
+		return
+	}
+
+	response.Response(w, http.StatusCreated, model.ResultWithDataResp("Tenant created", created))
+}
+
+func (h *Handler) ListTenants(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		response.MethodNotAllowed(w, "GET")
+		return
+	}
+
+	tenants, err := h.service.Tenants.List()
+	if err != nil {
+		response.InternalServerError(w)
+		return
+	}
+
+	response.Response(w, http.StatusOK, model.ResultWithDataResp("List of tenants", tenants))
+}
+
+func (h *Handler) TenantUsage(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		response.MethodNotAllowed(w, "GET")
+		return
+	}
+
+	usage, err := h.service.Tenants.Usage(r.URL.Query())
+	if err != nil {
+		switch {
+		case errors.Is(err, service.InvalidQuery):
+			response.Response(w, http.StatusBadRequest, model.ErrorRespFromError(err))
+		case errors.Is(err, tenant.ErrorTenantNotFound):
+			response.Response(w, http.StatusNotFound, model.ErrorResp(err.Error()))
+		default:
+			response.InternalServerError(w)
+		}
+		return
+	}
+
+	response.Response(w, http.StatusOK, model.ResultWithDataResp("Tenant usage", usage))
+}
@@ -0,0 +1,234 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+	"wb_l2/18/internal/api/openapi"
+	"wb_l2/18/internal/config"
+	"wb_l2/18/internal/repository"
+	"wb_l2/18/internal/service"
+	"wb_l2/18/pkg/date"
+)
+
+func setupTestRouter(t *testing.T, tenants ...string) *Router {
+	svc := service.NewService(repository.NewRepository(repository.InMemory), config.Default())
+	router := NewRouter(svc, config.Default())
+
+	for _, id := range tenants {
+		body := map[string]any{"id": id, "name": id, "hosts": []string{id + ".localhost"}}
+		if w := serveTenant(router, "", "POST", "/admin/create_tenant", body); w.Code != http.StatusCreated {
+			t.Fatalf("Expected status %d, got %d: %s", http.StatusCreated, w.Code, w.Body.String())
+		}
+	}
+
+	return router
+}
+
+func serveTenant(router http.Handler, tenant, method, path string, data any) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	if data != nil {
+		jsonData, _ := json.Marshal(data)
+		req = httptest.NewRequest(method, path, bytes.NewBuffer(jsonData))
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if tenant != "" {
+		req.Header.Set("X-Tenant-ID", tenant)
+	}
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestTenants_NoDataLeaksBetweenTenants(t *testing.T) {
+	router := setupTestRouter(t, "acme")
+	fresh := setupTestHandler()
+
+	seed := []struct {
+		path string
+		body map[string]any
+	}{
+		{"/create_calendar", map[string]any{"name": "Work", "user_id": 1}},
+		{"/create_event", map[string]any{"name": "Standup", "date": "2024-01-15", "user_id": 1, "calendar_id": 1}},
+		{"/share_events", map[string]any{"owner_id": 1, "grantee_id": 2, "permission": "read"}},
+		{"/create_webhook", map[string]any{"url": "http://localhost:9000/hook", "events": []string{"event.created"}, "user_id": 1}},
+		{"/set_schedule", map[string]any{"user_id": 1, "time_zone": "Europe/Moscow"}},
+	}
+	for _, s := range seed {
+		if w := serveTenant(router, "", "POST", s.path, s.body); w.Code >= 300 {
+			t.Fatalf("%s: expected success, got %d: %s", s.path, w.Code, w.Body.String())
+		}
+	}
+
+	// every documented read of tenant data answers in a new tenant like in a
+	// separate server, while the default tenant sees its own data
+	spec := openapi.Spec()
+	for path, item := range spec.Paths {
+		if item.Get == nil || strings.HasPrefix(path, "/admin/") {
+			continue
+		}
+
+		req := exampleRequest(t, path, "GET", item.Get)
+		expected := httptest.NewRecorder()
+		fresh.mux.ServeHTTP(expected, req)
+
+		req = exampleRequest(t, path, "GET", item.Get)
+		req.Header.Set("X-Tenant-ID", "acme")
+		got := httptest.NewRecorder()
+		router.ServeHTTP(got, req)
+
+		if got.Code != expected.Code || got.Body.String() != expected.Body.String() {
+			t.Errorf("GET %s: expected in a new tenant %d %s, got %d %s",
+				path, expected.Code, expected.Body.String(), got.Code, got.Body.String())
+		}
+	}
+
+	if w := serveTenant(router, "", "GET", "/events_for_day?user_id=1&date=2024-01-15", nil); !strings.Contains(w.Body.String(), "Standup") {
+		t.Errorf("Expected the event in the default tenant, got %s", w.Body.String())
+	}
+
+	if w := serveTenant(router, "", "GET", "/dav/1/1.ics", nil); w.Code != http.StatusOK {
+		t.Errorf("Expected the event over CalDAV in the default tenant, got %d", w.Code)
+	}
+	if w := serveTenant(router, "acme", "GET", "/dav/1/1.ics", nil); w.Code != http.StatusNotFound {
+		t.Errorf("Expected no event over CalDAV in another tenant, got %d: %s", w.Code, w.Body.String())
+	}
+
+	// the tenant registry is reachable from the default tenant only
+	admin := []struct {
+		method, path string
+		body         map[string]any
+	}{
+		{"GET", "/admin/tenants", nil},
+		{"GET", "/admin/tenant_usage?tenant_id=default", nil},
+		{"POST", "/admin/create_tenant", map[string]any{"id": "other", "name": "Other"}},
+	}
+	for _, a := range admin {
+		if w := serveTenant(router, "acme", a.method, a.path, a.body); w.Code != http.StatusNotFound {
+			t.Errorf("%s %s: expected status %d in another tenant, got %d: %s", a.method, a.path, http.StatusNotFound, w.Code, w.Body.String())
+		}
+
+		req := httptest.NewRequest(a.method, a.path, nil)
+		req.Host = "acme.localhost"
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != http.StatusNotFound {
+			t.Errorf("%s %s: expected status %d on the host of another tenant, got %d", a.method, a.path, http.StatusNotFound, w.Code)
+		}
+	}
+}
+
+func TestTenants_Resolution(t *testing.T) {
+	router := setupTestRouter(t, "acme")
+	event := map[string]any{"name": "Standup", "date": "2024-01-15", "user_id": 1}
+
+	for _, tenant := range []string{"", "acme"} {
+		w := serveTenant(router, tenant, "POST", "/create_event", event)
+		if w.Code != http.StatusCreated || !strings.Contains(w.Body.String(), `"id":1`) {
+			t.Errorf("Expected id 1 in tenant %q, got %d %s", tenant, w.Code, w.Body.String())
+		}
+	}
+
+	req := httptest.NewRequest("POST", "/create_event", strings.NewReader(`{"name": "Retro", "date": "2024-01-15", "user_id": 1}`))
+	req.Host = "acme.localhost:8080"
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusCreated || !strings.Contains(w.Body.String(), `"id":2`) {
+		t.Errorf("Expected the host to pick the tenant, got %d %s", w.Code, w.Body.String())
+	}
+
+	if w := serveTenant(router, "unknown", "GET", "/ping", nil); w.Code != http.StatusNotFound {
+		t.Errorf("Expected status %d for an unknown tenant, got %d", http.StatusNotFound, w.Code)
+	}
+
+	body := map[string]any{"id": "other", "name": "Other", "hosts": []string{"ACME.localhost"}}
+	if w := serveTenant(router, "", "POST", "/admin/create_tenant", body); w.Code != http.StatusConflict {
+		t.Errorf("Expected status %d for a taken host, got %d: %s", http.StatusConflict, w.Code, w.Body.String())
+	}
+
+	body = map[string]any{"id": "Not a slug", "name": "Other"}
+	w = serveTenant(router, "", "POST", "/admin/create_tenant", body)
+	if code := fieldCodes(responseFields(t, w))["id"]; w.Code != http.StatusBadRequest || code != "invalid_slug" {
+		t.Errorf("Expected invalid_slug, got %d %s", w.Code, w.Body.String())
+	}
+}
+
+func TestTenants_Agenda(t *testing.T) {
+	cfg := config.Default()
+	cfg.Agenda.Users = []int{1}
+	cfg.Agenda.Tenants = map[string][]int{"acme": {1}}
+	cfg.Agenda.OutputDir = t.TempDir()
+
+	svc := service.NewService(repository.NewRepository(repository.InMemory), cfg)
+	router := NewRouter(svc, cfg)
+	if w := serveTenant(router, "", "POST", "/admin/create_tenant", map[string]any{"id": "acme", "name": "Acme"}); w.Code != http.StatusCreated {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusCreated, w.Code, w.Body.String())
+	}
+
+	tomorrow := date.StringFromTime(time.Now().UTC().AddDate(0, 0, 1))
+	serveTenant(router, "", "POST", "/create_event", map[string]any{"name": "Default Standup", "date": tomorrow, "user_id": 1})
+	serveTenant(router, "acme", "POST", "/create_event", map[string]any{"name": "Acme Standup", "date": tomorrow, "user_id": 1})
+
+	acme, err := svc.Tenants.Resolve("acme", "")
+	if err != nil {
+		t.Fatalf("Failed to resolve the tenant: %v", err)
+	}
+	for _, services := range []*service.Service{svc, acme} {
+		if err := services.Agenda.Deliver(t.Context()); err != nil {
+			t.Fatalf("Failed to deliver agendas: %v", err)
+		}
+	}
+
+	name := "agenda-1-day-" + tomorrow + ".md"
+	for dir, want := range map[string]string{"": "Default Standup", "acme": "Acme Standup"} {
+		file, err := os.ReadFile(filepath.Join(cfg.Agenda.OutputDir, dir, name))
+		if err != nil {
+			t.Fatalf("Expected the digest of tenant %q: %v", dir, err)
+		}
+		if !strings.Contains(string(file), want) || strings.Count(string(file), "Standup") != 1 {
+			t.Errorf("Expected only %q in the digest of tenant %q:\n%s", want, dir, file)
+		}
+	}
+}
+
+func TestTenants_Usage(t *testing.T) {
+	router := setupTestRouter(t, "acme")
+
+	serveTenant(router, "acme", "POST", "/create_calendar", map[string]any{"name": "Work", "user_id": 1})
+	for _, name := range []string{"Standup", "Retro"} {
+		serveTenant(router, "acme", "POST", "/create_event", map[string]any{"name": name, "date": "2024-01-15", "user_id": 1})
+	}
+
+	var usage struct {
+		Data struct {
+			TenantID  string `json:"tenant_id"`
+			Events    int    `json:"events"`
+			Calendars int    `json:"calendars"`
+			Shares    int    `json:"shares"`
+		} `json:"data"`
+	}
+	w := serveTenant(router, "", "GET", "/admin/tenant_usage?tenant_id=acme", nil)
+	if err := json.Unmarshal(w.Body.Bytes(), &usage); err != nil || w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	if usage.Data.TenantID != "acme" || usage.Data.Events != 2 || usage.Data.Calendars != 1 || usage.Data.Shares != 0 {
+		t.Errorf("Unexpected usage: %s", w.Body.String())
+	}
+
+	if w := serveTenant(router, "", "GET", "/admin/tenant_usage?tenant_id=unknown", nil); w.Code != http.StatusNotFound {
+		t.Errorf("Expected status %d for an unknown tenant, got %d", http.StatusNotFound, w.Code)
+	}
+
+	w = serveTenant(router, "", "GET", "/admin/tenants", nil)
+	if !strings.Contains(w.Body.String(), `"id":"default"`) || !strings.Contains(w.Body.String(), `"id":"acme"`) {
+		t.Errorf("Expected both tenants listed, got %s", w.Body.String())
+	}
+}
@@ -0,0 +1,102 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+	"wb_l2/18/internal/model"
+	"wb_l2/18/internal/service"
+	"wb_l2/18/pkg/http/response"
+)
+
+// exportWriteTimeout replaces the server write timeout for exports, which are
+// streamed for as long as the period takes
+const exportWriteTimeout = 5 * time.Minute
+
+// flushWriter sends every write to the client right away
+type flushWriter struct {
+	w  http.ResponseWriter
+	rc *http.ResponseController
+}
+
+func (f flushWriter) Write(p []byte) (int, error) {
+	n, err := f.w.Write(p)
+	if err != nil {
+		return n, err
+	}
+
+	return n, f.rc.Flush()
+}
+
+func (h *Handler) ExportEvents(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		response.MethodNotAllowed(w, "GET")
+		return
+	}
+
+	format, write, err := h.service.Event.Export(r.URL.Query())
+	if err != nil {
+		switch {
+		case errors.Is(err, service.InvalidQuery):
+			response.Response(w, http.StatusBadRequest, model.ErrorRespFromError(err))
+		default:
+			response.InternalServerError(w)
+		}
+		return
+	}
+
+	rc := http.NewResponseController(w)
+	// not every writer supports deadlines, like httptest.ResponseRecorder
+	_ = rc.SetWriteDeadline(time.Now().Add(exportWriteTimeout))
+
+	w.Header().Set("Content-Type", format.ContentType())
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="events.%s"`, format))
+	w.WriteHeader(http.StatusOK)
+
+	// the status is sent already, a failed export ends with a truncated body
+	if err := write(flushWriter{w: w, rc: rc}); err != nil {
+		slog.Error("Unable to export events", "error", err)
+	}
+}
+
+func (h *Handler) ImportEvents(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		response.MethodNotAllowed(w, "POST")
+		return
+	}
+
+	imported, err := h.service.Event.Import(r.URL.Query(), r.Body)
+	if err != nil {
+		var importErr *model.ImportError
+		switch {
+		case errors.As(err, &importErr):
+			h.importError(w, importErr)
+		case errors.Is(err, service.InvalidQuery), errors.Is(err, model.InvalidFormat):
+			response.Response(w, http.StatusBadRequest, model.ErrorRespFromError(err))
+		default:
+			response.InternalServerError(w)
+		}
+		return
+	}
+
+	if imported.DryRun {
+		response.Response(w, http.StatusOK, model.ResultWithDataResp("Events are valid, nothing imported on a dry run", imported))
+		return
+	}
+
+	response.Response(w, http.StatusCreated, model.ResultWithDataResp("Events imported", imported))
+}
+
+// importError tells the client how far a stopped import got. A row that became
+// invalid after the validation pass, like one of a deleted calendar, is a
+// conflict
+func (h *Handler) importError(w http.ResponseWriter, err *model.ImportError) {
+	switch {
+	case errors.Is(err, model.InvalidFormat), errors.Is(err, model.OutsideWorkingHours):
+		response.Response(w, http.StatusConflict, model.ImportErrorResp(err, model.ErrorRespFromError(err.Err)))
+	default:
+		response.Response(w, http.StatusInternalServerError, model.ImportErrorResp(err, model.ErrorResp("Something went wrong, try again later")))
+	}
+}
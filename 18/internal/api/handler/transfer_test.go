@@ -0,0 +1,214 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"wb_l2/18/internal/config"
+	"wb_l2/18/internal/model"
+	"wb_l2/18/internal/repository"
+	"wb_l2/18/internal/repository/inmemory/event"
+	"wb_l2/18/internal/service"
+)
+
+func exportEvents(t *testing.T, h *Handler, query string) (string, string) {
+	req := httptest.NewRequest("GET", "/events/export?"+query, nil)
+	w := httptest.NewRecorder()
+	h.mux.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+
+	return w.Header().Get("Content-Type"), w.Body.String()
+}
+
+func importEvents(h *Handler, query, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("POST", "/events/import?"+query, strings.NewReader(body))
+	req.Header.Set("Content-Type", "text/csv")
+	w := httptest.NewRecorder()
+
+	h.mux.ServeHTTP(w, req)
+	return w
+}
+
+func TestExportEvents_Formats(t *testing.T) {
+	h := setupTestHandler()
+
+	events := []map[string]interface{}{
+		{"name": "Standup, daily", "date": "2024-03-01", "user_id": 1},
+		{"name": "Kickoff", "date": "2024-01-15", "user_id": 1},
+		{"name": "Other user", "date": "2024-01-16", "user_id": 2},
+		{"name": "Too late", "date": "2024-04-01", "user_id": 1},
+	}
+	for _, event := range events {
+		if w := postJSON(h, "/create_event", event); w.Code != http.StatusCreated {
+			t.Fatalf("Expected status %d, got %d: %s", http.StatusCreated, w.Code, w.Body.String())
+		}
+	}
+
+	contentType, body := exportEvents(t, h, "user_id=1&from=2024-01-01&to=2024-03-31")
+	if !strings.HasPrefix(contentType, "text/csv") {
+		t.Errorf("Expected a CSV content type, got %q", contentType)
+	}
+	expected := "id,name,date,user_id,calendar_id\n2,Kickoff,2024-01-15,1,0\n1,\"Standup, daily\",2024-03-01,1,0\n"
+	if body != expected {
+		t.Errorf("Expected CSV:\n%s\ngot:\n%s", expected, body)
+	}
+
+	contentType, body = exportEvents(t, h, "user_id=1&from=2024-01-01&to=2024-03-01&format=jsonl")
+	if contentType != "application/x-ndjson" {
+		t.Errorf("Expected a JSON lines content type, got %q", contentType)
+	}
+	lines := strings.Split(strings.TrimSuffix(body, "\n"), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected 2 lines, got %q", body)
+	}
+	var event map[string]interface{}
+	if err := json.Unmarshal([]byte(lines[1]), &event); err != nil || event["name"] != "Standup, daily" {
+		t.Errorf("Expected the last event on the last line, got %q (%v)", lines[1], err)
+	}
+
+	_, body = exportEvents(t, h, "user_id=3&from=2024-01-01&to=2024-03-01")
+	if body != "id,name,date,user_id,calendar_id\n" {
+		t.Errorf("Expected only the header for no events, got %q", body)
+	}
+}
+
+func TestExportEvents_InvalidQuery(t *testing.T) {
+	h := setupTestHandler()
+
+	req := httptest.NewRequest("GET", "/events/export?user_id=1&from=2024-02-01&to=2024-01-01&format=xml", nil)
+	w := httptest.NewRecorder()
+	h.mux.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("Expected status %d, got %d", http.StatusBadRequest, w.Code)
+	}
+	if codes := fieldCodes(responseFields(t, w)); codes["format"] != "unknown_value" {
+		t.Errorf("Expected an error for format, got %v", codes)
+	}
+
+	req = httptest.NewRequest("GET", "/events/export?user_id=1&from=2024-02-01&to=2024-01-01", nil)
+	w = httptest.NewRecorder()
+	h.mux.ServeHTTP(w, req)
+
+	if codes := fieldCodes(responseFields(t, w)); w.Code != http.StatusBadRequest || codes["to"] != "too_small" {
+		t.Errorf("Expected too_small for to before from, got %d %s", w.Code, w.Body.String())
+	}
+}
+
+func TestImportEvents_RoundTrip(t *testing.T) {
+	source := setupTestHandler()
+	postJSON(source, "/create_event", map[string]interface{}{"name": "Standup, daily", "date": "2024-01-15", "user_id": 1})
+	postJSON(source, "/create_event", map[string]interface{}{"name": "Retro", "date": "2024-01-19", "user_id": 1})
+	_, exported := exportEvents(t, source, "user_id=1&from=2024-01-01&to=2024-01-31")
+
+	h := setupTestHandler()
+
+	w := importEvents(h, "dry_run=true", exported)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d on a dry run, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	if _, body := exportEvents(t, h, "user_id=1&from=2024-01-01&to=2024-01-31"); strings.Count(body, "\n") != 1 {
+		t.Errorf("Expected nothing imported on a dry run, got:\n%s", body)
+	}
+
+	w = importEvents(h, "", exported)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusCreated, w.Code, w.Body.String())
+	}
+	if !strings.Contains(w.Body.String(), `"imported":2`) {
+		t.Errorf("Expected 2 imported events, got %s", w.Body.String())
+	}
+
+	if _, body := exportEvents(t, h, "user_id=1&from=2024-01-01&to=2024-01-31"); body != exported {
+		t.Errorf("Expected the same export after the import:\n%s\ngot:\n%s", exported, body)
+	}
+}
+
+func TestImportEvents_LineErrors(t *testing.T) {
+	h := setupTestHandler()
+
+	csv := "name,date,user_id\nStandup,2024-01-15,1\n,2024-01-16,1\nRetro,16.01.2024,abc\n"
+	w := importEvents(h, "", csv)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusBadRequest, w.Code, w.Body.String())
+	}
+
+	got := make(map[string]float64)
+	for _, field := range responseFields(t, w) {
+		got[field["field"].(string)+" "+field["code"].(string)] = field["line"].(float64)
+	}
+	expected := map[string]float64{"name required": 3, "date invalid_date": 4, "user_id invalid_type": 4}
+	for key, line := range expected {
+		if got[key] != line {
+			t.Errorf("Expected %s on line %v, got %v", key, line, got)
+		}
+	}
+
+	if _, body := exportEvents(t, h, "user_id=1&from=2024-01-01&to=2024-01-31"); strings.Contains(body, "Standup") {
+		t.Errorf("Expected nothing imported when a row fails, got:\n%s", body)
+	}
+
+	jsonl := `{"name":"Standup","date":"2024-01-15","user_id":1}` + "\n\n" + `{"name":"Retro","date":"2024-01-19"}` + "\n" + "{\n"
+	w = importEvents(h, "format=jsonl", jsonl)
+	got = make(map[string]float64)
+	for _, field := range responseFields(t, w) {
+		got[field["field"].(string)+" "+field["code"].(string)] = field["line"].(float64)
+	}
+	if w.Code != http.StatusBadRequest || got["user_id not_positive"] != 3 || got[" invalid_json"] != 4 {
+		t.Errorf("Expected errors on lines 3 and 4, got %d %s", w.Code, w.Body.String())
+	}
+
+	w = importEvents(h, "", "name,date,owner\n")
+	if code := fieldCodes(responseFields(t, w))["owner"]; w.Code != http.StatusBadRequest || code != "unknown_field" {
+		t.Errorf("Expected unknown_field for an unknown column, got %d %s", w.Code, w.Body.String())
+	}
+
+	w = importEvents(h, "", "name,date,user_id\n\"Standup,2024-01-15,1\n")
+	if code := fieldCodes(responseFields(t, w))[""]; w.Code != http.StatusBadRequest || code != "invalid_csv" {
+		t.Errorf("Expected invalid_csv for a broken quote, got %d %s", w.Code, w.Body.String())
+	}
+}
+
+// failingEvents stores the first limit events and fails after that
+type failingEvents struct {
+	*event.EventRepository
+	limit int
+}
+
+func (r *failingEvents) Create(e *model.Event) (int, error) {
+	if r.limit == 0 {
+		return 0, errors.New("storage is full")
+	}
+	r.limit--
+
+	return r.EventRepository.Create(e)
+}
+
+func TestImportEvents_StoppedHalfway(t *testing.T) {
+	repo := repository.NewRepository(repository.InMemory)
+	repo.Event = &failingEvents{EventRepository: repo.Event.(*event.EventRepository), limit: 1}
+	h := NewHandler(service.NewService(repo, config.Default()))
+	RegisterHandlers(h)
+
+	w := importEvents(h, "", "name,date,user_id\nStandup,2024-01-15,1\nRetro,2024-01-19,1\n")
+	if w.Code != http.StatusInternalServerError {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusInternalServerError, w.Code, w.Body.String())
+	}
+
+	var stopped struct {
+		Line     int `json:"line"`
+		Imported int `json:"imported"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &stopped); err != nil {
+		t.Fatalf("Failed to unmarshal response: %v", err)
+	}
+	if stopped.Line != 3 || stopped.Imported != 1 {
+		t.Errorf("Expected the import to stop at line 3 after 1 event, got %s", w.Body.String())
+	}
+}
@@ -0,0 +1,132 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func responseFields(t *testing.T, w *httptest.ResponseRecorder) []map[string]interface{} {
+	var response struct {
+		Fields []map[string]interface{} `json:"fields"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to unmarshal response: %v", err)
+	}
+
+	return response.Fields
+}
+
+func fieldCodes(fields []map[string]interface{}) map[string]string {
+	codes := make(map[string]string, len(fields))
+	for _, f := range fields {
+		codes[f["field"].(string)] = f["code"].(string)
+	}
+
+	return codes
+}
+
+func TestCreateEvent_FieldErrors(t *testing.T) {
+	handler := setupTestHandler()
+
+	cases := []struct {
+		name string
+		data map[string]interface{}
+		want map[string]string
+	}{
+		{
+			"all missing",
+			map[string]interface{}{},
+			map[string]string{"name": "required", "date": "required", "user_id": "not_positive"},
+		},
+		{
+			"bad values",
+			map[string]interface{}{"name": strings.Repeat("a", 256), "date": "15.01.2024", "user_id": -1},
+			map[string]string{"name": "too_long", "date": "invalid_date", "user_id": "not_positive"},
+		},
+		{
+			"wrong type",
+			map[string]interface{}{"name": "Event", "date": "2024-01-15", "user_id": "one"},
+			map[string]string{"user_id": "invalid_type"},
+		},
+	}
+
+	for _, tc := range cases {
+		w := postJSON(handler, "/create_event", tc.data)
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status %d, got %d", tc.name, http.StatusBadRequest, w.Code)
+		}
+
+		got := fieldCodes(responseFields(t, w))
+		if len(got) != len(tc.want) {
+			t.Errorf("%s: expected fields %v, got %v", tc.name, tc.want, got)
+			continue
+		}
+
+		for field, code := range tc.want {
+			if got[field] != code {
+				t.Errorf("%s: expected %s to fail with %q, got %q", tc.name, field, code, got[field])
+			}
+		}
+	}
+}
+
+func TestUpdateEvent_FieldErrors(t *testing.T) {
+	handler := setupTestHandler()
+
+	w := postJSON(handler, "/update_event", map[string]interface{}{"id": 0, "date": "2024-13-01"})
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d, got %d", http.StatusBadRequest, w.Code)
+	}
+
+	got := fieldCodes(responseFields(t, w))
+	if got["id"] != "not_positive" || got["date"] != "invalid_date" || len(got) != 2 {
+		t.Errorf("Expected id and date field errors, got %v", got)
+	}
+}
+
+func TestDeleteEvent_FieldErrors(t *testing.T) {
+	handler := setupTestHandler()
+
+	w := postJSON(handler, "/delete_event", map[string]interface{}{"id": -5})
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d, got %d", http.StatusBadRequest, w.Code)
+	}
+
+	got := fieldCodes(responseFields(t, w))
+	if got["id"] != "not_positive" {
+		t.Errorf("Expected id field error, got %v", got)
+	}
+}
+
+func TestListEvents_FieldErrors(t *testing.T) {
+	handler := setupTestHandler()
+
+	req := httptest.NewRequest("GET", "/events_for_week?user_id=x&date=tomorrow", nil)
+	w := httptest.NewRecorder()
+
+	handler.mux.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d, got %d", http.StatusBadRequest, w.Code)
+	}
+
+	var response map[string]interface{}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to unmarshal response: %v", err)
+	}
+
+	if response["error"] != "Invalid query provided" {
+		t.Errorf("Expected error 'Invalid query provided', got %v", response["error"])
+	}
+
+	got := fieldCodes(responseFields(t, w))
+	if got["user_id"] != "invalid_type" || got["date"] != "invalid_date" {
+		t.Errorf("Expected user_id and date field errors, got %v", got)
+	}
+}
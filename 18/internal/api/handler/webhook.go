@@ -0,0 +1,128 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"wb_l2/18/internal/model"
+	"wb_l2/18/internal/repository/inmemory/webhook"
+	"wb_l2/18/internal/service"
+	"wb_l2/18/pkg/http/request"
+	"wb_l2/18/pkg/http/response"
+)
+
+func (h *Handler) CreateWebhook(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		response.MethodNotAllowed(w, "POST")
+		return
+	}
+
+	body, err := request.ReadBody(w, r)
+	if err != nil {
+		return
+	}
+
+	webhook, err := h.service.Webhook.Create(body)
+	if err != nil {
+		switch {
+		case errors.Is(err, model.InvalidFormat):
+			response.Response(w, http.StatusBadRequest, model.ErrorRespFromError(err))
+		default:
+			response.InternalServerError(w)
+		}
+		return
+	}
+
+	response.Response(
+		w,
+		http.StatusCreated,
+		model.ResultWithDataResp("Webhook created successfully", webhook),
+	)
+}
+
+func (h *Handler) ListWebhooks(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		response.MethodNotAllowed(w, "GET")
+		return
+	}
+
+	webhooks, err := h.service.Webhook.List(r.URL.Query())
+	if err != nil {
+		switch {
+		case errors.Is(err, service.InvalidQuery):
+			response.Response(w, http.StatusBadRequest, model.ErrorRespFromError(err))
+		default:
+			response.InternalServerError(w)
+		}
+		return
+	}
+
+	response.Response(
+		w,
+		http.StatusOK,
+		model.ResultWithDataResp("List of webhooks", webhooks),
+	)
+}
+
+func (h *Handler) DeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		response.MethodNotAllowed(w, "POST")
+		return
+	}
+
+	body, err := request.ReadBody(w, r)
+	if err != nil {
+		return
+	}
+
+	if err := h.service.Webhook.Delete(body); err != nil {
+		switch {
+		case errors.Is(err, model.InvalidFormat):
+			response.Response(w, http.StatusBadRequest, model.ErrorRespFromError(err))
+		case errors.Is(err, service.Forbidden):
+			response.Response(w, http.StatusForbidden, model.ErrorResp(err.Error()))
+		case errors.Is(err, webhook.ErrorWebhookNotFound):
+			response.Response(w, http.StatusNotFound, model.ErrorResp(err.Error()))
+		default:
+			response.InternalServerError(w)
+		}
+		return
+	}
+
+	response.Response(
+		w,
+		http.StatusOK,
+		model.ResultResp("Webhook deleted successfully"),
+	)
+}
+
+func (h *Handler) ListWebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	h.listDeliveries(w, r, "", "List of webhook deliveries")
+}
+
+func (h *Handler) ListWebhookDeadLetters(w http.ResponseWriter, r *http.Request) {
+	h.listDeliveries(w, r, model.DeliveryDead, "List of dead webhook deliveries")
+}
+
+func (h *Handler) listDeliveries(w http.ResponseWriter, r *http.Request, status model.DeliveryStatus, message string) {
+	if r.Method != "GET" {
+		response.MethodNotAllowed(w, "GET")
+		return
+	}
+
+	deliveries, err := h.service.Webhook.Deliveries(r.URL.Query(), status)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.InvalidQuery):
+			response.Response(w, http.StatusBadRequest, model.ErrorRespFromError(err))
+		default:
+			response.InternalServerError(w)
+		}
+		return
+	}
+
+	response.Response(
+		w,
+		http.StatusOK,
+		model.ResultWithDataResp(message, deliveries),
+	)
+}
@@ -0,0 +1,327 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+	"wb_l2/18/internal/config"
+	"wb_l2/18/internal/repository"
+	"wb_l2/18/internal/service"
+)
+
+type received struct {
+	header http.Header
+	body   []byte
+}
+
+// receiver is a webhook endpoint answering with the next status from statuses,
+// the last status repeats
+type receiver struct {
+	server   *httptest.Server
+	statuses []int
+
+	mu       sync.Mutex
+	requests []received
+	calls    atomic.Int32
+}
+
+func newReceiver(t *testing.T, statuses ...int) *receiver {
+	rcv := &receiver{statuses: statuses}
+	rcv.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+
+		rcv.mu.Lock()
+		rcv.requests = append(rcv.requests, received{header: r.Header.Clone(), body: body})
+		rcv.mu.Unlock()
+
+		call := int(rcv.calls.Add(1))
+		w.WriteHeader(rcv.statuses[min(call, len(rcv.statuses))-1])
+	}))
+	t.Cleanup(rcv.server.Close)
+
+	return rcv
+}
+
+func setupWebhookTestHandler(t *testing.T) *Handler {
+	cfg := config.Default()
+	cfg.Webhook.MaxAttempts = 3
+	cfg.Webhook.Backoff = time.Millisecond
+
+	repo := repository.NewRepository(repository.InMemory)
+	svc := service.NewService(repo, cfg)
+	svc.Webhook.Start(t.Context())
+
+	h := NewHandler(svc)
+	RegisterHandlers(h)
+	return h
+}
+
+func postJSON(h *Handler, path string, data any) *httptest.ResponseRecorder {
+	jsonData, _ := json.Marshal(data)
+	req := httptest.NewRequest("POST", path, bytes.NewBuffer(jsonData))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+
+	h.mux.ServeHTTP(w, req)
+	return w
+}
+
+func getJSON(t *testing.T, h *Handler, path string) map[string]interface{} {
+	req := httptest.NewRequest("GET", path, nil)
+	w := httptest.NewRecorder()
+
+	h.mux.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+
+	var response map[string]interface{}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to unmarshal response: %v", err)
+	}
+
+	return response
+}
+
+func createWebhook(t *testing.T, h *Handler, url string, events ...string) string {
+	w := postJSON(h, "/create_webhook", map[string]interface{}{
+		"url":     url,
+		"events":  events,
+		"user_id": 1,
+	})
+
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusCreated, w.Code, w.Body.String())
+	}
+
+	var response map[string]interface{}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to unmarshal response: %v", err)
+	}
+
+	data := response["data"].(map[string]interface{})
+	secret, _ := data["secret"].(string)
+	if secret == "" {
+		t.Fatal("Expected generated secret in response data")
+	}
+
+	return secret
+}
+
+// waitForDeliveries polls the delivery log until every delivery is finished
+func waitForDeliveries(t *testing.T, h *Handler, count int) []interface{} {
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		response := getJSON(t, h, "/webhook_deliveries?user_id=1")
+		deliveries := response["data"].([]interface{})
+
+		finished := 0
+		for _, d := range deliveries {
+			if d.(map[string]interface{})["status"] != "pending" {
+				finished++
+			}
+		}
+
+		if len(deliveries) == count && finished == count {
+			return deliveries
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	t.Fatalf("Timed out waiting for %d finished deliveries", count)
+	return nil
+}
+
+func TestCreateWebhook_InvalidFormat(t *testing.T) {
+	handler := setupTestHandler()
+
+	cases := []map[string]interface{}{
+		{"url": "not a url", "events": []string{"event.created"}, "user_id": 1},
+		{"url": "http://localhost/hook", "events": []string{}, "user_id": 1},
+		{"url": "http://localhost/hook", "events": []string{"event.renamed"}, "user_id": 1},
+		{"url": "http://localhost/hook", "events": []string{"event.created"}, "user_id": 0},
+	}
+
+	for _, data := range cases {
+		w := postJSON(handler, "/create_webhook", data)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("Expected status %d for %v, got %d", http.StatusBadRequest, data, w.Code)
+		}
+	}
+}
+
+func TestListWebhooks_HidesSecret(t *testing.T) {
+	handler := setupTestHandler()
+
+	createWebhook(t, handler, "http://localhost/hook", "event.created")
+
+	response := getJSON(t, handler, "/webhooks?user_id=1")
+	webhooks := response["data"].([]interface{})
+	if len(webhooks) != 1 {
+		t.Fatalf("Expected 1 webhook, got %d", len(webhooks))
+	}
+
+	if _, exists := webhooks[0].(map[string]interface{})["secret"]; exists {
+		t.Error("Expected secret to be hidden in webhook list")
+	}
+}
+
+func TestDeleteWebhook_NotFound(t *testing.T) {
+	handler := setupTestHandler()
+
+	w := postJSON(handler, "/delete_webhook", map[string]interface{}{"id": 999, "user_id": 1})
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status %d, got %d", http.StatusNotFound, w.Code)
+	}
+}
+
+func TestDeleteWebhook_Owner(t *testing.T) {
+	handler := setupTestHandler()
+	createWebhook(t, handler, "http://example.com/hook", "event.created")
+
+	w := postJSON(handler, "/delete_webhook", map[string]interface{}{"id": 1})
+	if _, ok := fieldCodes(responseFields(t, w))["user_id"]; w.Code != http.StatusBadRequest || !ok {
+		t.Errorf("Expected user_id to be required, got %d %s", w.Code, w.Body.String())
+	}
+
+	w = postJSON(handler, "/delete_webhook", map[string]interface{}{"id": 1, "user_id": 2})
+	if w.Code != http.StatusForbidden {
+		t.Errorf("Expected status %d for another user, got %d", http.StatusForbidden, w.Code)
+	}
+
+	w = postJSON(handler, "/delete_webhook", map[string]interface{}{"id": 1, "user_id": 1})
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status %d for the owner, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+}
+
+func TestWebhook_SignedDelivery(t *testing.T) {
+	handler := setupWebhookTestHandler(t)
+	rcv := newReceiver(t, http.StatusOK)
+
+	secret := createWebhook(t, handler, rcv.server.URL, "event.created", "event.deleted")
+
+	w := postJSON(handler, "/create_event", map[string]interface{}{
+		"name":    "Test Event",
+		"date":    "2024-01-15",
+		"user_id": 1,
+	})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status %d, got %d", http.StatusCreated, w.Code)
+	}
+
+	// not subscribed, must not be delivered
+	postJSON(handler, "/update_event", map[string]interface{}{"id": 1, "name": "Renamed", "user_id": 1})
+	postJSON(handler, "/delete_event", map[string]interface{}{"id": 1, "user_id": 1})
+
+	waitForDeliveries(t, handler, 2)
+
+	rcv.mu.Lock()
+	defer rcv.mu.Unlock()
+
+	if len(rcv.requests) != 2 {
+		t.Fatalf("Expected 2 requests to receiver, got %d", len(rcv.requests))
+	}
+
+	// deliveries run on several workers, so the arrival order is not fixed
+	byEvent := make(map[string]received)
+	for _, req := range rcv.requests {
+		byEvent[req.header.Get(service.EventHeader)] = req
+	}
+
+	for _, want := range []string{"event.created", "event.deleted"} {
+		req, ok := byEvent[want]
+		if !ok {
+			t.Errorf("Expected %q delivery", want)
+			continue
+		}
+
+		if got := req.header.Get(service.SignatureHeader); got != service.Sign(secret, req.body) {
+			t.Errorf("Signature mismatch for %s delivery: %q", want, got)
+		}
+
+		var payload map[string]interface{}
+		if err := json.Unmarshal(req.body, &payload); err != nil {
+			t.Fatalf("Failed to unmarshal payload: %v", err)
+		}
+
+		if payload["type"] != want {
+			t.Errorf("Expected payload type %q, got %v", want, payload["type"])
+		}
+
+		event := payload["event"].(map[string]interface{})
+		if event["date"] != "2024-01-15" {
+			t.Errorf("Expected event date in payload, got %v", event["date"])
+		}
+	}
+}
+
+func TestWebhook_RetriesWithBackoff(t *testing.T) {
+	handler := setupWebhookTestHandler(t)
+	rcv := newReceiver(t, http.StatusInternalServerError, http.StatusBadGateway, http.StatusOK)
+
+	createWebhook(t, handler, rcv.server.URL, "event.created")
+
+	postJSON(handler, "/create_event", map[string]interface{}{
+		"name":    "Test Event",
+		"date":    "2024-01-15",
+		"user_id": 1,
+	})
+
+	deliveries := waitForDeliveries(t, handler, 1)
+	delivery := deliveries[0].(map[string]interface{})
+
+	if delivery["status"] != "succeeded" {
+		t.Errorf("Expected delivery to succeed, got %v", delivery["status"])
+	}
+
+	attempts := delivery["attempts"].([]interface{})
+	if len(attempts) != 3 {
+		t.Fatalf("Expected 3 attempts, got %d", len(attempts))
+	}
+
+	if code := attempts[0].(map[string]interface{})["status_code"]; code != float64(http.StatusInternalServerError) {
+		t.Errorf("Expected first attempt to record status 500, got %v", code)
+	}
+
+	response := getJSON(t, handler, "/webhook_dead_letters?user_id=1")
+	if dead := response["data"].([]interface{}); len(dead) != 0 {
+		t.Errorf("Expected no dead letters, got %d", len(dead))
+	}
+}
+
+func TestWebhook_DeadLetter(t *testing.T) {
+	handler := setupWebhookTestHandler(t)
+	rcv := newReceiver(t, http.StatusServiceUnavailable)
+
+	createWebhook(t, handler, rcv.server.URL, "event.created")
+
+	postJSON(handler, "/create_event", map[string]interface{}{
+		"name":    "Test Event",
+		"date":    "2024-01-15",
+		"user_id": 1,
+	})
+
+	waitForDeliveries(t, handler, 1)
+
+	if calls := rcv.calls.Load(); calls != 3 {
+		t.Errorf("Expected 3 delivery attempts, got %d", calls)
+	}
+
+	response := getJSON(t, handler, "/webhook_dead_letters?user_id=1")
+	dead := response["data"].([]interface{})
+	if len(dead) != 1 {
+		t.Fatalf("Expected 1 dead letter, got %d", len(dead))
+	}
+
+	if status := dead[0].(map[string]interface{})["status"]; status != "dead" {
+		t.Errorf("Expected dead status, got %v", status)
+	}
+}
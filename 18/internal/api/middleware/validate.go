@@ -0,0 +1,80 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"wb_l2/18/internal/api/openapi"
+	"wb_l2/18/internal/model"
+	"wb_l2/18/internal/service"
+	"wb_l2/18/pkg/http/response"
+)
+
+// Validate rejects requests that do not match the operation described in doc.
+// Paths missing from doc are passed through untouched, requests with a
+// non-JSON body are left to the handler to answer with 415
+func Validate(doc *openapi.Document) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(writer http.ResponseWriter, req *http.Request) {
+			item, ok := doc.Paths[req.URL.Path]
+			if !ok {
+				next.ServeHTTP(writer, req)
+				return
+			}
+
+			op := item.Operation(req.Method)
+			if op == nil {
+				response.MethodNotAllowed(writer, item.Allow())
+				return
+			}
+
+			if violations := doc.ValidateQuery(op, req.URL.Query()); len(violations) > 0 {
+				reject(writer, service.InvalidQuery, violations)
+				return
+			}
+
+			if op.RequestBody == nil || !strings.Contains(req.Header.Get("Content-Type"), openapi.JSON) {
+				next.ServeHTTP(writer, req)
+				return
+			}
+
+			// operations taking other media types answer a JSON body themselves
+			media, ok := op.RequestBody.Content[openapi.JSON]
+			if !ok {
+				next.ServeHTTP(writer, req)
+				return
+			}
+
+			body, err := io.ReadAll(req.Body)
+			if err != nil {
+				response.Response(writer, http.StatusBadRequest, model.ErrorResp("Invalid body"))
+				return
+			}
+			req.Body = io.NopCloser(bytes.NewReader(body))
+
+			var value any
+			if err := json.Unmarshal(body, &value); err != nil {
+				reject(writer, model.InvalidFormat, []model.FieldError{
+					{Code: model.CodeInvalidJSON, Message: "body must be a JSON object"},
+				})
+				return
+			}
+
+			if violations := doc.Validate(media.Schema, value); len(violations) > 0 {
+				reject(writer, model.InvalidFormat, violations)
+				return
+			}
+
+			next.ServeHTTP(writer, req)
+		})
+	}
+}
+
+func reject(writer http.ResponseWriter, base error, fields []model.FieldError) {
+	verr := model.NewValidationError(base)
+	verr.Fields = fields
+
+	response.Response(writer, http.StatusBadRequest, model.ErrorRespFromError(verr))
+}
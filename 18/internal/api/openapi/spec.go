@@ -0,0 +1,845 @@
+package openapi
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+	"wb_l2/18/internal/model"
+)
+
+// Spec returns the OpenAPI document of the calendar API. It is built once and
+// must not be modified by callers
+var Spec = sync.OnceValue(func() *Document {
+	return &Document{
+		OpenAPI: "3.0.3",
+		Info: Info{
+			Title: "HTTP Calendar Server",
+			Description: "Requests are served in the tenant named in the X-Tenant-ID header or owning the host, " +
+				"in the default tenant otherwise. Every tenant has its own users, events and ids",
+			Version: "1.0.0",
+		},
+		Paths: map[string]*PathItem{
+			"/ping": {
+				Get: &Operation{
+					Summary:     "Check that the server is up",
+					OperationID: "ping",
+					Responses:   responses(http.StatusOK, result("Pong", nil)),
+				},
+			},
+			"/openapi.json": {
+				Get: &Operation{
+					Summary:     "This document",
+					OperationID: "getOpenAPI",
+					Responses: responses(http.StatusOK, &Response{
+						Description: "OpenAPI document",
+						Content:     jsonContent(&Schema{Type: "object"}),
+					}),
+				},
+			},
+
+			"/healthz": {
+				Get: &Operation{
+					Summary:     "Liveness probe",
+					OperationID: "healthz",
+					Responses:   responses(http.StatusOK, result("Process is alive", nil)),
+				},
+			},
+			"/readyz": {
+				Get: &Operation{
+					Summary:     "Readiness probe, checks the storage and fails during graceful shutdown",
+					OperationID: "readyz",
+					Responses: responses(
+						http.StatusOK, result("Ready to serve", nil),
+						http.StatusServiceUnavailable, failure("Shutting down or storage is unavailable"),
+					),
+				},
+			},
+			"/version": {
+				Get: &Operation{
+					Summary:     "Build metadata of the server binary",
+					OperationID: "version",
+					Responses:   responses(http.StatusOK, result("Build info", ref("BuildInfo"))),
+				},
+			},
+
+			"/create_event": {
+				Post: &Operation{
+					Summary:     "Create an event",
+					OperationID: "createEvent",
+					Parameters: []*Parameter{
+						{Name: "Idempotency-Key", In: "header", Schema: &Schema{
+							Type:        "string",
+							MaxLength:   intPtr(model.MaxIdempotencyKeyLength),
+							Description: "Retries with the same key and body get the first response back instead of creating the event again",
+						}},
+					},
+					RequestBody: jsonBody(ref("EventCreate"), map[string]any{
+						"name": "Meeting", "date": "2024-01-15", "user_id": 1,
+					}),
+					Responses: responses(
+						http.StatusCreated, result("Event created", ref("WithID")),
+						http.StatusBadRequest, failure("Invalid body or Idempotency-Key"),
+						http.StatusConflict, failure("The first request with the Idempotency-Key is still in progress"),
+						http.StatusUnsupportedMediaType, failure("Body is not JSON"),
+						http.StatusUnprocessableEntity, failure("Date is a day off of the user and the schedule rejects such events, or the Idempotency-Key is used with another body"),
+					),
+				},
+			},
+			"/events_for_day": listEvents("listEventsForDay", "List events of a user for a day"),
+			"/events_for_week": listEvents("listEventsForWeek", "List events of a user for a week starting at date or containing it",
+				modeParam(), weekStartParam()),
+			"/events_for_month": listEvents("listEventsForMonth", "List events of a user for a month starting at date or containing it",
+				modeParam()),
+			"/update_event": {
+				Post: &Operation{
+					Summary:     "Update name and/or date of an event",
+					OperationID: "updateEvent",
+					RequestBody: jsonBody(ref("EventUpdate"), map[string]any{
+						"id": 1, "name": "Renamed", "user_id": 1,
+					}),
+					Responses: responses(
+						http.StatusOK, result("Updated event", ref("Event")),
+						http.StatusBadRequest, failure("Invalid body"),
+						http.StatusForbidden, failure("Acting user has read-only access"),
+						http.StatusNotFound, failure("Event is not found"),
+						http.StatusUnsupportedMediaType, failure("Body is not JSON"),
+					),
+				},
+			},
+			"/delete_event": {
+				Post: &Operation{
+					Summary:     "Delete an event",
+					OperationID: "deleteEvent",
+					RequestBody: jsonBody(ref("EventDelete"), map[string]any{"id": 1, "user_id": 1}),
+					Responses: responses(
+						http.StatusOK, result("Event deleted", nil),
+						http.StatusBadRequest, failure("Invalid body"),
+						http.StatusForbidden, failure("Acting user has read-only access"),
+						http.StatusNotFound, failure("Event is not found"),
+						http.StatusUnsupportedMediaType, failure("Body is not JSON"),
+					),
+				},
+			},
+			"/events/export": {
+				Get: &Operation{
+					Summary:     "Stream own events of a user from from to to as CSV with a header or as JSON lines",
+					OperationID: "exportEvents",
+					Parameters: []*Parameter{
+						userIDParam(),
+						{Name: "from", In: "query", Required: true, Schema: dateInput(), Example: "2024-01-01"},
+						{Name: "to", In: "query", Required: true, Schema: dateInput(), Example: "2024-12-31"},
+						transferFormatParam(),
+					},
+					Responses: responses(
+						http.StatusOK, &Response{
+							Description: "Events ordered by date, the CSV header is id,name,date,user_id,calendar_id",
+							Content: map[string]*MediaType{
+								"text/csv":             {Schema: &Schema{Type: "string"}},
+								"application/x-ndjson": {Schema: &Schema{Type: "string"}},
+							},
+						},
+						http.StatusBadRequest, failure("Invalid query"),
+					),
+				},
+			},
+			"/events/import": {
+				Post: &Operation{
+					Summary:     "Create events from a CSV file with a header or JSON lines, nothing is created when any row fails",
+					OperationID: "importEvents",
+					Parameters: []*Parameter{
+						transferFormatParam(),
+						{Name: "dry_run", In: "query", Schema: &Schema{
+							Type:        "boolean",
+							Description: "Only validate the rows, defaults to false",
+						}},
+					},
+					RequestBody: &RequestBody{
+						Required: true,
+						Content: map[string]*MediaType{
+							"text/csv": {
+								Schema:  &Schema{Type: "string", Description: "Columns name, date, user_id and calendar_id in any order, id is ignored"},
+								Example: "name,date,user_id\nStandup,2024-01-15,1\nRetro,2024-01-19,1\n",
+							},
+							"application/x-ndjson": {
+								Schema:  &Schema{Type: "string", Description: "An object like the body of /create_event per line"},
+								Example: `{"name":"Standup","date":"2024-01-15","user_id":1}` + "\n",
+							},
+						},
+					},
+					Responses: responses(
+						http.StatusCreated, result("Events imported", ref("Import")),
+						http.StatusOK, result("Dry run, every row is valid", ref("Import")),
+						http.StatusBadRequest, failure("Invalid query or rows, fields come with the line of the row"),
+						http.StatusConflict, importFailure("A row became invalid after the validation, the rows before it are imported"),
+						http.StatusInternalServerError, importFailure("The import stopped at a row, the rows before it are imported"),
+					),
+				},
+			},
+
+			"/create_calendar": {
+				Post: &Operation{
+					Summary:     "Create a named calendar for a user",
+					OperationID: "createCalendar",
+					RequestBody: jsonBody(ref("CalendarCreate"), map[string]any{
+						"name": "Work", "user_id": 1,
+					}),
+					Responses: responses(
+						http.StatusCreated, result("Calendar created", ref("Calendar")),
+						http.StatusBadRequest, failure("Invalid body"),
+						http.StatusConflict, failure("Calendar with this name already exists"),
+						http.StatusUnsupportedMediaType, failure("Body is not JSON"),
+					),
+				},
+			},
+			"/calendars": {
+				Get: &Operation{
+					Summary:     "List calendars of a user",
+					OperationID: "listCalendars",
+					Parameters:  []*Parameter{userIDParam()},
+					Responses: responses(
+						http.StatusOK, result("Calendars", arrayOf(ref("Calendar"))),
+						http.StatusBadRequest, failure("Invalid query"),
+					),
+				},
+			},
+			"/update_calendar": {
+				Post: &Operation{
+					Summary:     "Rename a calendar or toggle its visibility in event lists",
+					OperationID: "updateCalendar",
+					RequestBody: jsonBody(ref("CalendarUpdate"), map[string]any{
+						"id": 1, "visible": false, "user_id": 1,
+					}),
+					Responses: responses(
+						http.StatusOK, result("Updated calendar", ref("Calendar")),
+						http.StatusBadRequest, failure("Invalid body"),
+						http.StatusForbidden, failure("The user is not the owner of the calendar"),
+						http.StatusNotFound, failure("Calendar is not found"),
+						http.StatusConflict, failure("Calendar with this name already exists"),
+						http.StatusUnsupportedMediaType, failure("Body is not JSON"),
+					),
+				},
+			},
+			"/delete_calendar": {
+				Post: &Operation{
+					Summary:     "Delete a calendar, deleting its events or moving them to another calendar",
+					OperationID: "deleteCalendar",
+					RequestBody: jsonBody(ref("CalendarDelete"), map[string]any{
+						"id": 1, "events": "move", "user_id": 1,
+					}),
+					Responses: responses(
+						http.StatusOK, result("Calendar deleted", nil),
+						http.StatusBadRequest, failure("Invalid body"),
+						http.StatusForbidden, failure("The user is not the owner of the calendar"),
+						http.StatusNotFound, failure("Calendar is not found"),
+						http.StatusUnsupportedMediaType, failure("Body is not JSON"),
+					),
+				},
+			},
+
+			"/share_events": {
+				Post: &Operation{
+					Summary:     "Share all events of the owner with another user, replacing the permission of an existing share",
+					OperationID: "shareEvents",
+					RequestBody: jsonBody(ref("Share"), map[string]any{
+						"owner_id": 1, "grantee_id": 2, "permission": "read",
+					}),
+					Responses: responses(
+						http.StatusOK, result("Share", ref("Share")),
+						http.StatusBadRequest, failure("Invalid body"),
+						http.StatusUnsupportedMediaType, failure("Body is not JSON"),
+					),
+				},
+			},
+			"/shares": {
+				Get: &Operation{
+					Summary:     "Shares given and received by a user",
+					OperationID: "listShares",
+					Parameters:  []*Parameter{userIDParam()},
+					Responses: responses(
+						http.StatusOK, result("Shares", object([]string{"given", "received"}, map[string]*Schema{
+							"given":    arrayOf(ref("Share")),
+							"received": arrayOf(ref("Share")),
+						})),
+						http.StatusBadRequest, failure("Invalid query"),
+					),
+				},
+			},
+			"/revoke_share": {
+				Post: &Operation{
+					Summary:     "Revoke access of a user to the events of the owner",
+					OperationID: "revokeShare",
+					RequestBody: jsonBody(ref("ShareRevoke"), map[string]any{
+						"owner_id": 1, "grantee_id": 2,
+					}),
+					Responses: responses(
+						http.StatusOK, result("Share revoked", nil),
+						http.StatusBadRequest, failure("Invalid body"),
+						http.StatusNotFound, failure("Share is not found"),
+						http.StatusUnsupportedMediaType, failure("Body is not JSON"),
+					),
+				},
+			},
+
+			"/create_webhook": {
+				Post: &Operation{
+					Summary:     "Subscribe a URL to event changes of a user",
+					OperationID: "createWebhook",
+					RequestBody: jsonBody(ref("WebhookCreate"), map[string]any{
+						"url": "http://localhost:9000/hook", "events": []string{"event.created"}, "user_id": 1,
+					}),
+					Responses: responses(
+						http.StatusCreated, result("Webhook created, the secret is shown only once", ref("Webhook")),
+						http.StatusBadRequest, failure("Invalid body"),
+						http.StatusUnsupportedMediaType, failure("Body is not JSON"),
+					),
+				},
+			},
+			"/webhooks": {
+				Get: &Operation{
+					Summary:     "List webhooks of a user",
+					OperationID: "listWebhooks",
+					Parameters:  []*Parameter{userIDParam()},
+					Responses: responses(
+						http.StatusOK, result("Webhooks", arrayOf(ref("Webhook"))),
+						http.StatusBadRequest, failure("Invalid query"),
+					),
+				},
+			},
+			"/delete_webhook": {
+				Post: &Operation{
+					Summary:     "Delete a webhook",
+					OperationID: "deleteWebhook",
+					RequestBody: jsonBody(ref("WebhookDelete"), map[string]any{"id": 1, "user_id": 1}),
+					Responses: responses(
+						http.StatusOK, result("Webhook deleted", nil),
+						http.StatusBadRequest, failure("Invalid body"),
+						http.StatusForbidden, failure("The user is not the owner of the webhook"),
+						http.StatusNotFound, failure("Webhook is not found"),
+						http.StatusUnsupportedMediaType, failure("Body is not JSON"),
+					),
+				},
+			},
+			"/webhook_deliveries": {
+				Get: &Operation{
+					Summary:     "Delivery log of the webhooks of a user",
+					OperationID: "listWebhookDeliveries",
+					Parameters:  []*Parameter{userIDParam()},
+					Responses: responses(
+						http.StatusOK, result("Deliveries", arrayOf(ref("Delivery"))),
+						http.StatusBadRequest, failure("Invalid query"),
+					),
+				},
+			},
+			"/webhook_dead_letters": {
+				Get: &Operation{
+					Summary:     "Deliveries that ran out of attempts",
+					OperationID: "listWebhookDeadLetters",
+					Parameters:  []*Parameter{userIDParam()},
+					Responses: responses(
+						http.StatusOK, result("Dead deliveries", arrayOf(ref("Delivery"))),
+						http.StatusBadRequest, failure("Invalid query"),
+					),
+				},
+			},
+
+			"/set_schedule": {
+				Post: &Operation{
+					Summary:     "Set working hours, time zone and holiday calendars of a user",
+					OperationID: "setSchedule",
+					RequestBody: jsonBody(ref("Schedule"), map[string]any{
+						"user_id":   1,
+						"time_zone": "Europe/Moscow",
+						"working_hours": map[string]any{
+							"monday":    []map[string]any{{"start": "09:00", "end": "13:00"}, {"start": "14:00", "end": "18:00"}},
+							"wednesday": []map[string]any{{"start": "10:00", "end": "16:00"}},
+						},
+						"outside_hours": "warn",
+					}),
+					Responses: responses(
+						http.StatusOK, result("Saved schedule", ref("Schedule")),
+						http.StatusBadRequest, failure("Invalid body"),
+						http.StatusUnsupportedMediaType, failure("Body is not JSON"),
+					),
+				},
+			},
+			"/schedule": {
+				Get: &Operation{
+					Summary:     "Schedule of a user, the default one when it was never set",
+					OperationID: "getSchedule",
+					Parameters:  []*Parameter{userIDParam()},
+					Responses: responses(
+						http.StatusOK, result("Schedule", ref("Schedule")),
+						http.StatusBadRequest, failure("Invalid query"),
+					),
+				},
+			},
+			"/availability": {
+				Get: &Operation{
+					Summary:     "Working hours, holidays and events of a user for every day from from to to",
+					OperationID: "getAvailability",
+					Parameters: []*Parameter{
+						userIDParam(),
+						{Name: "from", In: "query", Required: true, Schema: dateInput(), Example: "2024-01-15"},
+						{Name: "to", In: "query", Required: true, Schema: dateInput(), Example: "2024-01-21"},
+					},
+					Responses: responses(
+						http.StatusOK, result("Days", arrayOf(ref("DayAvailability"))),
+						http.StatusBadRequest, failure("Invalid query"),
+					),
+				},
+			},
+
+			"/agenda": {
+				Get: &Operation{
+					Summary:     "Digest of the events a user sees tomorrow or in the 7 days from tomorrow, in the user's time zone",
+					OperationID: "getAgenda",
+					Parameters: []*Parameter{
+						userIDParam(),
+						{Name: "range", In: "query", Schema: &Schema{
+							Type:        "string",
+							Enum:        []any{"day", "week"},
+							Description: "Defaults to day",
+						}, Example: "week"},
+						{Name: "format", In: "query", Schema: &Schema{
+							Type:        "string",
+							Enum:        []any{"text", "md", "html"},
+							Description: "Defaults to text",
+						}, Example: "md"},
+					},
+					Responses: responses(
+						http.StatusOK, &Response{
+							Description: "Rendered digest",
+							Content: map[string]*MediaType{
+								"text/plain":    {Schema: &Schema{Type: "string"}},
+								"text/markdown": {Schema: &Schema{Type: "string"}},
+								"text/html":     {Schema: &Schema{Type: "string"}},
+							},
+						},
+						http.StatusBadRequest, failure("Invalid query"),
+					),
+				},
+			},
+
+			"/admin/create_tenant": {
+				Post: &Operation{
+					Summary:     "Create a tenant with its own empty storage",
+					OperationID: "createTenant",
+					RequestBody: jsonBody(ref("Tenant"), map[string]any{
+						"id": "acme", "name": "Acme", "hosts": []string{"acme.localhost"},
+					}),
+					Responses: responses(
+						http.StatusCreated, result("Tenant created", ref("Tenant")),
+						http.StatusBadRequest, failure("Invalid body"),
+						http.StatusConflict, failure("Tenant with this id or host already exists"),
+						http.StatusUnsupportedMediaType, failure("Body is not JSON"),
+					),
+				},
+			},
+			"/admin/tenants": {
+				Get: &Operation{
+					Summary:     "List tenants",
+					OperationID: "listTenants",
+					Responses:   responses(http.StatusOK, result("Tenants", arrayOf(ref("Tenant")))),
+				},
+			},
+			"/admin/tenant_usage": {
+				Get: &Operation{
+					Summary:     "Count what a tenant stores",
+					OperationID: "getTenantUsage",
+					Parameters: []*Parameter{
+						{Name: "tenant_id", In: "query", Required: true, Schema: &Schema{Type: "string"}, Example: "default"},
+					},
+					Responses: responses(
+						http.StatusOK, result("Usage", ref("TenantUsage")),
+						http.StatusBadRequest, failure("Invalid query"),
+						http.StatusNotFound, failure("Tenant is not found"),
+					),
+				},
+			},
+		},
+		Components: Components{
+			Schemas: map[string]*Schema{
+				"Error": object([]string{"error"}, map[string]*Schema{
+					"error":  {Type: "string"},
+					"fields": arrayOf(ref("FieldError")),
+				}),
+				"FieldError": object([]string{"field", "code", "message"}, map[string]*Schema{
+					"field": {Type: "string", Description: "Name of the failing field, empty for the whole body"},
+					"code": {Type: "string", Enum: []any{
+						model.CodeRequired, model.CodeInvalidJSON, model.CodeInvalidType, model.CodeInvalidDate,
+						model.CodeInvalidURL, model.CodeNotPositive, model.CodeTooSmall, model.CodeTooShort,
+						model.CodeTooLong, model.CodeUnknownValue, model.CodeUnknownField,
+						model.CodeInvalidTime, model.CodeOutsideHours, model.CodeInvalidCSV, model.CodeInvalidSlug,
+					}},
+					"message": {Type: "string"},
+					"line":    {Type: "integer", Description: "Line of the failing row of an imported file"},
+				}),
+				"BuildInfo": object([]string{"path", "version", "go_version", "modified"}, map[string]*Schema{
+					"path":       {Type: "string", Description: "Main module path"},
+					"version":    {Type: "string", Description: "Main module version, (devel) for local builds"},
+					"go_version": {Type: "string"},
+					"revision":   {Type: "string", Description: "VCS revision the binary was built from"},
+					"time":       {Type: "string", Description: "VCS commit time"},
+					"modified":   {Type: "boolean", Description: "Whether the working tree had local changes"},
+				}),
+				"WithID": object([]string{"id"}, map[string]*Schema{
+					"id": positive(),
+					"warnings": {
+						Type:        "array",
+						Items:       &Schema{Type: "string"},
+						Description: "Why the created event may be unwanted, like a date outside working hours",
+					},
+				}),
+				"Event": object([]string{"id", "name", "date", "user_id"}, map[string]*Schema{
+					"id":          {Type: "integer"},
+					"name":        {Type: "string"},
+					"date":        {Type: "string", Description: "YYYY-MM-DD unless date_format asks for another layout"},
+					"user_id":     {Type: "integer"},
+					"calendar_id": {Type: "integer", Description: "Omitted for the default calendar"},
+				}),
+				"EventCreate": object([]string{"name", "date", "user_id"}, map[string]*Schema{
+					"name":        {Type: "string", MinLength: intPtr(1), MaxLength: intPtr(model.MaxNameLength)},
+					"date":        dateInput(),
+					"user_id":     positive(),
+					"calendar_id": calendarID(),
+				}),
+				"EventUpdate": object([]string{"id", "user_id"}, map[string]*Schema{
+					"id":          positive(),
+					"name":        {Type: "string", MaxLength: intPtr(model.MaxNameLength)},
+					"date":        dateInput(),
+					"calendar_id": movedCalendarID(),
+					"user_id":     actor(),
+				}),
+				"EventDelete": object([]string{"id", "user_id"}, map[string]*Schema{
+					"id":      positive(),
+					"user_id": actor(),
+				}),
+				"ImportError": object([]string{"error", "line", "imported"}, map[string]*Schema{
+					"error":    {Type: "string"},
+					"fields":   arrayOf(ref("FieldError")),
+					"line":     {Type: "integer", Description: "Line of the row the import stopped at"},
+					"imported": {Type: "integer", Description: "Events created from the rows before it"},
+				}),
+				"Import": object([]string{"imported", "dry_run"}, map[string]*Schema{
+					"imported": {Type: "integer", Description: "Events created, or that would be created on a dry run"},
+					"dry_run":  {Type: "boolean"},
+					"warnings": {
+						Type:        "array",
+						Items:       &Schema{Type: "string"},
+						Description: "Rows dated outside working hours of users whose schedule warns about them",
+					},
+				}),
+				"Tenant": object([]string{"id", "name"}, map[string]*Schema{
+					"id":         {Type: "string", Description: "Lowercase letters, digits and dashes, sent in the X-Tenant-ID header"},
+					"name":       {Type: "string", MinLength: intPtr(1), MaxLength: intPtr(model.MaxNameLength)},
+					"hosts":      {Type: "array", Items: &Schema{Type: "string"}, Description: "Host names resolved to the tenant without the header"},
+					"created_at": {Type: "string", Format: "date-time"},
+				}),
+				"TenantUsage": object([]string{"tenant_id", "events", "calendars", "shares", "webhooks"}, map[string]*Schema{
+					"tenant_id": {Type: "string"},
+					"events":    {Type: "integer"},
+					"calendars": {Type: "integer"},
+					"shares":    {Type: "integer"},
+					"webhooks":  {Type: "integer"},
+				}),
+				"Share": object([]string{"owner_id", "grantee_id", "permission"}, map[string]*Schema{
+					"owner_id":   positive(),
+					"grantee_id": positive(),
+					"permission": {Type: "string", Enum: []any{"read", "write"}},
+				}),
+				"ShareRevoke": object([]string{"owner_id", "grantee_id"}, map[string]*Schema{
+					"owner_id":   positive(),
+					"grantee_id": positive(),
+				}),
+				"Calendar": object([]string{"id", "name", "visible", "user_id"}, map[string]*Schema{
+					"id":      {Type: "integer"},
+					"name":    {Type: "string"},
+					"visible": {Type: "boolean"},
+					"user_id": {Type: "integer"},
+				}),
+				"CalendarCreate": object([]string{"name", "user_id"}, map[string]*Schema{
+					"name":    {Type: "string", MinLength: intPtr(1), MaxLength: intPtr(model.MaxNameLength)},
+					"visible": {Type: "boolean", Description: "Defaults to true"},
+					"user_id": positive(),
+				}),
+				"CalendarUpdate": object([]string{"id", "user_id"}, map[string]*Schema{
+					"id":      positive(),
+					"name":    {Type: "string", MaxLength: intPtr(model.MaxNameLength)},
+					"visible": {Type: "boolean"},
+					"user_id": owner("calendar"),
+				}),
+				"CalendarDelete": object([]string{"id", "user_id"}, map[string]*Schema{
+					"id":      positive(),
+					"events":  {Type: "string", Enum: []any{"move", "delete"}, Description: "Defaults to move"},
+					"move_to": calendarID(),
+					"user_id": owner("calendar"),
+				}),
+				"EventType": {
+					Type: "string",
+					Enum: []any{"event.created", "event.updated", "event.deleted"},
+				},
+				"Webhook": object([]string{"id", "url", "events", "user_id"}, map[string]*Schema{
+					"id":      {Type: "integer"},
+					"url":     {Type: "string", Format: "uri"},
+					"events":  arrayOf(ref("EventType")),
+					"secret":  {Type: "string"},
+					"user_id": {Type: "integer"},
+				}),
+				"WebhookCreate": object([]string{"url", "events", "user_id"}, map[string]*Schema{
+					"url":     {Type: "string", Format: "uri"},
+					"events":  arrayOf(ref("EventType")),
+					"secret":  {Type: "string"},
+					"user_id": positive(),
+				}),
+				"WebhookDelete": object([]string{"id", "user_id"}, map[string]*Schema{
+					"id":      positive(),
+					"user_id": owner("webhook"),
+				}),
+				"Delivery": object([]string{"id", "webhook_id", "event", "status", "attempts", "created_at", "user_id"}, map[string]*Schema{
+					"id":         {Type: "integer"},
+					"webhook_id": {Type: "integer"},
+					"event":      ref("EventType"),
+					"status":     {Type: "string", Enum: []any{"pending", "succeeded", "dead"}},
+					"attempts": arrayOf(object([]string{"at"}, map[string]*Schema{
+						"at":          {Type: "string", Format: "date-time"},
+						"status_code": {Type: "integer"},
+						"error":       {Type: "string"},
+					})),
+					"created_at": {Type: "string", Format: "date-time"},
+					"payload":    {Description: "Body sent to the webhook"},
+					"user_id":    {Type: "integer"},
+				}),
+				"Interval": object([]string{"start", "end"}, map[string]*Schema{
+					"start": {Type: "string", Description: "HH:MM in the user's time zone"},
+					"end":   {Type: "string", Description: "HH:MM in the user's time zone, exclusive, 24:00 for the end of the day"},
+				}),
+				"Schedule": object([]string{"user_id"}, map[string]*Schema{
+					"user_id":   positive(),
+					"time_zone": {Type: "string", Description: "IANA time zone name, defaults to UTC"},
+					"working_hours": object(nil, map[string]*Schema{
+						"monday":    arrayOf(ref("Interval")),
+						"tuesday":   arrayOf(ref("Interval")),
+						"wednesday": arrayOf(ref("Interval")),
+						"thursday":  arrayOf(ref("Interval")),
+						"friday":    arrayOf(ref("Interval")),
+						"saturday":  arrayOf(ref("Interval")),
+						"sunday":    arrayOf(ref("Interval")),
+					}),
+					"holiday_calendars": {
+						Type:        "array",
+						Items:       &Schema{Type: "string"},
+						Description: "Names of holiday calendars from the server config",
+					},
+					"outside_hours": {
+						Type:        "string",
+						Enum:        []any{"allow", "warn", "reject"},
+						Description: "What happens to events created on days off, defaults to allow",
+					},
+				}),
+				"DayAvailability": object([]string{"date", "working", "hours", "events", "free"}, map[string]*Schema{
+					"date":    {Type: "string", Format: "date"},
+					"working": {Type: "boolean", Description: "The weekday has working hours and is not a holiday"},
+					"holiday": {Type: "string", Description: "Name of the holiday on this day"},
+					"hours": arrayOf(object([]string{"start", "end"}, map[string]*Schema{
+						"start": {Type: "string", Format: "date-time"},
+						"end":   {Type: "string", Format: "date-time"},
+					})),
+					"events": arrayOf(ref("Event")),
+					"free":   {Type: "boolean", Description: "A working day without events"},
+				}),
+			},
+		},
+	}
+})
+
+func listEvents(operationID, summary string, extra ...*Parameter) *PathItem {
+	return &PathItem{
+		Get: &Operation{
+			Summary:     summary,
+			OperationID: operationID,
+			Parameters: append([]*Parameter{
+				userIDParam(),
+				{Name: "date", In: "query", Required: true, Schema: dateInput(), Example: "2024-01-15"},
+				{Name: "shared", In: "query", Schema: &Schema{
+					Type:        "boolean",
+					Description: "Include events shared with the user, defaults to true",
+				}},
+				{Name: "calendars", In: "query", Schema: &Schema{
+					Type:        "string",
+					Description: "Comma separated calendar ids to show, hidden calendars included. 0 is the default calendar",
+				}},
+				{Name: "date_format", In: "query", Schema: &Schema{
+					Type:        "string",
+					Enum:        []any{"date", "rfc3339", "dotted", "week", "ordinal"},
+					Description: "Layout of dates in the response: 2024-01-15 (default), 2024-01-15T00:00:00Z, 15.01.2024, 2024-W03-1 or 2024-015",
+				}},
+				{Name: "If-None-Match", In: "header", Schema: &Schema{
+					Type:        "string",
+					Description: "ETag of a previous response, answered with 304 while the list is the same",
+				}},
+			}, extra...),
+			Responses: responses(
+				http.StatusOK, versioned(result("Events", arrayOf(ref("Event")))),
+				http.StatusNotModified, versioned(&Response{Description: "The list did not change"}),
+				http.StatusBadRequest, failure("Invalid query"),
+			),
+		},
+	}
+}
+
+// versioned adds the validators of a list of events to the response
+func versioned(res *Response) *Response {
+	res.Headers = map[string]*Header{
+		"ETag": {
+			Description: "Changes whenever the events of the user, their calendars or events shared with them change",
+			Schema:      &Schema{Type: "string"},
+		},
+		"Last-Modified": {
+			Description: "Time of the last such change, missing if there was none",
+			Schema:      &Schema{Type: "string"},
+		},
+	}
+
+	return res
+}
+
+// dateInput accepts the strict forms of pkg/date
+func dateInput() *Schema {
+	return &Schema{
+		Type:        "string",
+		Format:      "date",
+		Description: "YYYY-MM-DD, RFC 3339 date-time, ISO week date (2024-W03-1) or ordinal date (2024-015)",
+	}
+}
+
+func transferFormatParam() *Parameter {
+	return &Parameter{Name: "format", In: "query", Schema: &Schema{
+		Type:        "string",
+		Enum:        []any{"csv", "jsonl"},
+		Description: "Defaults to csv",
+	}, Example: "csv"}
+}
+
+func modeParam() *Parameter {
+	return &Parameter{Name: "mode", In: "query", Schema: &Schema{
+		Type:        "string",
+		Enum:        []any{"rolling", "calendar"},
+		Description: "rolling lists 7 days or a month from date, calendar lists the week or month containing date. Defaults to rolling",
+	}}
+}
+
+func weekStartParam() *Parameter {
+	return &Parameter{Name: "week_start", In: "query", Schema: &Schema{
+		Type:        "string",
+		Enum:        []any{"monday", "tuesday", "wednesday", "thursday", "friday", "saturday", "sunday"},
+		Description: "First day of calendar weeks, defaults to monday",
+	}}
+}
+
+func userIDParam() *Parameter {
+	return &Parameter{Name: "user_id", In: "query", Required: true, Schema: positive(), Example: 1}
+}
+
+// responses builds the responses map from status/response pairs and adds
+// the ones every endpoint may answer with
+func responses(pairs ...any) map[string]*Response {
+	res := map[string]*Response{
+		strconv.Itoa(http.StatusNotFound):            failure("Tenant is not found"),
+		strconv.Itoa(http.StatusMethodNotAllowed):    failure("Method is not allowed"),
+		strconv.Itoa(http.StatusInternalServerError): failure("Something went wrong"),
+	}
+
+	for i := 0; i < len(pairs); i += 2 {
+		res[strconv.Itoa(pairs[i].(int))] = pairs[i+1].(*Response)
+	}
+
+	return res
+}
+
+func result(description string, data *Schema) *Response {
+	properties := map[string]*Schema{
+		"message": {Type: "string"},
+	}
+	if data != nil {
+		properties["data"] = data
+	}
+
+	return &Response{
+		Description: description,
+		Content:     jsonContent(object([]string{"message"}, properties)),
+	}
+}
+
+func failure(description string) *Response {
+	return &Response{
+		Description: description,
+		Content:     jsonContent(ref("Error")),
+	}
+}
+
+func importFailure(description string) *Response {
+	return &Response{
+		Description: description,
+		Content:     jsonContent(ref("ImportError")),
+	}
+}
+
+func jsonBody(schema *Schema, example any) *RequestBody {
+	return &RequestBody{
+		Required: true,
+		Content: map[string]*MediaType{
+			JSON: {Schema: schema, Example: example},
+		},
+	}
+}
+
+func jsonContent(schema *Schema) map[string]*MediaType {
+	return map[string]*MediaType{
+		JSON: {Schema: schema},
+	}
+}
+
+func object(required []string, properties map[string]*Schema) *Schema {
+	return &Schema{Type: "object", Required: required, Properties: properties}
+}
+
+func arrayOf(items *Schema) *Schema {
+	return &Schema{Type: "array", Items: items}
+}
+
+func ref(name string) *Schema {
+	return &Schema{Ref: "#/components/schemas/" + name}
+}
+
+// calendarID allows 0, the default calendar of a user
+func calendarID() *Schema {
+	minimum := 0.0
+	return &Schema{Type: "integer", Minimum: &minimum}
+}
+
+func movedCalendarID() *Schema {
+	schema := calendarID()
+	schema.Description = "Calendar to move the event to, 0 is the default one. Omitted keeps the calendar"
+	return schema
+}
+
+func actor() *Schema {
+	schema := positive()
+	schema.Description = "User performing the change. Needs write access to events of other users"
+	return schema
+}
+
+func owner(of string) *Schema {
+	schema := positive()
+	schema.Description = "User performing the change, the owner of the " + of
+	return schema
+}
+
+func positive() *Schema {
+	minimum := 1.0
+	return &Schema{Type: "integer", Minimum: &minimum}
+}
+
+func intPtr(n int) *int {
+	return &n
+}
@@ -0,0 +1,230 @@
+package openapi
+
+import (
+	"fmt"
+	"math"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+	"wb_l2/18/internal/model"
+	"wb_l2/18/pkg/date"
+)
+
+// Violations are reported with the same codes the services use, so clients
+// get identical errors whether a request is rejected here or in a handler
+type Violations []model.FieldError
+
+func (vs Violations) Error() string {
+	messages := make([]string, 0, len(vs))
+	for _, v := range vs {
+		messages = append(messages, v.Message)
+	}
+
+	return strings.Join(messages, "; ")
+}
+
+func violation(field, code, message string) model.FieldError {
+	subject := field
+	if subject == "" {
+		subject = "body"
+	}
+
+	return model.FieldError{Field: field, Code: code, Message: subject + " " + message}
+}
+
+// ValidateQuery checks query parameters of the operation, converting raw
+// strings to the type declared in the parameter schema
+func (d *Document) ValidateQuery(op *Operation, query url.Values) Violations {
+	var violations Violations
+
+	for _, param := range op.Parameters {
+		if param.In != "query" {
+			continue
+		}
+
+		if !query.Has(param.Name) || query.Get(param.Name) == "" {
+			if param.Required {
+				violations = append(violations, violation(param.Name, model.CodeRequired, "is required"))
+			}
+			continue
+		}
+
+		schema := d.Resolve(param.Schema)
+		raw := query.Get(param.Name)
+
+		var value any = raw
+		switch schema.Type {
+		case "integer", "number":
+			number, err := strconv.ParseFloat(raw, 64)
+			if err != nil {
+				violations = append(violations, violation(param.Name, model.CodeInvalidType, "must be "+article(schema.Type)))
+				continue
+			}
+			value = number
+		case "boolean":
+			flag, err := strconv.ParseBool(raw)
+			if err != nil {
+				violations = append(violations, violation(param.Name, model.CodeInvalidType, "must be a boolean"))
+				continue
+			}
+			value = flag
+		}
+
+		violations = append(violations, d.validate(param.Name, schema, value)...)
+	}
+
+	return violations
+}
+
+// Validate checks a value decoded by encoding/json against the schema
+func (d *Document) Validate(schema *Schema, value any) Violations {
+	return d.validate("", schema, value)
+}
+
+func (d *Document) validate(field string, schema *Schema, value any) Violations {
+	schema = d.Resolve(schema)
+	if schema == nil {
+		return nil
+	}
+
+	if value == nil {
+		if schema.Nullable || schema.Type == "" {
+			return nil
+		}
+		return Violations{violation(field, model.CodeInvalidType, "must be "+article(schema.Type))}
+	}
+
+	var violations Violations
+	fail := func(code, format string, args ...any) {
+		violations = append(violations, violation(field, code, fmt.Sprintf(format, args...)))
+	}
+
+	switch schema.Type {
+	case "object":
+		object, ok := value.(map[string]any)
+		if !ok {
+			fail(model.CodeInvalidType, "must be an object")
+			return violations
+		}
+
+		for _, name := range schema.Required {
+			if _, ok := object[name]; !ok {
+				violations = append(violations, violation(join(field, name), model.CodeRequired, "is required"))
+			}
+		}
+
+		for _, name := range sortedKeys(object) {
+			property, ok := schema.Properties[name]
+			if !ok {
+				if schema.AdditionalProperties != nil && !*schema.AdditionalProperties {
+					violations = append(violations, violation(join(field, name), model.CodeUnknownField, "is not allowed"))
+				}
+				continue
+			}
+
+			violations = append(violations, d.validate(join(field, name), property, object[name])...)
+		}
+	case "array":
+		array, ok := value.([]any)
+		if !ok {
+			fail(model.CodeInvalidType, "must be an array")
+			return violations
+		}
+
+		for i, item := range array {
+			violations = append(violations, d.validate(fmt.Sprintf("%s[%d]", field, i), schema.Items, item)...)
+		}
+	case "string":
+		str, ok := value.(string)
+		if !ok {
+			fail(model.CodeInvalidType, "must be a string")
+			return violations
+		}
+
+		length := utf8.RuneCountInString(str)
+		switch {
+		case schema.MinLength != nil && *schema.MinLength == 1 && length == 0:
+			fail(model.CodeRequired, "must not be empty")
+		case schema.MinLength != nil && length < *schema.MinLength:
+			fail(model.CodeTooShort, "must be at least %d characters long", *schema.MinLength)
+		case schema.MaxLength != nil && length > *schema.MaxLength:
+			fail(model.CodeTooLong, "must be at most %d characters long", *schema.MaxLength)
+		}
+
+		switch schema.Format {
+		case "date":
+			if _, err := date.TimeFromString(str); err != nil {
+				fail(model.CodeInvalidDate, "must be an ISO 8601 date like 2024-01-15, 2024-W03-1 or 2024-015")
+			}
+		case "uri":
+			if target, err := url.Parse(str); err != nil || !target.IsAbs() {
+				fail(model.CodeInvalidURL, "must be an absolute URL")
+			}
+		}
+	case "integer", "number":
+		number, ok := value.(float64)
+		if !ok {
+			fail(model.CodeInvalidType, "must be %s", article(schema.Type))
+			return violations
+		}
+
+		if schema.Type == "integer" && number != math.Trunc(number) {
+			fail(model.CodeInvalidType, "must be an integer")
+		}
+		switch {
+		case schema.Minimum != nil && *schema.Minimum == 1 && number < 1:
+			fail(model.CodeNotPositive, "must be a positive integer")
+		case schema.Minimum != nil && number < *schema.Minimum:
+			fail(model.CodeTooSmall, "must be at least %v", *schema.Minimum)
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			fail(model.CodeInvalidType, "must be a boolean")
+		}
+	}
+
+	if len(schema.Enum) > 0 && !enumContains(schema.Enum, value) {
+		fail(model.CodeUnknownValue, "must be one of %v", schema.Enum)
+	}
+
+	return violations
+}
+
+// enumContains compares scalars only, objects and arrays are not comparable
+func enumContains(enum []any, value any) bool {
+	switch value.(type) {
+	case map[string]any, []any:
+		return false
+	}
+
+	return slices.Contains(enum, value)
+}
+
+func join(field, name string) string {
+	if field == "" {
+		return name
+	}
+
+	return field + "." + name
+}
+
+func article(typ string) string {
+	switch typ {
+	case "object", "array", "integer":
+		return "an " + typ
+	default:
+		return "a " + typ
+	}
+}
+
+func sortedKeys(object map[string]any) []string {
+	keys := make([]string, 0, len(object))
+	for key := range object {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+
+	return keys
+}
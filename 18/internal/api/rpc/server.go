@@ -0,0 +1,335 @@
+// Package rpc serves the event API over gRPC, see proto/calendar.proto. It
+// goes through the same services as the HTTP handlers
+package rpc
+
+//go:generate protoc -I ../../../proto --go_out=. --go_opt=module=wb_l2/18/internal/api/rpc --go-grpc_out=. --go-grpc_opt=module=wb_l2/18/internal/api/rpc calendar.proto
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"wb_l2/18/internal/api/rpc/calendarpb"
+	"wb_l2/18/internal/model"
+	"wb_l2/18/internal/repository/inmemory/event"
+	"wb_l2/18/internal/repository/inmemory/tenant"
+	"wb_l2/18/internal/service"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+type Server struct {
+	calendarpb.UnimplementedEventsServer
+
+	service *service.Service
+	grpc    *grpc.Server
+
+	// header is the metadata key naming the tenant of a call
+	header string
+
+	// draining is cancelled on Stop to end watch streams, which would keep a
+	// graceful stop waiting forever
+	draining context.Context
+	drain    context.CancelFunc
+}
+
+func NewServer(root *service.Service, tenantHeader string) *Server {
+	draining, drain := context.WithCancel(context.Background())
+
+	s := &Server{
+		service:  root,
+		header:   strings.ToLower(tenantHeader),
+		grpc:     grpc.NewServer(),
+		draining: draining,
+		drain:    drain,
+	}
+	calendarpb.RegisterEventsServer(s.grpc, s)
+
+	return s
+}
+
+// Serve accepts connections until Stop
+func (s *Server) Serve(listener net.Listener) error {
+	return s.grpc.Serve(listener)
+}
+
+// Stop ends watch streams and waits for unary calls in flight until ctx is
+// done, then closes the remaining connections
+func (s *Server) Stop(ctx context.Context) {
+	s.drain()
+
+	stopped := make(chan struct{})
+	go func() {
+		s.grpc.GracefulStop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		s.grpc.Stop()
+	}
+}
+
+// Requests are handed to the services as the JSON bodies and queries of the
+// HTTP API, so both APIs validate them the same way
+
+// CreateEvent takes the idempotency key of the HTTP API from the
+// idempotency-key metadata
+func (s *Server) CreateEvent(ctx context.Context, req *calendarpb.CreateEventRequest) (*calendarpb.CreateEventResponse, error) {
+	services, err := s.tenant(ctx)
+	if err != nil {
+		return nil, statusError(err)
+	}
+
+	id, warnings, err := services.Event.Create(body(model.EventOut{
+		Name:       req.Name,
+		Date:       req.Date,
+		UserID:     int(req.UserId),
+		CalendarID: int(req.CalendarId),
+	}), firstValue(ctx, "idempotency-key"))
+	if err != nil {
+		return nil, statusError(err)
+	}
+
+	return &calendarpb.CreateEventResponse{Id: int64(id), Warnings: warnings}, nil
+}
+
+var periods = map[calendarpb.Period]service.ListFor{
+	calendarpb.Period_PERIOD_UNSPECIFIED: service.Day,
+	calendarpb.Period_PERIOD_DAY:         service.Day,
+	calendarpb.Period_PERIOD_WEEK:        service.Week,
+	calendarpb.Period_PERIOD_MONTH:       service.Month,
+}
+
+func (s *Server) ListEvents(ctx context.Context, req *calendarpb.ListEventsRequest) (*calendarpb.ListEventsResponse, error) {
+	by, ok := periods[req.Period]
+	if !ok {
+		return nil, status.Errorf(codes.InvalidArgument, "unknown period %d", req.Period)
+	}
+
+	query := url.Values{
+		"user_id": {strconv.FormatInt(req.UserId, 10)},
+		"date":    {req.Date},
+	}
+	if len(req.Calendars) > 0 {
+		ids := make([]string, 0, len(req.Calendars))
+		for _, id := range req.Calendars {
+			ids = append(ids, strconv.FormatInt(id, 10))
+		}
+		query.Set("calendars", strings.Join(ids, ","))
+	}
+	if req.Shared != nil {
+		query.Set("shared", strconv.FormatBool(*req.Shared))
+	}
+	for name, value := range map[string]string{"mode": req.Mode, "week_start": req.WeekStart, "date_format": req.DateFormat} {
+		if value != "" {
+			query.Set(name, value)
+		}
+	}
+
+	services, err := s.tenant(ctx)
+	if err != nil {
+		return nil, statusError(err)
+	}
+
+	events, err := services.Event.List(query, by)
+	if err != nil {
+		return nil, statusError(err)
+	}
+
+	res := &calendarpb.ListEventsResponse{Events: make([]*calendarpb.Event, 0, len(events))}
+	for _, event := range events {
+		res.Events = append(res.Events, eventOut(event))
+	}
+
+	return res, nil
+}
+
+func (s *Server) UpdateEvent(ctx context.Context, req *calendarpb.UpdateEventRequest) (*calendarpb.UpdateEventResponse, error) {
+	services, err := s.tenant(ctx)
+	if err != nil {
+		return nil, statusError(err)
+	}
+
+	event, err := services.Event.Update(body(eventUpdate{
+		ID:         req.Id,
+		Name:       req.Name,
+		Date:       req.Date,
+		UserID:     req.ActorId,
+		CalendarID: req.CalendarId,
+	}))
+	if err != nil {
+		return nil, statusError(err)
+	}
+
+	return &calendarpb.UpdateEventResponse{Event: eventOut(event)}, nil
+}
+
+func (s *Server) DeleteEvent(ctx context.Context, req *calendarpb.DeleteEventRequest) (*calendarpb.DeleteEventResponse, error) {
+	services, err := s.tenant(ctx)
+	if err != nil {
+		return nil, statusError(err)
+	}
+
+	err = services.Event.Delete(body(map[string]int64{"id": req.Id, "user_id": req.ActorId}))
+	if err != nil {
+		return nil, statusError(err)
+	}
+
+	return &calendarpb.DeleteEventResponse{}, nil
+}
+
+func (s *Server) WatchEvents(req *calendarpb.WatchEventsRequest, stream grpc.ServerStreamingServer[calendarpb.EventChange]) error {
+	if req.UserId <= 0 {
+		return status.Error(codes.InvalidArgument, "user_id must be a positive integer")
+	}
+	if req.SinceVersion < 0 {
+		return status.Error(codes.InvalidArgument, "since_version must not be negative")
+	}
+
+	services, err := s.tenant(stream.Context())
+	if err != nil {
+		return statusError(err)
+	}
+
+	ctx, cancel := context.WithCancel(stream.Context())
+	defer cancel()
+	stopDraining := context.AfterFunc(s.draining, cancel)
+	defer stopDraining()
+
+	err = services.Watch.Watch(ctx, int(req.UserId), int(req.SinceVersion), func(changes *model.EventChanges) error {
+		for _, changed := range changes.Changed {
+			err := stream.Send(&calendarpb.EventChange{
+				Type:    calendarpb.EventChange_TYPE_CHANGED,
+				Event:   eventOut(changed.FormatDate()),
+				EventId: int64(changed.ID),
+				Version: int64(changes.Version),
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		for _, deleted := range changes.Deleted {
+			err := stream.Send(&calendarpb.EventChange{
+				Type:    calendarpb.EventChange_TYPE_DELETED,
+				EventId: int64(deleted.ID),
+				Version: int64(changes.Version),
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+
+	switch {
+	case s.draining.Err() != nil:
+		return status.Error(codes.Unavailable, "Server is shutting down")
+	case stream.Context().Err() != nil:
+		return status.FromContextError(stream.Context().Err()).Err()
+	default:
+		return statusError(err)
+	}
+}
+
+// tenant resolves the services of a call like the HTTP API does, by the tenant
+// metadata or the :authority host
+func (s *Server) tenant(ctx context.Context) (*service.Service, error) {
+	host, _, err := net.SplitHostPort(firstValue(ctx, ":authority"))
+	if err != nil {
+		host = firstValue(ctx, ":authority")
+	}
+
+	return s.service.Tenants.Resolve(firstValue(ctx, s.header), strings.ToLower(host))
+}
+
+func firstValue(ctx context.Context, key string) string {
+	if values := metadata.ValueFromIncomingContext(ctx, key); len(values) > 0 {
+		return values[0]
+	}
+
+	return ""
+}
+
+func eventOut(event *model.EventOut) *calendarpb.Event {
+	return &calendarpb.Event{
+		Id:         int64(event.ID),
+		Name:       event.Name,
+		Date:       event.Date,
+		UserId:     int64(event.UserID),
+		CalendarId: int64(event.CalendarID),
+	}
+}
+
+// eventUpdate is the update_event body, calendar_id is left out unless set so
+// that 0 moves the event to the default calendar
+type eventUpdate struct {
+	ID         int64  `json:"id"`
+	Name       string `json:"name"`
+	Date       string `json:"date"`
+	UserID     int64  `json:"user_id"`
+	CalendarID *int64 `json:"calendar_id,omitempty"`
+}
+
+func body(v any) []byte {
+	data, err := json.Marshal(v)
+	if err != nil {
+		panic(err)
+	}
+
+	return data
+}
+
+// statusError maps service errors like the HTTP handlers map them to status
+// codes, failing fields go to a BadRequest detail
+func statusError(err error) error {
+	var code codes.Code
+	switch {
+	case errors.Is(err, model.InvalidFormat), errors.Is(err, service.InvalidQuery):
+		code = codes.InvalidArgument
+	case errors.Is(err, model.OutsideWorkingHours), errors.Is(err, model.IdempotencyKeyReused):
+		code = codes.FailedPrecondition
+	case errors.Is(err, model.IdempotencyKeyInProgress):
+		code = codes.Aborted
+	case errors.Is(err, event.ErrorEventNotFound), errors.Is(err, tenant.ErrorTenantNotFound):
+		code = codes.NotFound
+	case errors.Is(err, service.Forbidden):
+		code = codes.PermissionDenied
+	default:
+		slog.Error(err.Error())
+		return status.Error(codes.Internal, "Something went wrong, try again later")
+	}
+
+	st := status.New(code, err.Error())
+
+	var verr *model.ValidationError
+	if !errors.As(err, &verr) {
+		return st.Err()
+	}
+
+	details := &errdetails.BadRequest{}
+	for _, field := range verr.Fields {
+		details.FieldViolations = append(details.FieldViolations, &errdetails.BadRequest_FieldViolation{
+			Field:       field.Field,
+			Description: field.Message,
+			Reason:      field.Code,
+		})
+	}
+
+	if withDetails, err := st.WithDetails(details); err == nil {
+		st = withDetails
+	}
+
+	return st.Err()
+}
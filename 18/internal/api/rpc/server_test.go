@@ -0,0 +1,178 @@
+package rpc
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+	"wb_l2/18/internal/api/rpc/calendarpb"
+	"wb_l2/18/internal/config"
+	"wb_l2/18/internal/repository"
+	"wb_l2/18/internal/service"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func setupTestClient(t *testing.T) (calendarpb.EventsClient, *Server) {
+	repo := repository.NewRepository(repository.InMemory)
+	server := NewServer(service.NewService(repo, config.Default()), config.Default().Tenancy.Header)
+
+	listener := bufconn.Listen(1 << 20)
+	go server.Serve(listener)
+	t.Cleanup(func() { server.Stop(context.Background()) })
+
+	conn, err := grpc.NewClient("passthrough:///bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return listener.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("Failed to dial: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	return calendarpb.NewEventsClient(conn), server
+}
+
+func TestEvents_CRUD(t *testing.T) {
+	client, _ := setupTestClient(t)
+	ctx := t.Context()
+
+	created, err := client.CreateEvent(ctx, &calendarpb.CreateEventRequest{Name: "Standup", Date: "2024-01-15", UserId: 1})
+	if err != nil {
+		t.Fatalf("Failed to create event: %v", err)
+	}
+
+	updated, err := client.UpdateEvent(ctx, &calendarpb.UpdateEventRequest{Id: created.Id, Name: "Retro", Date: "2024-01-16", ActorId: 1})
+	if err != nil {
+		t.Fatalf("Failed to update event: %v", err)
+	}
+	if updated.Event.Name != "Retro" || updated.Event.Date != "2024-01-16" {
+		t.Errorf("Unexpected updated event: %v", updated.Event)
+	}
+
+	listed, err := client.ListEvents(ctx, &calendarpb.ListEventsRequest{UserId: 1, Date: "2024-01-15", Period: calendarpb.Period_PERIOD_WEEK})
+	if err != nil {
+		t.Fatalf("Failed to list events: %v", err)
+	}
+	if len(listed.Events) != 1 || listed.Events[0].Id != created.Id {
+		t.Errorf("Expected the event in the week, got %v", listed.Events)
+	}
+
+	if _, err := client.DeleteEvent(ctx, &calendarpb.DeleteEventRequest{Id: created.Id, ActorId: 1}); err != nil {
+		t.Fatalf("Failed to delete event: %v", err)
+	}
+
+	_, err = client.DeleteEvent(ctx, &calendarpb.DeleteEventRequest{Id: created.Id, ActorId: 1})
+	if status.Code(err) != codes.NotFound {
+		t.Errorf("Expected NotFound for a deleted event, got %v", err)
+	}
+}
+
+func TestEvents_UpdateCalendar(t *testing.T) {
+	client, server := setupTestClient(t)
+	ctx := t.Context()
+
+	calendar, err := server.service.Calendar.Create([]byte(`{"name": "Work", "user_id": 1}`))
+	if err != nil {
+		t.Fatalf("Failed to create calendar: %v", err)
+	}
+
+	created, err := client.CreateEvent(ctx, &calendarpb.CreateEventRequest{Name: "Standup", Date: "2024-01-15", UserId: 1, CalendarId: int64(calendar.ID)})
+	if err != nil {
+		t.Fatalf("Failed to create event: %v", err)
+	}
+
+	// calendar_id that is not set keeps the calendar
+	updated, err := client.UpdateEvent(ctx, &calendarpb.UpdateEventRequest{Id: created.Id, Name: "Retro", ActorId: 1})
+	if err != nil {
+		t.Fatalf("Failed to update event: %v", err)
+	}
+	if updated.Event.CalendarId != int64(calendar.ID) {
+		t.Errorf("Expected the event to stay in calendar %d, got %v", calendar.ID, updated.Event)
+	}
+
+	defaultCalendar := int64(0)
+	updated, err = client.UpdateEvent(ctx, &calendarpb.UpdateEventRequest{Id: created.Id, CalendarId: &defaultCalendar, ActorId: 1})
+	if err != nil {
+		t.Fatalf("Failed to update event: %v", err)
+	}
+	if updated.Event.CalendarId != 0 {
+		t.Errorf("Expected the event in the default calendar, got %v", updated.Event)
+	}
+}
+
+func TestEvents_InvalidArgument(t *testing.T) {
+	client, _ := setupTestClient(t)
+
+	_, err := client.CreateEvent(t.Context(), &calendarpb.CreateEventRequest{Date: "15-01-2024", UserId: 1})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("Expected InvalidArgument, got %v", err)
+	}
+
+	violations := map[string]string{}
+	for _, detail := range status.Convert(err).Details() {
+		if badRequest, ok := detail.(*errdetails.BadRequest); ok {
+			for _, violation := range badRequest.FieldViolations {
+				violations[violation.Field] = violation.Reason
+			}
+		}
+	}
+	if _, ok := violations["name"]; !ok {
+		t.Errorf("Expected a violation for name, got %v", violations)
+	}
+	if _, ok := violations["date"]; !ok {
+		t.Errorf("Expected a violation for date, got %v", violations)
+	}
+}
+
+func TestEvents_Watch(t *testing.T) {
+	client, server := setupTestClient(t)
+	ctx := t.Context()
+
+	first, err := client.CreateEvent(ctx, &calendarpb.CreateEventRequest{Name: "Standup", Date: "2024-01-15", UserId: 1})
+	if err != nil {
+		t.Fatalf("Failed to create event: %v", err)
+	}
+
+	stream, err := client.WatchEvents(ctx, &calendarpb.WatchEventsRequest{UserId: 1})
+	if err != nil {
+		t.Fatalf("Failed to watch events: %v", err)
+	}
+
+	change, err := stream.Recv()
+	if err != nil {
+		t.Fatalf("Failed to receive a change: %v", err)
+	}
+	if change.Type != calendarpb.EventChange_TYPE_CHANGED || change.EventId != first.Id {
+		t.Errorf("Expected the existing event first, got %v", change)
+	}
+
+	// another user's events are not watched
+	if _, err := client.CreateEvent(ctx, &calendarpb.CreateEventRequest{Name: "Other", Date: "2024-01-15", UserId: 2}); err != nil {
+		t.Fatalf("Failed to create event: %v", err)
+	}
+	if _, err := client.DeleteEvent(ctx, &calendarpb.DeleteEventRequest{Id: first.Id, ActorId: 1}); err != nil {
+		t.Fatalf("Failed to delete event: %v", err)
+	}
+
+	change, err = stream.Recv()
+	if err != nil {
+		t.Fatalf("Failed to receive a change: %v", err)
+	}
+	if change.Type != calendarpb.EventChange_TYPE_DELETED || change.EventId != first.Id || change.Version <= 0 {
+		t.Errorf("Expected the deletion, got %v", change)
+	}
+
+	stopCtx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	server.Stop(stopCtx)
+
+	if _, err := stream.Recv(); status.Code(err) != codes.Unavailable {
+		t.Errorf("Expected Unavailable on shutdown, got %v", err)
+	}
+}
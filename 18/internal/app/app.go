@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
@@ -11,43 +12,64 @@ import (
 	"time"
 	"wb_l2/18/internal/api/handler"
 	"wb_l2/18/internal/api/middleware"
+	"wb_l2/18/internal/api/openapi"
+	"wb_l2/18/internal/api/rpc"
 	"wb_l2/18/internal/config"
 	"wb_l2/18/internal/repository"
 	"wb_l2/18/internal/service"
 )
 
 type App struct {
-	server *http.Server
-	config *config.Config
+	server  *http.Server
+	rpc     *rpc.Server
+	service *service.Service
+	config  *config.Config
 }
 
-func NewApp(config *config.Config) *App {
+func NewApp(config *config.Config) (*App, error) {
 	repo := repository.NewRepository(repository.InMemory)
 
-	service := service.NewService(repo)
+	service := service.NewService(repo, config)
+	if err := service.Availability.LoadHolidayCalendars(config.Availability.HolidayCalendars); err != nil {
+		return nil, err
+	}
 
-	h := handler.NewHandler(service)
-	handler.RegisterHandlers(h)
+	router := handler.NewRouter(service, config)
 
 	server := &http.Server{
 		Addr:    ":" + config.Port,
-		Handler: middleware.Chain(h.HTTPHandler(), middleware.Log),
+		Handler: middleware.Chain(router, middleware.Log, middleware.Validate(openapi.Spec())),
 		// TODO: ensure timeouts
 		ReadTimeout:  10 * time.Second,
 		WriteTimeout: 10 * time.Second,
 	}
 
 	return &App{
-		server: server,
-		config: config,
-	}
+		server:  server,
+		rpc:     rpc.NewServer(service, config.Tenancy.Header),
+		service: service,
+		config:  config,
+	}, nil
 }
 
 func (a *App) Run(ctx context.Context) error {
-	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
-	defer cancel()
+	workersCtx, stopWorkers := context.WithCancel(ctx)
+	defer stopWorkers()
+
+	a.service.Tenants.Start(workersCtx)
+
+	listener, err := net.Listen("tcp", ":"+a.config.GRPCPort)
+	if err != nil {
+		return fmt.Errorf("Unable to start gRPC server: %s", err)
+	}
+
+	errorChan := make(chan error, 2)
+	go func() {
+		if err := a.rpc.Serve(listener); err != nil {
+			errorChan <- err
+		}
+	}()
 
-	errorChan := make(chan error, 1)
 	go func() {
 		slog.Info("Starting server...")
 		if err := a.server.ListenAndServe(); err != nil {
@@ -66,16 +88,30 @@ func (a *App) Run(ctx context.Context) error {
 	}
 
 	slog.Info("Listening on http://localhost:" + a.config.Port)
+	slog.Info("Listening for gRPC on localhost:" + a.config.GRPCPort)
 
 	signalChan := make(chan os.Signal, 1)
 	signal.Notify(signalChan, syscall.SIGINT, syscall.SIGTERM)
 	<-signalChan
 
 	fmt.Println()
+	a.service.Tenants.Drain()
+	if a.config.ShutdownDelay > 0 {
+		slog.Info("Draining before shutdown...", "delay", a.config.ShutdownDelay)
+		time.Sleep(a.config.ShutdownDelay)
+	}
+
 	slog.Info("Shutting down the server...")
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
 	if err := a.server.Shutdown(ctx); err != nil {
 		return fmt.Errorf("Unable to shutdown gracefully: %s", err)
 	}
+	a.rpc.Stop(ctx)
+
+	stopWorkers()
+	a.service.Tenants.Wait()
 
 	return nil
 }
@@ -3,12 +3,131 @@ package config
 import (
 	"fmt"
 	"os"
+	"path/filepath"
+	"time"
 
 	"gopkg.in/yaml.v3"
 )
 
 type Config struct {
 	Port string `yaml:"port"`
+	// GRPCPort serves the gRPC API next to the HTTP one
+	GRPCPort string `yaml:"grpc_port"`
+	// ShutdownDelay keeps serving with /readyz failing before the shutdown, so
+	// load balancers have time to notice
+	ShutdownDelay time.Duration     `yaml:"shutdown_delay"`
+	Webhook       WebhookConfig     `yaml:"webhook"`
+	Idempotency   IdempotencyConfig `yaml:"idempotency"`
+	Tenancy       TenancyConfig     `yaml:"tenancy"`
+	ListCache     ListCacheConfig   `yaml:"list_cache"`
+
+	Availability AvailabilityConfig `yaml:"availability"`
+	Agenda       AgendaConfig       `yaml:"agenda"`
+}
+
+type WebhookConfig struct {
+	Workers     int           `yaml:"workers"`
+	QueueSize   int           `yaml:"queue_size"`
+	MaxAttempts int           `yaml:"max_attempts"`
+	Backoff     time.Duration `yaml:"backoff"`
+	Timeout     time.Duration `yaml:"timeout"`
+}
+
+// IdempotencyConfig sets how long the response to a /create_event request
+// with an Idempotency-Key header is given back to retries
+type IdempotencyConfig struct {
+	TTL time.Duration `yaml:"ttl"`
+}
+
+// TenancyConfig resolves requests to tenants: by the header, then by the host
+// and to the default tenant otherwise
+type TenancyConfig struct {
+	Header  string `yaml:"header"`
+	Default string `yaml:"default"`
+}
+
+// ListCacheConfig keeps up to Size serialized responses of /events_for_*
+// per tenant, 0 turns the cache off
+type ListCacheConfig struct {
+	Size int `yaml:"size"`
+}
+
+type AvailabilityConfig struct {
+	// HolidayCalendars maps calendar names users pick in their schedules to
+	// .ics or .yaml files
+	HolidayCalendars map[string]string `yaml:"holiday_calendars"`
+}
+
+// AgendaConfig sets up the digest job. It runs when there are users and
+// either an output directory or an SMTP server to deliver to
+type AgendaConfig struct {
+	Users  []int  `yaml:"users"`
+	Range  string `yaml:"range"`
+	Format string `yaml:"format"`
+	// At is the HH:MM local time of the first run, Interval the time between
+	// runs
+	At       string        `yaml:"at"`
+	Interval time.Duration `yaml:"interval"`
+
+	OutputDir string     `yaml:"output_dir"`
+	SMTP      SMTPConfig `yaml:"smtp"`
+
+	// Tenants lists users of other tenants by tenant id, the other settings
+	// are shared with the default tenant
+	Tenants map[string][]int `yaml:"tenants"`
+}
+
+// ForTenant is the digest job of a tenant other than the default one. Its
+// files go to a subdirectory and mails to a subdomain named by the tenant, so
+// users with the same id in different tenants don't get each other's digests
+func (c AgendaConfig) ForTenant(id string) AgendaConfig {
+	c.Users = c.Tenants[id]
+	c.Tenants = nil
+	if c.OutputDir != "" {
+		c.OutputDir = filepath.Join(c.OutputDir, id)
+	}
+	c.SMTP.Domain = id + "." + c.SMTP.Domain
+
+	return c
+}
+
+// SMTPConfig points to a local SMTP server without authentication, digests
+// go to user<id>@Domain
+type SMTPConfig struct {
+	Addr   string `yaml:"addr"`
+	From   string `yaml:"from"`
+	Domain string `yaml:"domain"`
+}
+
+func Default() *Config {
+	return &Config{
+		Port:     "8080",
+		GRPCPort: "9090",
+		Webhook: WebhookConfig{
+			Workers:     2,
+			QueueSize:   256,
+			MaxAttempts: 5,
+			Backoff:     time.Second,
+			Timeout:     5 * time.Second,
+		},
+		Idempotency: IdempotencyConfig{
+			TTL: 24 * time.Hour,
+		},
+		Tenancy: TenancyConfig{
+			Header:  "X-Tenant-ID",
+			Default: "default",
+		},
+		Agenda: AgendaConfig{
+			Range:    "day",
+			Format:   "md",
+			At:       "07:00",
+			Interval: 24 * time.Hour,
+			SMTP: SMTPConfig{
+				From:   "calendar@localhost",
+				Domain: "localhost",
+			},
+		},
+	}
 }
 
 func ReadConfig() (*Config, error) {
@@ -17,8 +136,8 @@ func ReadConfig() (*Config, error) {
 		return nil, fmt.Errorf("config.yaml does not exist in root path")
 	}
 
-	var config Config
-	if err := yaml.Unmarshal(file, &config); err != nil {
+	config := Default()
+	if err := yaml.Unmarshal(file, config); err != nil {
 		return nil, err
 	}
 
@@ -26,5 +145,5 @@ func ReadConfig() (*Config, error) {
 		config.Port = "8080"
 	}
 
-	return &config, nil
+	return config, nil
 }
@@ -0,0 +1,61 @@
+package model
+
+import "time"
+
+// AgendaRange is the period a digest covers, starting tomorrow in the user's
+// time zone
+type AgendaRange string
+
+const (
+	AgendaDay  AgendaRange = "day"
+	AgendaWeek AgendaRange = "week"
+)
+
+var AgendaRanges = []AgendaRange{AgendaDay, AgendaWeek}
+
+type AgendaFormat string
+
+const (
+	AgendaText     AgendaFormat = "text"
+	AgendaMarkdown AgendaFormat = "md"
+	AgendaHTML     AgendaFormat = "html"
+)
+
+var AgendaFormats = []AgendaFormat{AgendaText, AgendaMarkdown, AgendaHTML}
+
+// ContentType is the media type of a rendered digest
+func (f AgendaFormat) ContentType() string {
+	switch f {
+	case AgendaMarkdown:
+		return "text/markdown; charset=utf-8"
+	case AgendaHTML:
+		return "text/html; charset=utf-8"
+	default:
+		return "text/plain; charset=utf-8"
+	}
+}
+
+// Extension names digest files written by the agenda job
+func (f AgendaFormat) Extension() string {
+	if f == AgendaText {
+		return "txt"
+	}
+
+	return string(f)
+}
+
+// Agenda lists the events a user sees, own and shared, day by day. From and
+// To are both inclusive
+type Agenda struct {
+	UserID int
+	Range  AgendaRange
+	From   time.Time
+	To     time.Time
+	Days   []*DayAgenda
+}
+
+// DayAgenda is a day with at least one event
+type DayAgenda struct {
+	Date   time.Time
+	Events []*EventOut
+}
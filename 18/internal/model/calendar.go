@@ -0,0 +1,160 @@
+package model
+
+type Calendar struct {
+	ID      int
+	Name    string
+	Visible bool
+
+	UserID int
+}
+
+type CalendarOut struct {
+	ID      int    `json:"id"`
+	Name    string `json:"name"`
+	Visible bool   `json:"visible"`
+
+	UserID int `json:"user_id"`
+}
+
+// calendarParse tells an omitted visible flag from false
+type calendarParse struct {
+	ID      int    `json:"id"`
+	Name    string `json:"name"`
+	Visible *bool  `json:"visible"`
+
+	UserID int `json:"user_id"`
+}
+
+func CalendarFromBody(body []byte) (*Calendar, error) {
+	verr := NewValidationError(InvalidFormat)
+
+	var calendarParse calendarParse
+	if !unmarshalBody(body, &calendarParse, verr) {
+		return nil, verr
+	}
+	calendar := &Calendar{Visible: true}
+
+	if !verr.Has("name") {
+		calendar.Name = validateName(calendarParse.Name, true, verr)
+	}
+
+	if !verr.Has("visible") && calendarParse.Visible != nil {
+		calendar.Visible = *calendarParse.Visible
+	}
+
+	if !verr.Has("user_id") {
+		calendar.UserID = validatePositive("user_id", calendarParse.UserID, verr)
+	}
+
+	if err := verr.Err(); err != nil {
+		return nil, err
+	}
+
+	return calendar, nil
+}
+
+// CalendarUpdate holds the fields present in an update request, nil ones are
+// kept as is
+type CalendarUpdate struct {
+	Name    *string
+	Visible *bool
+}
+
+func CalendarUpdateFromBody(body []byte) (int, *CalendarUpdate, error) {
+	verr := NewValidationError(InvalidFormat)
+
+	var calendarParse calendarParse
+	if !unmarshalBody(body, &calendarParse, verr) {
+		return 0, nil, verr
+	}
+	update := new(CalendarUpdate)
+
+	id := 0
+	if !verr.Has("id") {
+		id = validatePositive("id", calendarParse.ID, verr)
+	}
+
+	if !verr.Has("name") && calendarParse.Name != "" {
+		name := validateName(calendarParse.Name, false, verr)
+		update.Name = &name
+	}
+
+	if !verr.Has("visible") {
+		update.Visible = calendarParse.Visible
+	}
+
+	if err := verr.Err(); err != nil {
+		return 0, nil, err
+	}
+
+	return id, update, nil
+}
+
+type CalendarEventsMode string
+
+const (
+	// Events of a deleted calendar are deleted too
+	CalendarEventsDelete CalendarEventsMode = "delete"
+	// Events of a deleted calendar are moved to another one, the default
+	// calendar unless move_to is given
+	CalendarEventsMove CalendarEventsMode = "move"
+)
+
+type CalendarDelete struct {
+	ID     int
+	Events CalendarEventsMode
+	MoveTo int
+}
+
+func CalendarDeleteFromBody(body []byte) (*CalendarDelete, error) {
+	verr := NewValidationError(InvalidFormat)
+
+	var deleteParse struct {
+		ID     int                `json:"id"`
+		Events CalendarEventsMode `json:"events"`
+		MoveTo int                `json:"move_to"`
+	}
+	if !unmarshalBody(body, &deleteParse, verr) {
+		return nil, verr
+	}
+	calendarDelete := &CalendarDelete{Events: CalendarEventsMove}
+
+	if !verr.Has("id") {
+		calendarDelete.ID = validatePositive("id", deleteParse.ID, verr)
+	}
+
+	if !verr.Has("events") {
+		switch deleteParse.Events {
+		case "":
+		case CalendarEventsDelete, CalendarEventsMove:
+			calendarDelete.Events = deleteParse.Events
+		default:
+			verr.Add("events", CodeUnknownValue, `events must be "delete" or "move"`)
+		}
+	}
+
+	if !verr.Has("move_to") {
+		if deleteParse.MoveTo < 0 {
+			verr.Add("move_to", CodeTooSmall, "move_to must not be negative")
+		}
+		if deleteParse.MoveTo != 0 && calendarDelete.Events == CalendarEventsDelete {
+			verr.Add("move_to", CodeUnknownField, `move_to is allowed only with "move" events mode`)
+		}
+		calendarDelete.MoveTo = deleteParse.MoveTo
+	}
+
+	if err := verr.Err(); err != nil {
+		return nil, err
+	}
+
+	return calendarDelete, nil
+}
+
+func (c *Calendar) Format() *CalendarOut {
+	return &CalendarOut{
+		ID:      c.ID,
+		Name:    c.Name,
+		Visible: c.Visible,
+		UserID:  c.UserID,
+	}
+}
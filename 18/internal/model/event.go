@@ -1,9 +1,9 @@
 package model
 
 import (
-	"encoding/json"
 	"fmt"
 	"time"
+	"unicode/utf8"
 	"wb_l2/18/pkg/date"
 )
 
@@ -14,7 +14,24 @@ type Event struct {
 	Name string
 	Date time.Time
 
-	UserID int
+	UserID     int
+	CalendarID int
+
+	// Resource is the name a CalDAV client stored the event under, empty for
+	// events created by other APIs
+	Resource string
+
+	// Version and UpdatedAt are stamped by the repository on every change
+	Version   int
+	UpdatedAt time.Time
+}
+
+// EventChanges is a delta of a user's events since some version, Deleted
+// holds the last state of the deleted events
+type EventChanges struct {
+	Changed []*Event
+	Deleted []*Event
+	Version int
 }
 
 type EventOut struct {
@@ -22,46 +39,181 @@ type EventOut struct {
 	Name string `json:"name"`
 	Date string `json:"date"`
 
-	UserID int `json:"user_id"`
+	UserID     int `json:"user_id"`
+	CalendarID int `json:"calendar_id,omitempty"`
 }
 
 func EventFromBody(body []byte) (*Event, error) {
+	verr := NewValidationError(InvalidFormat)
+
 	var eventParse EventOut
-	if err := json.Unmarshal(body, &eventParse); err != nil {
-		return nil, InvalidFormat
+	if !unmarshalBody(body, &eventParse, verr) {
+		return nil, verr
 	}
 	event := new(Event)
 
-	if eventParse.Name == "" {
-		return nil, InvalidFormat
+	if !verr.Has("name") {
+		event.Name = validateName(eventParse.Name, true, verr)
 	}
-	event.Name = eventParse.Name
 
-	if eventParse.Date == "" {
-		return nil, InvalidFormat
+	if !verr.Has("date") {
+		event.Date = validateDate(eventParse.Date, true, verr)
 	}
 
-	time, err := date.TimeFromString(eventParse.Date)
-	if err != nil {
-		return nil, InvalidFormat
+	if !verr.Has("user_id") {
+		event.UserID = validatePositive("user_id", eventParse.UserID, verr)
+	}
+
+	if !verr.Has("calendar_id") {
+		event.CalendarID = validateCalendarID(eventParse.CalendarID, verr)
 	}
-	event.Date = time
 
-	if eventParse.UserID <= 0 {
-		return nil, InvalidFormat
+	if err := verr.Err(); err != nil {
+		return nil, err
 	}
-	event.UserID = eventParse.UserID
 
 	return event, nil
 }
 
+// EventUpdate holds the fields present in an update request: empty name and
+// date are kept as is, as well as a nil calendar. A zero calendar moves the
+// event back to the default one
+type EventUpdate struct {
+	Name       string
+	Date       time.Time
+	CalendarID *int
+}
+
+// eventUpdateParse tells an omitted calendar_id from the default calendar
+type eventUpdateParse struct {
+	ID         int    `json:"id"`
+	Name       string `json:"name"`
+	Date       string `json:"date"`
+	CalendarID *int   `json:"calendar_id"`
+}
+
+func EventUpdateFromBody(body []byte) (int, *EventUpdate, error) {
+	verr := NewValidationError(InvalidFormat)
+
+	var eventParse eventUpdateParse
+	if !unmarshalBody(body, &eventParse, verr) {
+		return 0, nil, verr
+	}
+	update := new(EventUpdate)
+
+	id := 0
+	if !verr.Has("id") {
+		id = validatePositive("id", eventParse.ID, verr)
+	}
+
+	if !verr.Has("name") && eventParse.Name != "" {
+		update.Name = validateName(eventParse.Name, false, verr)
+	}
+
+	if !verr.Has("date") && eventParse.Date != "" {
+		update.Date = validateDate(eventParse.Date, false, verr)
+	}
+
+	if !verr.Has("calendar_id") && eventParse.CalendarID != nil {
+		calendarID := validateCalendarID(*eventParse.CalendarID, verr)
+		update.CalendarID = &calendarID
+	}
+
+	if err := verr.Err(); err != nil {
+		return 0, nil, err
+	}
+
+	return id, update, nil
+}
+
+func IDFromBody(body []byte) (int, error) {
+	verr := NewValidationError(InvalidFormat)
+
+	var parse struct {
+		ID int `json:"id"`
+	}
+	if !unmarshalBody(body, &parse, verr) {
+		return 0, verr
+	}
+
+	id := 0
+	if !verr.Has("id") {
+		id = validatePositive("id", parse.ID, verr)
+	}
+
+	return id, verr.Err()
+}
+
+// Validate applies the create rules to an event built without a JSON body
+func (e *Event) Validate() error {
+	verr := NewValidationError(InvalidFormat)
+
+	validateName(e.Name, true, verr)
+	if e.Date.IsZero() {
+		verr.Add("date", CodeRequired, "date is required")
+	}
+	validatePositive("user_id", e.UserID, verr)
+	validateCalendarID(e.CalendarID, verr)
+
+	return verr.Err()
+}
+
+func validateName(name string, required bool, verr *ValidationError) string {
+	switch {
+	case name == "" && required:
+		verr.Add("name", CodeRequired, "name is required")
+	case utf8.RuneCountInString(name) > MaxNameLength:
+		verr.Add("name", CodeTooLong, fmt.Sprintf("name must be at most %d characters long", MaxNameLength))
+	}
+
+	return name
+}
+
+func validateDate(str string, required bool, verr *ValidationError) time.Time {
+	if str == "" {
+		if required {
+			verr.Add("date", CodeRequired, "date is required")
+		}
+		return time.Time{}
+	}
+
+	time, err := date.TimeFromString(str)
+	if err != nil {
+		verr.Add("date", CodeInvalidDate, "date must be an ISO 8601 date like 2024-01-15, 2024-W03-1 or 2024-015")
+	}
+
+	return time
+}
+
+// validateCalendarID allows 0, the default calendar every user has implicitly
+func validateCalendarID(value int, verr *ValidationError) int {
+	if value < 0 {
+		verr.Add("calendar_id", CodeTooSmall, "calendar_id must not be negative")
+	}
+
+	return value
+}
+
+func validatePositive(field string, value int, verr *ValidationError) int {
+	if value <= 0 {
+		verr.Add(field, CodeNotPositive, field+" must be a positive integer")
+	}
+
+	return value
+}
+
 func (e *Event) FormatDate() *EventOut {
-	time := date.StringFromTime(e.Date)
+	return e.FormatDateAs(date.FormatDate)
+}
+
+func (e *Event) FormatDateAs(format date.Format) *EventOut {
+	time := date.StringFromTimeAs(e.Date, format)
 
 	return &EventOut{
-		ID:     e.ID,
-		Name:   e.Name,
-		Date:   time,
-		UserID: e.UserID,
+		ID:         e.ID,
+		Name:       e.Name,
+		Date:       time,
+		UserID:     e.UserID,
+		CalendarID: e.CalendarID,
 	}
 }
@@ -0,0 +1,69 @@
+package model
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+var (
+	IdempotencyKeyReused     = fmt.Errorf("Idempotency-Key is already used with another body")
+	IdempotencyKeyInProgress = fmt.Errorf("Request with this Idempotency-Key is in progress")
+)
+
+const MaxIdempotencyKeyLength = 255
+
+// Idempotency remembers the response to the first request with a key, so
+// retries get it back instead of creating the event again
+type Idempotency struct {
+	Key         string
+	Fingerprint string
+	// Done is false while the first request is in progress
+	Done bool
+
+	ID       int
+	Warnings []string
+
+	ExpiresAt time.Time
+}
+
+// Fingerprint hashes a JSON body regardless of key order and whitespace,
+// other bodies are hashed as they are
+func Fingerprint(body []byte) string {
+	var value any
+	if err := json.Unmarshal(body, &value); err == nil {
+		if canonical, err := json.Marshal(value); err == nil {
+			body = canonical
+		}
+	}
+
+	sum := sha256.Sum256(bytes.TrimSpace(body))
+	return hex.EncodeToString(sum[:])
+}
+
+// ValidateIdempotencyKey allows up to 255 printable ASCII characters
+func ValidateIdempotencyKey(key string) error {
+	verr := NewValidationError(InvalidFormat)
+
+	switch {
+	case len(key) > MaxIdempotencyKeyLength:
+		verr.Add("Idempotency-Key", CodeTooLong, fmt.Sprintf("Idempotency-Key must be at most %d characters long", MaxIdempotencyKeyLength))
+	case !printable(key):
+		verr.Add("Idempotency-Key", CodeInvalidType, "Idempotency-Key must consist of printable ASCII characters")
+	}
+
+	return verr.Err()
+}
+
+func printable(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < ' ' || s[i] > '~' {
+			return false
+		}
+	}
+
+	return true
+}
@@ -2,11 +2,13 @@ package model
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 )
 
 type errorResp struct {
-	Err string `json:"error"`
+	Err    string       `json:"error"`
+	Fields []FieldError `json:"fields,omitempty"`
 }
 
 func ErrorResp(err string) errorResp {
@@ -15,6 +17,18 @@ func ErrorResp(err string) errorResp {
 	}
 }
 
+// ErrorRespFromError adds the failing fields when err is a ValidationError
+func ErrorRespFromError(err error) errorResp {
+	resp := ErrorResp(err.Error())
+
+	var verr *ValidationError
+	if errors.As(err, &verr) {
+		resp.Fields = verr.Fields
+	}
+
+	return resp
+}
+
 func (e errorResp) ToJSON() []byte {
 	return marshal("error response", e)
 }
@@ -0,0 +1,254 @@
+package model
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+	_ "time/tzdata"
+	"wb_l2/18/pkg/date"
+)
+
+// OutsideHours is what happens to an event created on a day the owner doesn't
+// work: a weekday without working hours or a holiday
+type OutsideHours string
+
+const (
+	OutsideHoursAllow  OutsideHours = "allow"
+	OutsideHoursWarn   OutsideHours = "warn"
+	OutsideHoursReject OutsideHours = "reject"
+)
+
+var OutsideHoursPolicies = []OutsideHours{OutsideHoursAllow, OutsideHoursWarn, OutsideHoursReject}
+
+var OutsideWorkingHours = fmt.Errorf("Event is outside working hours")
+
+// Interval is a part of a working day in minutes since midnight, End is
+// exclusive
+type Interval struct {
+	Start int
+	End   int
+}
+
+type Schedule struct {
+	UserID int
+
+	TimeZone string
+	Location *time.Location
+
+	// Weekdays missing from the map are days off
+	WorkingHours     map[time.Weekday][]Interval
+	HolidayCalendars []string
+	OutsideHours     OutsideHours
+}
+
+// DefaultSchedule is used for users who never set theirs: Monday to Friday,
+// 09:00 to 18:00 UTC, events on other days are allowed
+func DefaultSchedule(userID int) *Schedule {
+	workday := []Interval{{Start: 9 * 60, End: 18 * 60}}
+
+	return &Schedule{
+		UserID:   userID,
+		TimeZone: "UTC",
+		Location: time.UTC,
+		WorkingHours: map[time.Weekday][]Interval{
+			time.Monday:    workday,
+			time.Tuesday:   workday,
+			time.Wednesday: workday,
+			time.Thursday:  workday,
+			time.Friday:    workday,
+		},
+		HolidayCalendars: []string{},
+		OutsideHours:     OutsideHoursAllow,
+	}
+}
+
+type IntervalOut struct {
+	Start string `json:"start"`
+	End   string `json:"end"`
+}
+
+type ScheduleOut struct {
+	UserID int `json:"user_id"`
+
+	TimeZone         string                   `json:"time_zone"`
+	WorkingHours     map[string][]IntervalOut `json:"working_hours"`
+	HolidayCalendars []string                 `json:"holiday_calendars"`
+	OutsideHours     OutsideHours             `json:"outside_hours"`
+}
+
+func ScheduleFromBody(body []byte) (*Schedule, error) {
+	verr := NewValidationError(InvalidFormat)
+
+	var scheduleParse ScheduleOut
+	if !unmarshalBody(body, &scheduleParse, verr) {
+		return nil, verr
+	}
+	schedule := DefaultSchedule(0)
+
+	if !verr.Has("user_id") {
+		schedule.UserID = validatePositive("user_id", scheduleParse.UserID, verr)
+	}
+
+	if !verr.Has("time_zone") && scheduleParse.TimeZone != "" {
+		location, err := time.LoadLocation(scheduleParse.TimeZone)
+		if err != nil {
+			verr.Add("time_zone", CodeUnknownValue, fmt.Sprintf("unknown time zone %q, use an IANA name like Europe/Moscow", scheduleParse.TimeZone))
+		} else {
+			schedule.TimeZone, schedule.Location = scheduleParse.TimeZone, location
+		}
+	}
+
+	if !verr.Has("working_hours") && scheduleParse.WorkingHours != nil {
+		schedule.WorkingHours = validateWorkingHours(scheduleParse.WorkingHours, verr)
+	}
+
+	if !verr.Has("holiday_calendars") && scheduleParse.HolidayCalendars != nil {
+		schedule.HolidayCalendars = scheduleParse.HolidayCalendars
+	}
+
+	if !verr.Has("outside_hours") && scheduleParse.OutsideHours != "" {
+		if !slices.Contains(OutsideHoursPolicies, scheduleParse.OutsideHours) {
+			verr.Add("outside_hours", CodeUnknownValue, "outside_hours must be one of allow, warn or reject")
+		}
+		schedule.OutsideHours = scheduleParse.OutsideHours
+	}
+
+	if err := verr.Err(); err != nil {
+		return nil, err
+	}
+
+	return schedule, nil
+}
+
+// validateWorkingHours reads weekday names to sorted, non-overlapping
+// intervals. An empty list makes the day a day off
+func validateWorkingHours(days map[string][]IntervalOut, verr *ValidationError) map[time.Weekday][]Interval {
+	res := make(map[time.Weekday][]Interval)
+
+	for name, intervals := range days {
+		field := "working_hours." + name
+
+		weekday, err := date.WeekdayFromString(name)
+		if err != nil {
+			verr.Add(field, CodeUnknownValue, fmt.Sprintf("unknown weekday %q", name))
+			continue
+		}
+
+		parsed := make([]Interval, 0, len(intervals))
+		for i, interval := range intervals {
+			start, okStart := minutesFromClock(interval.Start)
+			end, okEnd := minutesFromClock(interval.End)
+			switch {
+			case !okStart || !okEnd:
+				verr.Add(fmt.Sprintf("%s[%d]", field, i), CodeInvalidTime, "start and end must be times like 09:00, end may be 24:00")
+				continue
+			case start >= end:
+				verr.Add(fmt.Sprintf("%s[%d]", field, i), CodeInvalidTime, "start must be before end")
+				continue
+			}
+			parsed = append(parsed, Interval{Start: start, End: end})
+		}
+
+		slices.SortFunc(parsed, func(a, b Interval) int { return a.Start - b.Start })
+		for i := 1; i < len(parsed); i++ {
+			if parsed[i].Start < parsed[i-1].End {
+				verr.Add(field, CodeInvalidTime, "working hours of a day must not overlap")
+				break
+			}
+		}
+
+		if len(parsed) > 0 {
+			res[weekday] = parsed
+		}
+	}
+
+	return res
+}
+
+// minutesFromClock reads HH:MM, 24:00 is allowed as the end of a day
+func minutesFromClock(str string) (int, bool) {
+	t, err := time.Parse("15:04", str)
+	if err == nil {
+		return t.Hour()*60 + t.Minute(), true
+	}
+
+	if str == "24:00" {
+		return 24 * 60, true
+	}
+
+	return 0, false
+}
+
+func clockFromMinutes(minutes int) string {
+	return fmt.Sprintf("%02d:%02d", minutes/60, minutes%60)
+}
+
+// Works tells whether the weekday of the day has working hours, holidays are
+// checked separately
+func (s *Schedule) Works(day time.Time) bool {
+	return len(s.WorkingHours[day.Weekday()]) > 0
+}
+
+// Hours returns the working intervals of the day as instants in the user's
+// time zone
+func (s *Schedule) Hours(day time.Time) []IntervalOut {
+	res := make([]IntervalOut, 0)
+	for _, interval := range s.WorkingHours[day.Weekday()] {
+		res = append(res, IntervalOut{
+			Start: s.at(day, interval.Start).Format(time.RFC3339),
+			End:   s.at(day, interval.End).Format(time.RFC3339),
+		})
+	}
+
+	return res
+}
+
+func (s *Schedule) at(day time.Time, minutes int) time.Time {
+	return time.Date(day.Year(), day.Month(), day.Day(), 0, minutes, 0, 0, s.Location)
+}
+
+func (s *Schedule) Format() *ScheduleOut {
+	hours := make(map[string][]IntervalOut, len(s.WorkingHours))
+	for weekday, intervals := range s.WorkingHours {
+		out := make([]IntervalOut, 0, len(intervals))
+		for _, interval := range intervals {
+			out = append(out, IntervalOut{Start: clockFromMinutes(interval.Start), End: clockFromMinutes(interval.End)})
+		}
+		hours[strings.ToLower(weekday.String())] = out
+	}
+
+	return &ScheduleOut{
+		UserID:           s.UserID,
+		TimeZone:         s.TimeZone,
+		WorkingHours:     hours,
+		HolidayCalendars: slices.Clone(s.HolidayCalendars),
+		OutsideHours:     s.OutsideHours,
+	}
+}
+
+// Holiday is a day off from a holiday calendar. Yearly ones repeat on the
+// same month and day starting from Date
+type Holiday struct {
+	Date   time.Time
+	Name   string
+	Yearly bool
+}
+
+func (h Holiday) On(day time.Time) bool {
+	if !h.Yearly {
+		return h.Date.Equal(day)
+	}
+
+	return h.Date.Month() == day.Month() && h.Date.Day() == day.Day() && !day.Before(h.Date)
+}
+
+type DayAvailabilityOut struct {
+	Date    string        `json:"date"`
+	Working bool          `json:"working"`
+	Holiday string        `json:"holiday,omitempty"`
+	Hours   []IntervalOut `json:"hours"`
+	Events  []*EventOut   `json:"events"`
+	// Free is a working day without events
+	Free bool `json:"free"`
+}
@@ -0,0 +1,114 @@
+package model
+
+type Permission string
+
+const (
+	PermissionRead  Permission = "read"
+	PermissionWrite Permission = "write"
+)
+
+// Share gives the grantee access to every event of the owner
+type Share struct {
+	OwnerID    int
+	GranteeID  int
+	Permission Permission
+}
+
+type ShareOut struct {
+	OwnerID    int        `json:"owner_id"`
+	GranteeID  int        `json:"grantee_id"`
+	Permission Permission `json:"permission"`
+}
+
+type SharesOut struct {
+	Given    []*ShareOut `json:"given"`
+	Received []*ShareOut `json:"received"`
+}
+
+func ShareFromBody(body []byte) (*Share, error) {
+	verr := NewValidationError(InvalidFormat)
+
+	var shareParse ShareOut
+	if !unmarshalBody(body, &shareParse, verr) {
+		return nil, verr
+	}
+	share := new(Share)
+
+	if !verr.Has("owner_id") {
+		share.OwnerID = validatePositive("owner_id", shareParse.OwnerID, verr)
+	}
+
+	if !verr.Has("grantee_id") {
+		share.GranteeID = validatePositive("grantee_id", shareParse.GranteeID, verr)
+		if share.GranteeID > 0 && share.GranteeID == share.OwnerID {
+			verr.Add("grantee_id", CodeUnknownValue, "events can not be shared with their owner")
+		}
+	}
+
+	if !verr.Has("permission") {
+		switch shareParse.Permission {
+		case "":
+			verr.Add("permission", CodeRequired, "permission is required")
+		case PermissionRead, PermissionWrite:
+			share.Permission = shareParse.Permission
+		default:
+			verr.Add("permission", CodeUnknownValue, `permission must be "read" or "write"`)
+		}
+	}
+
+	if err := verr.Err(); err != nil {
+		return nil, err
+	}
+
+	return share, nil
+}
+
+// ShareRevokeFromBody parses the owner and grantee of a share, permission is
+// ignored
+func ShareRevokeFromBody(body []byte) (int, int, error) {
+	verr := NewValidationError(InvalidFormat)
+
+	var shareParse ShareOut
+	if !unmarshalBody(body, &shareParse, verr) {
+		return 0, 0, verr
+	}
+
+	ownerID, granteeID := 0, 0
+	if !verr.Has("owner_id") {
+		ownerID = validatePositive("owner_id", shareParse.OwnerID, verr)
+	}
+
+	if !verr.Has("grantee_id") {
+		granteeID = validatePositive("grantee_id", shareParse.GranteeID, verr)
+	}
+
+	return ownerID, granteeID, verr.Err()
+}
+
+// ActorFromBody returns the required user_id of the user performing a change
+// of an event, the owner or a grantee
+func ActorFromBody(body []byte) (int, error) {
+	verr := NewValidationError(InvalidFormat)
+
+	var actorParse struct {
+		UserID int `json:"user_id"`
+	}
+	if !unmarshalBody(body, &actorParse, verr) {
+		return 0, verr
+	}
+
+	actorID := 0
+	if !verr.Has("user_id") {
+		actorID = validatePositive("user_id", actorParse.UserID, verr)
+	}
+
+	return actorID, verr.Err()
+}
+
+func (s *Share) Format() *ShareOut {
+	return &ShareOut{
+		OwnerID:    s.OwnerID,
+		GranteeID:  s.GranteeID,
+		Permission: s.Permission,
+	}
+}
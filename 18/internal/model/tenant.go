@@ -0,0 +1,107 @@
+package model
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+)
+
+var TenantExists = fmt.Errorf("Tenant with this id or host already exists")
+
+// Tenant is an organization with its own users, events and everything else.
+// Requests name it in a header or come to one of its hosts
+type Tenant struct {
+	ID    string
+	Name  string
+	Hosts []string
+
+	CreatedAt time.Time
+}
+
+type TenantOut struct {
+	ID    string   `json:"id"`
+	Name  string   `json:"name"`
+	Hosts []string `json:"hosts"`
+
+	CreatedAt string `json:"created_at,omitempty"`
+}
+
+// TenantUsageOut counts what a tenant stores
+type TenantUsageOut struct {
+	TenantID  string `json:"tenant_id"`
+	Events    int    `json:"events"`
+	Calendars int    `json:"calendars"`
+	Shares    int    `json:"shares"`
+	Webhooks  int    `json:"webhooks"`
+}
+
+var tenantIDPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{0,62}$`)
+
+func TenantFromBody(body []byte) (*Tenant, error) {
+	verr := NewValidationError(InvalidFormat)
+
+	var tenantParse TenantOut
+	if !unmarshalBody(body, &tenantParse, verr) {
+		return nil, verr
+	}
+	tenant := new(Tenant)
+
+	if !verr.Has("id") {
+		tenant.ID = ValidateTenantID("id", tenantParse.ID, verr)
+	}
+
+	if !verr.Has("name") {
+		tenant.Name = validateName(tenantParse.Name, true, verr)
+	}
+
+	if !verr.Has("hosts") {
+		for i, host := range tenantParse.Hosts {
+			field := fmt.Sprintf("hosts[%d]", i)
+			host = strings.ToLower(host)
+
+			switch {
+			case host == "" || strings.ContainsAny(host, " /:@"):
+				verr.Add(field, CodeInvalidURL, "host must be a host name without a port like acme.example.com")
+			case slices.Contains(tenant.Hosts, host):
+				verr.Add(field, CodeUnknownValue, "hosts must not repeat")
+			default:
+				tenant.Hosts = append(tenant.Hosts, host)
+			}
+		}
+	}
+
+	if err := verr.Err(); err != nil {
+		return nil, err
+	}
+
+	return tenant, nil
+}
+
+// ValidateTenantID allows lowercase letters, digits and dashes, so ids are
+// safe in headers and host names
+func ValidateTenantID(field, id string, verr *ValidationError) string {
+	switch {
+	case id == "":
+		verr.Add(field, CodeRequired, field+" is required")
+	case !tenantIDPattern.MatchString(id):
+		verr.Add(field, CodeInvalidSlug, field+" must be up to 63 lowercase letters, digits and dashes, starting with a letter or digit")
+	}
+
+	return id
+}
+
+func (t *Tenant) Format() *TenantOut {
+	hosts := t.Hosts
+	if hosts == nil {
+		hosts = []string{}
+	}
+
+	return &TenantOut{
+		ID:        t.ID,
+		Name:      t.Name,
+		Hosts:     hosts,
+		CreatedAt: t.CreatedAt.Format(time.RFC3339),
+	}
+}
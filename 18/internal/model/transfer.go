@@ -0,0 +1,101 @@
+package model
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+)
+
+// TransferFormat is the layout of exported and imported events, one event per
+// row or line
+type TransferFormat string
+
+const (
+	TransferCSV   TransferFormat = "csv"
+	TransferJSONL TransferFormat = "jsonl"
+)
+
+var TransferFormats = []TransferFormat{TransferCSV, TransferJSONL}
+
+func (f TransferFormat) ContentType() string {
+	if f == TransferJSONL {
+		return "application/x-ndjson"
+	}
+
+	return "text/csv; charset=utf-8"
+}
+
+// EventColumns is the header of exported CSV files. Imported files name the
+// columns they have in the header, in any order
+var EventColumns = []string{"id", "name", "date", "user_id", "calendar_id"}
+
+func (e *EventOut) Record() []string {
+	return []string{
+		strconv.Itoa(e.ID),
+		e.Name,
+		e.Date,
+		strconv.Itoa(e.UserID),
+		strconv.Itoa(e.CalendarID),
+	}
+}
+
+// EventFromRecord applies the EventFromBody rules to a CSV row. Integer columns
+// that don't parse are passed on as strings, so they fail as invalid_type. The
+// id column is ignored, imported events get new ids
+func EventFromRecord(header, record []string) (*Event, error) {
+	row := make(map[string]any, len(header))
+	for i, column := range header {
+		if i >= len(record) || record[i] == "" || column == "id" {
+			continue
+		}
+
+		row[column] = record[i]
+		if column == "user_id" || column == "calendar_id" {
+			if value, err := strconv.Atoi(record[i]); err == nil {
+				row[column] = value
+			}
+		}
+	}
+
+	body, err := json.Marshal(row)
+	if err != nil {
+		return nil, err
+	}
+
+	return EventFromBody(body)
+}
+
+// ImportOut sums up an import, nothing is stored on a dry run
+type ImportOut struct {
+	Imported int      `json:"imported"`
+	DryRun   bool     `json:"dry_run"`
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// ImportError stops an import at the row on Line, the Imported rows before it
+// stay stored
+type ImportError struct {
+	Line     int
+	Imported int
+	Err      error
+}
+
+func (e *ImportError) Error() string {
+	return fmt.Sprintf("line %d: %v", e.Line, e.Err)
+}
+
+func (e *ImportError) Unwrap() error {
+	return e.Err
+}
+
+type importErrorResp struct {
+	errorResp
+	Line     int `json:"line"`
+	Imported int `json:"imported"`
+}
+
+// ImportErrorResp adds where the import stopped and how many events it
+// created to resp
+func ImportErrorResp(err *ImportError, resp errorResp) importErrorResp {
+	return importErrorResp{errorResp: resp, Line: err.Line, Imported: err.Imported}
+}
@@ -0,0 +1,130 @@
+package model
+
+import (
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// Machine-readable codes of field validation errors
+const (
+	CodeRequired     = "required"
+	CodeInvalidJSON  = "invalid_json"
+	CodeInvalidType  = "invalid_type"
+	CodeInvalidDate  = "invalid_date"
+	CodeInvalidURL   = "invalid_url"
+	CodeNotPositive  = "not_positive"
+	CodeTooSmall     = "too_small"
+	CodeTooShort     = "too_short"
+	CodeTooLong      = "too_long"
+	CodeUnknownValue = "unknown_value"
+	CodeUnknownField = "unknown_field"
+	CodeInvalidTime  = "invalid_time"
+	CodeOutsideHours = "outside_working_hours"
+	CodeInvalidCSV   = "invalid_csv"
+	CodeInvalidSlug  = "invalid_slug"
+)
+
+const MaxNameLength = 255
+
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	// Line points to the failing row of an imported file
+	Line int `json:"line,omitempty"`
+}
+
+func (f FieldError) String() string {
+	message := f.Message
+	if f.Field != "" {
+		message = f.Field + ": " + message
+	}
+
+	if f.Line > 0 {
+		message = "line " + strconv.Itoa(f.Line) + ": " + message
+	}
+
+	return message
+}
+
+// ValidationError lists every failing field. It unwraps to the base error
+// (InvalidFormat for bodies, service.InvalidQuery for queries), so callers
+// matching the base error keep working
+type ValidationError struct {
+	Base   error
+	Fields []FieldError
+}
+
+func NewValidationError(base error) *ValidationError {
+	return &ValidationError{Base: base}
+}
+
+func (e *ValidationError) Error() string {
+	return e.Base.Error()
+}
+
+func (e *ValidationError) Unwrap() error {
+	return e.Base
+}
+
+func (e *ValidationError) Add(field, code, message string) {
+	e.Fields = append(e.Fields, FieldError{Field: field, Code: code, Message: message})
+}
+
+func (e *ValidationError) Has(field string) bool {
+	for _, f := range e.Fields {
+		if f.Field == field {
+			return true
+		}
+	}
+
+	return false
+}
+
+// AddLine adds the fields of a failing row of an imported file
+func (e *ValidationError) AddLine(line int, verr *ValidationError) {
+	for _, field := range verr.Fields {
+		field.Line = line
+		e.Fields = append(e.Fields, field)
+	}
+}
+
+// Err returns nil when no field failed, so it can be returned directly
+func (e *ValidationError) Err() error {
+	if len(e.Fields) == 0 {
+		return nil
+	}
+
+	return e
+}
+
+// unmarshalBody decodes body into v recording syntax and type errors. Fields
+// with a type error are left zero, callers check Has before validating them
+func unmarshalBody(body []byte, v any, verr *ValidationError) bool {
+	err := json.Unmarshal(body, v)
+	if err == nil {
+		return true
+	}
+
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) && typeErr.Field != "" {
+		verr.Add(typeErr.Field, CodeInvalidType, "must be "+jsonKind(typeErr.Type.Kind().String()))
+		return true
+	}
+
+	verr.Add("", CodeInvalidJSON, "body must be a JSON object")
+	return false
+}
+
+func jsonKind(kind string) string {
+	switch {
+	case strings.HasPrefix(kind, "int"), strings.HasPrefix(kind, "uint"):
+		return "an integer"
+	case kind == "slice":
+		return "an array"
+	default:
+		return "a " + kind
+	}
+}
@@ -0,0 +1,41 @@
+package model
+
+import (
+	"fmt"
+	"hash/fnv"
+	"slices"
+	"time"
+)
+
+// Version tells when what a user sees in lists of events last changed: their
+// events, calendars or shares with them
+type Version struct {
+	UserID     int
+	Version    int
+	ModifiedAt time.Time
+}
+
+// ListVersion identifies the content of a list of events, it changes with the
+// version of any user whose events are listed
+type ListVersion struct {
+	ETag       string
+	ModifiedAt time.Time
+}
+
+// NewListVersion combines versions of the listed users in any order
+func NewListVersion(versions []*Version) *ListVersion {
+	versions = slices.Clone(versions)
+	slices.SortFunc(versions, func(a, b *Version) int { return a.UserID - b.UserID })
+
+	hash := fnv.New64a()
+	res := &ListVersion{}
+	for _, version := range versions {
+		fmt.Fprintf(hash, "%d:%d;", version.UserID, version.Version)
+		if version.ModifiedAt.After(res.ModifiedAt) {
+			res.ModifiedAt = version.ModifiedAt
+		}
+	}
+	res.ETag = fmt.Sprintf(`"%x"`, hash.Sum64())
+
+	return res
+}
@@ -0,0 +1,179 @@
+package model
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"slices"
+	"time"
+)
+
+type EventType string
+
+const (
+	EventCreated EventType = "event.created"
+	EventUpdated EventType = "event.updated"
+	EventDeleted EventType = "event.deleted"
+)
+
+var EventTypes = []EventType{EventCreated, EventUpdated, EventDeleted}
+
+type Webhook struct {
+	ID     int
+	URL    string
+	Events []EventType
+	Secret string
+
+	UserID int
+}
+
+type WebhookOut struct {
+	ID     int         `json:"id"`
+	URL    string      `json:"url"`
+	Events []EventType `json:"events"`
+	Secret string      `json:"secret,omitempty"`
+
+	UserID int `json:"user_id"`
+}
+
+func WebhookFromBody(body []byte) (*Webhook, error) {
+	verr := NewValidationError(InvalidFormat)
+
+	var webhookParse WebhookOut
+	if !unmarshalBody(body, &webhookParse, verr) {
+		return nil, verr
+	}
+	webhook := new(Webhook)
+
+	if !verr.Has("url") {
+		target, err := url.Parse(webhookParse.URL)
+		switch {
+		case webhookParse.URL == "":
+			verr.Add("url", CodeRequired, "url is required")
+		case err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "":
+			verr.Add("url", CodeInvalidURL, "url must be an absolute http or https URL")
+		default:
+			webhook.URL = target.String()
+		}
+	}
+
+	if !verr.Has("events") {
+		if len(webhookParse.Events) == 0 {
+			verr.Add("events", CodeRequired, "at least one event type is required")
+		}
+		for i, eventType := range webhookParse.Events {
+			if !slices.Contains(EventTypes, eventType) {
+				verr.Add(fmt.Sprintf("events[%d]", i), CodeUnknownValue, fmt.Sprintf("unknown event type %q", eventType))
+				continue
+			}
+			if !slices.Contains(webhook.Events, eventType) {
+				webhook.Events = append(webhook.Events, eventType)
+			}
+		}
+	}
+
+	if !verr.Has("user_id") {
+		webhook.UserID = validatePositive("user_id", webhookParse.UserID, verr)
+	}
+
+	webhook.Secret = webhookParse.Secret
+
+	if err := verr.Err(); err != nil {
+		return nil, err
+	}
+
+	return webhook, nil
+}
+
+func (w *Webhook) Subscribed(eventType EventType) bool {
+	return slices.Contains(w.Events, eventType)
+}
+
+// Format hides the signing secret, it is shown only once on creation
+func (w *Webhook) Format() *WebhookOut {
+	return &WebhookOut{
+		ID:     w.ID,
+		URL:    w.URL,
+		Events: slices.Clone(w.Events),
+		UserID: w.UserID,
+	}
+}
+
+type DeliveryStatus string
+
+const (
+	DeliveryPending   DeliveryStatus = "pending"
+	DeliverySucceeded DeliveryStatus = "succeeded"
+	DeliveryDead      DeliveryStatus = "dead"
+)
+
+type DeliveryAttempt struct {
+	At         time.Time
+	StatusCode int
+	Error      string
+}
+
+type Delivery struct {
+	ID        int
+	WebhookID int
+	Event     EventType
+	Payload   []byte
+	Status    DeliveryStatus
+	Attempts  []DeliveryAttempt
+	CreatedAt time.Time
+
+	UserID int
+}
+
+func (d *Delivery) Clone() *Delivery {
+	clone := *d
+	clone.Payload = slices.Clone(d.Payload)
+	clone.Attempts = slices.Clone(d.Attempts)
+	return &clone
+}
+
+type DeliveryAttemptOut struct {
+	At         string `json:"at"`
+	StatusCode int    `json:"status_code,omitempty"`
+	Error      string `json:"error,omitempty"`
+}
+
+type DeliveryOut struct {
+	ID        int                  `json:"id"`
+	WebhookID int                  `json:"webhook_id"`
+	Event     EventType            `json:"event"`
+	Status    DeliveryStatus       `json:"status"`
+	Attempts  []DeliveryAttemptOut `json:"attempts"`
+	CreatedAt string               `json:"created_at"`
+	Payload   json.RawMessage      `json:"payload"`
+
+	UserID int `json:"user_id"`
+}
+
+func (d *Delivery) Format() *DeliveryOut {
+	attempts := make([]DeliveryAttemptOut, 0, len(d.Attempts))
+	for _, attempt := range d.Attempts {
+		attempts = append(attempts, DeliveryAttemptOut{
+			At:         attempt.At.Format(time.RFC3339),
+			StatusCode: attempt.StatusCode,
+			Error:      attempt.Error,
+		})
+	}
+
+	return &DeliveryOut{
+		ID:        d.ID,
+		WebhookID: d.WebhookID,
+		Event:     d.Event,
+		Status:    d.Status,
+		Attempts:  attempts,
+		CreatedAt: d.CreatedAt.Format(time.RFC3339),
+		Payload:   d.Payload,
+		UserID:    d.UserID,
+	}
+}
+
+type WebhookPayload struct {
+	Type       EventType `json:"type"`
+	OccurredAt string    `json:"occurred_at"`
+	Event      *EventOut `json:"event"`
+}
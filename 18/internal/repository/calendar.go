@@ -0,0 +1,14 @@
+package repository
+
+import "wb_l2/18/internal/model"
+
+type calendarRepository interface {
+	pinger
+
+	Create(calendar *model.Calendar) (int, error)
+	Get(ID int) (*model.Calendar, error)
+	ListForUser(userID int) ([]*model.Calendar, error)
+	Update(ID int, update *model.CalendarUpdate) (*model.Calendar, error)
+	Delete(ID int) error
+	Count() (int, error)
+}
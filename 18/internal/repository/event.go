@@ -6,10 +6,18 @@ import (
 )
 
 type eventRepository interface {
+	pinger
+
 	Create(event *model.Event) (int, error)
-	ListForDay(userID int, date time.Time) ([]*model.Event, error)
-	ListForWeek(userID int, starting time.Time) ([]*model.Event, error)
-	ListForMonth(userID int, starting time.Time) ([]*model.Event, error)
-	Update(ID int, event *model.Event) (*model.Event, error)
+	Get(ID int) (*model.Event, error)
+	GetByResource(userID int, resource string) (*model.Event, error)
+	ListForPeriod(userID int, from, to time.Time) ([]*model.Event, error)
+	Update(ID int, update *model.EventUpdate) (*model.Event, error)
 	Delete(ID int) error
+	Count() (int, error)
+
+	MoveCalendar(from, to int) ([]*model.Event, error)
+	DeleteForCalendar(calendarID int) ([]*model.Event, error)
+
+	Changes(userID, since int) (*model.EventChanges, error)
 }
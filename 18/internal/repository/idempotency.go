@@ -0,0 +1,20 @@
+package repository
+
+import (
+	"time"
+	"wb_l2/18/internal/model"
+)
+
+type idempotencyRepository interface {
+	pinger
+
+	// Claim stores the record unless a record with the key that has not
+	// expired by now exists, which is returned instead
+	Claim(record *model.Idempotency, now time.Time) (*model.Idempotency, bool, error)
+	// Complete stores the response in the claimed record, unless the key
+	// has expired and was claimed by another request since
+	Complete(record *model.Idempotency, id int, warnings []string) error
+	// Release forgets the claimed record while it is in progress, so a failed
+	// request can be retried. A newer claim of the same key is kept
+	Release(record *model.Idempotency) error
+}
@@ -0,0 +1,135 @@
+package calendar
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"wb_l2/18/internal/model"
+	"wb_l2/18/internal/repository/inmemory"
+)
+
+var (
+	ErrorCalendarNotFound = fmt.Errorf("Calendar is not found")
+	ErrorCalendarExists   = fmt.Errorf("Calendar with this name already exists")
+)
+
+type CalendarRepository struct {
+	calendars     map[int]*model.Calendar
+	autoincrement int
+
+	mu sync.Mutex
+}
+
+func NewCalendarRepositoryInMemory() *CalendarRepository {
+	return &CalendarRepository{
+		calendars:     make(map[int]*model.Calendar),
+		autoincrement: 1,
+	}
+}
+
+func (r *CalendarRepository) Create(calendar *model.Calendar) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.nameTaken(calendar.UserID, calendar.Name, 0) {
+		return 0, ErrorCalendarExists
+	}
+
+	id := r.autoincrement
+	calendar.ID = id
+	r.calendars[id] = calendar
+
+	r.autoincrement++
+
+	return id, nil
+}
+
+func (r *CalendarRepository) Get(ID int) (*model.Calendar, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	calendar, ok := r.calendars[ID]
+	if !ok {
+		return nil, ErrorCalendarNotFound
+	}
+
+	return calendar, nil
+}
+
+func (r *CalendarRepository) ListForUser(userID int) ([]*model.Calendar, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	res := make([]*model.Calendar, 0)
+	for _, id := range slices.Sorted(maps.Keys(r.calendars)) {
+		if r.calendars[id].UserID != userID {
+			continue
+		}
+
+		res = append(res, r.calendars[id])
+	}
+
+	return res, nil
+}
+
+func (r *CalendarRepository) Update(ID int, update *model.CalendarUpdate) (*model.Calendar, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	calendar, ok := r.calendars[ID]
+	if !ok {
+		return nil, ErrorCalendarNotFound
+	}
+
+	if update.Name != nil {
+		if r.nameTaken(calendar.UserID, *update.Name, ID) {
+			return nil, ErrorCalendarExists
+		}
+		calendar.Name = *update.Name
+	}
+
+	if update.Visible != nil {
+		calendar.Visible = *update.Visible
+	}
+
+	return calendar, nil
+}
+
+func (r *CalendarRepository) Delete(ID int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.calendars[ID]; !ok {
+		return ErrorCalendarNotFound
+	}
+
+	delete(r.calendars, ID)
+	return nil
+}
+
+// nameTaken checks case-insensitive uniqueness of names per user, skipping the
+// calendar being renamed
+func (r *CalendarRepository) nameTaken(userID int, name string, skipID int) bool {
+	for id, calendar := range r.calendars {
+		if id != skipID && calendar.UserID == userID && strings.EqualFold(calendar.Name, name) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Count is the number of stored calendars, for usage reports
+func (r *CalendarRepository) Count() (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return len(r.calendars), nil
+}
+
+func (r *CalendarRepository) Ping(ctx context.Context) error {
+	return inmemory.PingLock(ctx, &r.mu)
+}
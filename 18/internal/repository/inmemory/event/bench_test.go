@@ -0,0 +1,77 @@
+package event
+
+import (
+	"testing"
+	"time"
+	"wb_l2/18/internal/model"
+)
+
+const (
+	benchUsers  = 100
+	benchEvents = 10000
+)
+
+var benchStart = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// filled spreads events over users and the days of a year
+func filled(b *testing.B) *EventRepository {
+	r := NewEventRepositoryInMemory()
+	for i := range benchEvents {
+		event := &model.Event{Name: "Event", Date: benchStart.AddDate(0, 0, i%366), UserID: 1 + i%benchUsers}
+		if _, err := r.Create(event); err != nil {
+			b.Fatal(err)
+		}
+	}
+
+	return r
+}
+
+func BenchmarkCreate(b *testing.B) {
+	r := NewEventRepositoryInMemory()
+
+	for i := 0; b.Loop(); i++ {
+		r.Create(&model.Event{Name: "Event", Date: benchStart, UserID: 1 + i%benchUsers})
+	}
+}
+
+func BenchmarkListForPeriod(b *testing.B) {
+	r := filled(b)
+
+	for i := 0; b.Loop(); i++ {
+		from := benchStart.AddDate(0, 0, i%366)
+		if _, err := r.ListForPeriod(1+i%benchUsers, from, from.AddDate(0, 1, 0)); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkUpdate(b *testing.B) {
+	r := filled(b)
+
+	for i := 0; b.Loop(); i++ {
+		if _, err := r.Update(1+i%benchEvents, &model.EventUpdate{Name: "Renamed"}); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkChanges(b *testing.B) {
+	r := filled(b)
+
+	for i := 0; b.Loop(); i++ {
+		if _, err := r.Changes(1+i%benchUsers, benchEvents/2); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkListForPeriodParallel(b *testing.B) {
+	r := filled(b)
+
+	b.RunParallel(func(pb *testing.PB) {
+		for i := 0; pb.Next(); i++ {
+			from := benchStart.AddDate(0, 0, i%366)
+			r.ListForPeriod(1+i%benchUsers, from, from.AddDate(0, 0, 7))
+		}
+	})
+}
@@ -1,30 +1,73 @@
 package event
 
 import (
+	"context"
 	"fmt"
-	"iter"
 	"maps"
+	"slices"
 	"sync"
 	"time"
 	"wb_l2/18/internal/model"
+	"wb_l2/18/internal/repository/inmemory"
 )
 
 var ErrorEventNotFound = fmt.Errorf("Event is not found")
 
+// tombstone remembers a deleted event so that sync clients learn about it
+type tombstone struct {
+	event   *model.Event
+	version int
+}
+
+// resource is a CalDAV resource name, unique within the events of a user
+type resource struct {
+	userID int
+	name   string
+}
+
 type EventRepository struct {
 	events        map[int]*model.Event
+	resources     map[resource]int
 	autoincrement int
 
+	// version grows with every change, userVersions keeps the last change of
+	// each user
+	version      int
+	userVersions map[int]int
+	tombstones   map[int][]tombstone
+
 	mu sync.Mutex
 }
 
 func NewEventRepositoryInMemory() *EventRepository {
 	return &EventRepository{
 		events:        make(map[int]*model.Event),
+		resources:     make(map[resource]int),
 		autoincrement: 1,
+		userVersions:  make(map[int]int),
+		tombstones:    make(map[int][]tombstone),
 	}
 }
 
+// touch stamps a changed event with the next version
+func (r *EventRepository) touch(event *model.Event) {
+	r.version++
+	event.Version = r.version
+	event.UpdatedAt = time.Now().UTC()
+	r.userVersions[event.UserID] = r.version
+}
+
+func (r *EventRepository) bury(event *model.Event) {
+	delete(r.events, event.ID)
+	if event.Resource != "" {
+		delete(r.resources, resource{event.UserID, event.Resource})
+	}
+
+	r.version++
+	r.tombstones[event.UserID] = append(r.tombstones[event.UserID], tombstone{event, r.version})
+	r.userVersions[event.UserID] = r.version
+}
+
 func (r *EventRepository) Create(event *model.Event) (int, error) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
@@ -32,124 +75,179 @@ func (r *EventRepository) Create(event *model.Event) (int, error) {
 	id := r.autoincrement
 	event.ID = id
 	r.events[id] = event
+	if event.Resource != "" {
+		r.resources[resource{event.UserID, event.Resource}] = id
+	}
+	r.touch(event)
 
 	r.autoincrement++
 
 	return id, nil
 }
 
-func (r *EventRepository) ListForDay(userID int, date time.Time) ([]*model.Event, error) {
+func (r *EventRepository) Get(ID int) (*model.Event, error) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
-	res := make([]*model.Event, 0)
-	next, stop := iter.Pull(maps.Values(r.events))
+	event, ok := r.events[ID]
+	if !ok {
+		return nil, ErrorEventNotFound
+	}
 
-	for {
-		event, ok := next()
-		if !ok {
-			stop()
-			break
-		}
+	return event, nil
+}
+
+// GetByResource finds an event of the user by the CalDAV resource name it was
+// created under
+func (r *EventRepository) GetByResource(userID int, name string) (*model.Event, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	id, ok := r.resources[resource{userID, name}]
+	if !ok {
+		return nil, ErrorEventNotFound
+	}
+
+	return r.events[id], nil
+}
 
+// ListForPeriod returns events of the user dated within [from, to), ordered by
+// date
+func (r *EventRepository) ListForPeriod(userID int, from, to time.Time) ([]*model.Event, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	res := make([]*model.Event, 0)
+	for _, id := range slices.Sorted(maps.Keys(r.events)) {
+		event := r.events[id]
 		if event.UserID != userID {
 			continue
 		}
 
-		if !event.Date.Equal(date) {
+		if event.Date.Before(from) || !event.Date.Before(to) {
 			continue
 		}
 
 		res = append(res, event)
 	}
 
+	slices.SortStableFunc(res, func(a, b *model.Event) int {
+		return a.Date.Compare(b.Date)
+	})
+
 	return res, nil
 }
 
-func (r *EventRepository) ListForWeek(userID int, starting time.Time) ([]*model.Event, error) {
+func (r *EventRepository) Update(ID int, update *model.EventUpdate) (*model.Event, error) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
-	res := make([]*model.Event, 0)
-	next, stop := iter.Pull(maps.Values(r.events))
+	event, ok := r.events[ID]
+	if !ok {
+		return nil, ErrorEventNotFound
+	}
 
-	ending := starting.AddDate(0, 0, 7)
+	if update.Name != "" {
+		event.Name = update.Name
+	}
 
-	for {
-		event, ok := next()
-		if !ok {
-			stop()
-			break
-		}
+	if !update.Date.IsZero() {
+		event.Date = update.Date
+	}
 
-		if event.UserID != userID {
-			continue
-		}
+	if update.CalendarID != nil {
+		event.CalendarID = *update.CalendarID
+	}
 
-		if event.Date.Compare(starting) < 0 || event.Date.Compare(ending) > 0 {
-			continue
-		}
+	r.touch(event)
 
-		res = append(res, event)
+	return event, nil
+}
+
+func (r *EventRepository) Delete(ID int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	event, ok := r.events[ID]
+	if !ok {
+		return ErrorEventNotFound
 	}
 
-	return res, nil
+	r.bury(event)
+	return nil
 }
 
-func (r *EventRepository) ListForMonth(userID int, starting time.Time) ([]*model.Event, error) {
+func (r *EventRepository) MoveCalendar(from, to int) ([]*model.Event, error) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
 	res := make([]*model.Event, 0)
-	next, stop := iter.Pull(maps.Values(r.events))
+	for _, event := range r.events {
+		if event.CalendarID != from {
+			continue
+		}
 
-	ending := starting.AddDate(0, 1, 0)
+		event.CalendarID = to
+		r.touch(event)
+		res = append(res, event)
+	}
 
-	for {
-		event, ok := next()
-		if !ok {
-			stop()
-			break
-		}
+	return res, nil
+}
 
-		if event.UserID != userID {
-			continue
-		}
+func (r *EventRepository) DeleteForCalendar(calendarID int) ([]*model.Event, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
 
-		if event.Date.Compare(starting) < 0 || event.Date.Compare(ending) > 0 {
+	res := make([]*model.Event, 0)
+	for _, event := range r.events {
+		if event.CalendarID != calendarID {
 			continue
 		}
 
+		r.bury(event)
 		res = append(res, event)
 	}
 
 	return res, nil
 }
 
-func (r *EventRepository) Update(ID int, event *model.Event) (*model.Event, error) {
-	if _, ok := r.events[ID]; !ok {
-		return nil, ErrorEventNotFound
+// Changes returns events of the user changed after the since version and ids of
+// the deleted ones, along with the current version of the user
+func (r *EventRepository) Changes(userID, since int) (*model.EventChanges, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	changes := &model.EventChanges{
+		Changed: make([]*model.Event, 0),
+		Deleted: make([]*model.Event, 0),
+		Version: r.userVersions[userID],
 	}
 
-	if event.Name != "" {
-		r.events[ID].Name = event.Name
+	for _, id := range slices.Sorted(maps.Keys(r.events)) {
+		event := r.events[id]
+		if event.UserID == userID && event.Version > since {
+			changes.Changed = append(changes.Changed, event)
+		}
 	}
 
-	if !event.Date.IsZero() {
-		r.events[ID].Date = event.Date
+	for _, tombstone := range r.tombstones[userID] {
+		if tombstone.version > since {
+			changes.Deleted = append(changes.Deleted, tombstone.event)
+		}
 	}
 
-	return r.events[ID], nil
+	return changes, nil
 }
 
-func (r *EventRepository) Delete(ID int) error {
+// Count is the number of stored events, for usage reports
+func (r *EventRepository) Count() (int, error) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
-	if _, ok := r.events[ID]; !ok {
-		return ErrorEventNotFound
-	}
+	return len(r.events), nil
+}
 
-	delete(r.events, ID)
-	return nil
+func (r *EventRepository) Ping(ctx context.Context) error {
+	return inmemory.PingLock(ctx, &r.mu)
 }
@@ -0,0 +1,92 @@
+package idempotency
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+	"wb_l2/18/internal/model"
+	"wb_l2/18/internal/repository/inmemory"
+)
+
+var ErrorIdempotencyNotFound = fmt.Errorf("Idempotency key is not found")
+
+type IdempotencyRepository struct {
+	records map[string]*model.Idempotency
+	// order keeps the claimed keys oldest first, so expired records are
+	// dropped from its front. Keys released or claimed again stay behind
+	// and are skipped
+	order []*model.Idempotency
+
+	mu sync.Mutex
+}
+
+func NewIdempotencyRepositoryInMemory() *IdempotencyRepository {
+	return &IdempotencyRepository{
+		records: make(map[string]*model.Idempotency),
+	}
+}
+
+func (r *IdempotencyRepository) Claim(record *model.Idempotency, now time.Time) (*model.Idempotency, bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.expire(now)
+
+	if existing, ok := r.records[record.Key]; ok && existing.ExpiresAt.After(now) {
+		copied := *existing
+		copied.Warnings = slices.Clone(existing.Warnings)
+		return &copied, false, nil
+	}
+
+	r.records[record.Key] = record
+	r.order = append(r.order, record)
+
+	return nil, true, nil
+}
+
+// expire drops records whose time has come, they are claimed with growing
+// expiry times as long as the TTL stays the same
+func (r *IdempotencyRepository) expire(now time.Time) {
+	for len(r.order) > 0 && !r.order[0].ExpiresAt.After(now) {
+		if r.records[r.order[0].Key] == r.order[0] {
+			delete(r.records, r.order[0].Key)
+		}
+		r.order[0] = nil
+		r.order = r.order[1:]
+	}
+}
+
+func (r *IdempotencyRepository) Complete(record *model.Idempotency, id int, warnings []string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.records[record.Key] != record {
+		return ErrorIdempotencyNotFound
+	}
+
+	record.Done = true
+	record.ID = id
+	record.Warnings = slices.Clone(warnings)
+
+	return nil
+}
+
+// Release compares records by identity, the key may have expired and been
+// claimed by a newer request in the meantime
+func (r *IdempotencyRepository) Release(record *model.Idempotency) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.records[record.Key] != record || record.Done {
+		return ErrorIdempotencyNotFound
+	}
+
+	delete(r.records, record.Key)
+	return nil
+}
+
+func (r *IdempotencyRepository) Ping(ctx context.Context) error {
+	return inmemory.PingLock(ctx, &r.mu)
+}
@@ -0,0 +1,31 @@
+// Package inmemory holds helpers shared by the in-memory repositories
+package inmemory
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// pingRetry is how often PingLock tries a busy lock again
+const pingRetry = 5 * time.Millisecond
+
+// PingLock makes sure a storage is not stuck by taking its lock, retrying until
+// ctx is done. A probe that gives up leaves nothing waiting on the lock
+func PingLock(ctx context.Context, mu *sync.Mutex) error {
+	ticker := time.NewTicker(pingRetry)
+	defer ticker.Stop()
+
+	for {
+		if mu.TryLock() {
+			mu.Unlock()
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package schedule
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"wb_l2/18/internal/model"
+	"wb_l2/18/internal/repository/inmemory"
+)
+
+var ErrorScheduleNotFound = fmt.Errorf("Schedule is not found")
+
+// ScheduleRepository keeps one schedule per user, saving replaces it
+type ScheduleRepository struct {
+	schedules map[int]*model.Schedule
+
+	mu sync.Mutex
+}
+
+func NewScheduleRepositoryInMemory() *ScheduleRepository {
+	return &ScheduleRepository{
+		schedules: make(map[int]*model.Schedule),
+	}
+}
+
+func (r *ScheduleRepository) Save(schedule *model.Schedule) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.schedules[schedule.UserID] = schedule
+	return nil
+}
+
+func (r *ScheduleRepository) Get(userID int) (*model.Schedule, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	schedule, ok := r.schedules[userID]
+	if !ok {
+		return nil, ErrorScheduleNotFound
+	}
+
+	return schedule, nil
+}
+
+func (r *ScheduleRepository) Ping(ctx context.Context) error {
+	return inmemory.PingLock(ctx, &r.mu)
+}
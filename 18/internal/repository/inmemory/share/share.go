@@ -0,0 +1,105 @@
+package share
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"wb_l2/18/internal/model"
+	"wb_l2/18/internal/repository/inmemory"
+)
+
+var ErrorShareNotFound = fmt.Errorf("Share is not found")
+
+type key struct {
+	ownerID   int
+	granteeID int
+}
+
+type ShareRepository struct {
+	shares map[key]*model.Share
+
+	mu sync.Mutex
+}
+
+func NewShareRepositoryInMemory() *ShareRepository {
+	return &ShareRepository{
+		shares: make(map[key]*model.Share),
+	}
+}
+
+// Save creates the share or replaces the permission of an existing one
+func (r *ShareRepository) Save(share *model.Share) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.shares[key{share.OwnerID, share.GranteeID}] = share
+	return nil
+}
+
+func (r *ShareRepository) Get(ownerID, granteeID int) (*model.Share, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	share, ok := r.shares[key{ownerID, granteeID}]
+	if !ok {
+		return nil, ErrorShareNotFound
+	}
+
+	return share, nil
+}
+
+func (r *ShareRepository) ListForOwner(ownerID int) ([]*model.Share, error) {
+	return r.list(func(share *model.Share) bool {
+		return share.OwnerID == ownerID
+	}), nil
+}
+
+func (r *ShareRepository) ListForGrantee(granteeID int) ([]*model.Share, error) {
+	return r.list(func(share *model.Share) bool {
+		return share.GranteeID == granteeID
+	}), nil
+}
+
+func (r *ShareRepository) Delete(ownerID, granteeID int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.shares[key{ownerID, granteeID}]; !ok {
+		return ErrorShareNotFound
+	}
+
+	delete(r.shares, key{ownerID, granteeID})
+	return nil
+}
+
+func (r *ShareRepository) list(match func(*model.Share) bool) []*model.Share {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	res := make([]*model.Share, 0)
+	for _, share := range r.shares {
+		if match(share) {
+			res = append(res, share)
+		}
+	}
+
+	slices.SortFunc(res, func(a, b *model.Share) int {
+		return cmp.Or(cmp.Compare(a.OwnerID, b.OwnerID), cmp.Compare(a.GranteeID, b.GranteeID))
+	})
+
+	return res
+}
+
+// Count is the number of stored shares, for usage reports
+func (r *ShareRepository) Count() (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return len(r.shares), nil
+}
+
+func (r *ShareRepository) Ping(ctx context.Context) error {
+	return inmemory.PingLock(ctx, &r.mu)
+}
@@ -0,0 +1,91 @@
+package tenant
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"sync"
+	"wb_l2/18/internal/model"
+	"wb_l2/18/internal/repository/inmemory"
+)
+
+var ErrorTenantNotFound = fmt.Errorf("Tenant is not found")
+
+type TenantRepository struct {
+	tenants map[string]*model.Tenant
+	hosts   map[string]*model.Tenant
+
+	mu sync.Mutex
+}
+
+func NewTenantRepositoryInMemory() *TenantRepository {
+	return &TenantRepository{
+		tenants: make(map[string]*model.Tenant),
+		hosts:   make(map[string]*model.Tenant),
+	}
+}
+
+// Create fails with model.TenantExists when the id or any of the hosts is
+// taken
+func (r *TenantRepository) Create(tenant *model.Tenant) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.tenants[tenant.ID]; ok {
+		return model.TenantExists
+	}
+	for _, host := range tenant.Hosts {
+		if _, ok := r.hosts[host]; ok {
+			return model.TenantExists
+		}
+	}
+
+	r.tenants[tenant.ID] = tenant
+	for _, host := range tenant.Hosts {
+		r.hosts[host] = tenant
+	}
+
+	return nil
+}
+
+func (r *TenantRepository) Get(id string) (*model.Tenant, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	tenant, ok := r.tenants[id]
+	if !ok {
+		return nil, ErrorTenantNotFound
+	}
+
+	return tenant, nil
+}
+
+func (r *TenantRepository) GetByHost(host string) (*model.Tenant, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	tenant, ok := r.hosts[host]
+	if !ok {
+		return nil, ErrorTenantNotFound
+	}
+
+	return tenant, nil
+}
+
+// List returns tenants ordered by id
+func (r *TenantRepository) List() ([]*model.Tenant, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	res := make([]*model.Tenant, 0, len(r.tenants))
+	for _, id := range slices.Sorted(maps.Keys(r.tenants)) {
+		res = append(res, r.tenants[id])
+	}
+
+	return res, nil
+}
+
+func (r *TenantRepository) Ping(ctx context.Context) error {
+	return inmemory.PingLock(ctx, &r.mu)
+}
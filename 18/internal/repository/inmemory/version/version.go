@@ -0,0 +1,54 @@
+package version
+
+import (
+	"context"
+	"sync"
+	"time"
+	"wb_l2/18/internal/model"
+	"wb_l2/18/internal/repository/inmemory"
+)
+
+// VersionRepository numbers changes of all users with one counter, so a
+// version is never reused for a user
+type VersionRepository struct {
+	version  int
+	versions map[int]*model.Version
+
+	mu sync.Mutex
+}
+
+func NewVersionRepositoryInMemory() *VersionRepository {
+	return &VersionRepository{
+		versions: make(map[int]*model.Version),
+	}
+}
+
+func (r *VersionRepository) Touch(at time.Time, userIDs ...int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.version++
+	for _, userID := range userIDs {
+		r.versions[userID] = &model.Version{UserID: userID, Version: r.version, ModifiedAt: at}
+	}
+
+	return nil
+}
+
+// Get returns version 0 for users that never changed
+func (r *VersionRepository) Get(userID int) (*model.Version, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	version, ok := r.versions[userID]
+	if !ok {
+		return &model.Version{UserID: userID}, nil
+	}
+
+	copied := *version
+	return &copied, nil
+}
+
+func (r *VersionRepository) Ping(ctx context.Context) error {
+	return inmemory.PingLock(ctx, &r.mu)
+}
@@ -0,0 +1,148 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"sync"
+	"wb_l2/18/internal/model"
+	"wb_l2/18/internal/repository/inmemory"
+)
+
+var (
+	ErrorWebhookNotFound  = fmt.Errorf("Webhook is not found")
+	ErrorDeliveryNotFound = fmt.Errorf("Delivery is not found")
+)
+
+type WebhookRepository struct {
+	webhooks      map[int]*model.Webhook
+	autoincrement int
+
+	deliveries            map[int]*model.Delivery
+	deliveryAutoincrement int
+
+	mu sync.Mutex
+}
+
+func NewWebhookRepositoryInMemory() *WebhookRepository {
+	return &WebhookRepository{
+		webhooks:              make(map[int]*model.Webhook),
+		autoincrement:         1,
+		deliveries:            make(map[int]*model.Delivery),
+		deliveryAutoincrement: 1,
+	}
+}
+
+func (r *WebhookRepository) Create(webhook *model.Webhook) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	id := r.autoincrement
+	webhook.ID = id
+	r.webhooks[id] = webhook
+
+	r.autoincrement++
+
+	return id, nil
+}
+
+func (r *WebhookRepository) Get(ID int) (*model.Webhook, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	webhook, ok := r.webhooks[ID]
+	if !ok {
+		return nil, ErrorWebhookNotFound
+	}
+
+	return webhook, nil
+}
+
+func (r *WebhookRepository) ListForUser(userID int) ([]*model.Webhook, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	res := make([]*model.Webhook, 0)
+	for _, id := range slices.Sorted(maps.Keys(r.webhooks)) {
+		if r.webhooks[id].UserID != userID {
+			continue
+		}
+
+		res = append(res, r.webhooks[id])
+	}
+
+	return res, nil
+}
+
+func (r *WebhookRepository) Delete(ID int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.webhooks[ID]; !ok {
+		return ErrorWebhookNotFound
+	}
+
+	delete(r.webhooks, ID)
+	return nil
+}
+
+// Deliveries are mutated by the delivery workers concurrently with reads from
+// the API, so the repository stores and hands out copies only
+func (r *WebhookRepository) CreateDelivery(delivery *model.Delivery) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	id := r.deliveryAutoincrement
+	delivery.ID = id
+	r.deliveries[id] = delivery.Clone()
+
+	r.deliveryAutoincrement++
+
+	return id, nil
+}
+
+func (r *WebhookRepository) UpdateDelivery(delivery *model.Delivery) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.deliveries[delivery.ID]; !ok {
+		return ErrorDeliveryNotFound
+	}
+
+	r.deliveries[delivery.ID] = delivery.Clone()
+	return nil
+}
+
+func (r *WebhookRepository) ListDeliveries(userID int, status model.DeliveryStatus) ([]*model.Delivery, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	res := make([]*model.Delivery, 0)
+	for _, id := range slices.Sorted(maps.Keys(r.deliveries)) {
+		delivery := r.deliveries[id]
+		if delivery.UserID != userID {
+			continue
+		}
+
+		if status != "" && delivery.Status != status {
+			continue
+		}
+
+		res = append(res, delivery.Clone())
+	}
+
+	return res, nil
+}
+
+// Count is the number of stored webhooks, for usage reports
+func (r *WebhookRepository) Count() (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return len(r.webhooks), nil
+}
+
+func (r *WebhookRepository) Ping(ctx context.Context) error {
+	return inmemory.PingLock(ctx, &r.mu)
+}
@@ -1,8 +1,16 @@
 package repository
 
 import (
+	"context"
 	"fmt"
-	inmemory "wb_l2/18/internal/repository/inmemory/event"
+	"wb_l2/18/internal/repository/inmemory/calendar"
+	"wb_l2/18/internal/repository/inmemory/event"
+	"wb_l2/18/internal/repository/inmemory/idempotency"
+	"wb_l2/18/internal/repository/inmemory/schedule"
+	"wb_l2/18/internal/repository/inmemory/share"
+	"wb_l2/18/internal/repository/inmemory/tenant"
+	"wb_l2/18/internal/repository/inmemory/version"
+	"wb_l2/18/internal/repository/inmemory/webhook"
 )
 
 type StorageType int
@@ -20,14 +28,65 @@ func (st StorageType) String() string {
 }
 
 type Repository struct {
-	Event eventRepository
+	Event    eventRepository
+	Calendar calendarRepository
+	Share    shareRepository
+	Webhook  webhookRepository
+	Schedule scheduleRepository
+	Version  versionRepository
+
+	Idempotency idempotencyRepository
+
+	// Tenant is the registry of tenants, kept in the repository of the
+	// default tenant
+	Tenant tenantRepository
+}
+
+// pinger is embedded by every storage interface, so readiness checks reach
+// all of them
+type pinger interface {
+	Ping(ctx context.Context) error
+}
+
+// Ping checks that every storage answers before ctx is done
+func (r *Repository) Ping(ctx context.Context) error {
+	storages := []struct {
+		name    string
+		storage pinger
+	}{
+		{"event", r.Event},
+		{"calendar", r.Calendar},
+		{"share", r.Share},
+		{"webhook", r.Webhook},
+		{"schedule", r.Schedule},
+		{"version", r.Version},
+		{"idempotency", r.Idempotency},
+		{"tenant", r.Tenant},
+	}
+
+	for _, s := range storages {
+		if err := s.storage.Ping(ctx); err != nil {
+			return fmt.Errorf("%s storage: %w", s.name, err)
+		}
+	}
+
+	return nil
 }
 
 func NewRepository(storageType StorageType) *Repository {
 	switch storageType {
 	case InMemory:
 		return &Repository{
-			Event: inmemory.NewEventRepositoryInMemory(),
+			Event:    event.NewEventRepositoryInMemory(),
+			Calendar: calendar.NewCalendarRepositoryInMemory(),
+			Share:    share.NewShareRepositoryInMemory(),
+			Webhook:  webhook.NewWebhookRepositoryInMemory(),
+			Schedule: schedule.NewScheduleRepositoryInMemory(),
+			Version:  version.NewVersionRepositoryInMemory(),
+
+			Idempotency: idempotency.NewIdempotencyRepositoryInMemory(),
+
+			Tenant: tenant.NewTenantRepositoryInMemory(),
 		}
 	default:
 		panic(fmt.Errorf("Unknown repository storage type: %s", storageType))
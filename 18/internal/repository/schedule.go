@@ -0,0 +1,10 @@
+package repository
+
+import "wb_l2/18/internal/model"
+
+type scheduleRepository interface {
+	pinger
+
+	Save(schedule *model.Schedule) error
+	Get(userID int) (*model.Schedule, error)
+}
@@ -0,0 +1,14 @@
+package repository
+
+import "wb_l2/18/internal/model"
+
+type shareRepository interface {
+	pinger
+
+	Save(share *model.Share) error
+	Get(ownerID, granteeID int) (*model.Share, error)
+	ListForOwner(ownerID int) ([]*model.Share, error)
+	ListForGrantee(granteeID int) ([]*model.Share, error)
+	Delete(ownerID, granteeID int) error
+	Count() (int, error)
+}
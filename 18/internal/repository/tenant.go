@@ -0,0 +1,12 @@
+package repository
+
+import "wb_l2/18/internal/model"
+
+type tenantRepository interface {
+	pinger
+
+	Create(tenant *model.Tenant) error
+	Get(id string) (*model.Tenant, error)
+	GetByHost(host string) (*model.Tenant, error)
+	List() ([]*model.Tenant, error)
+}
@@ -0,0 +1,14 @@
+package repository
+
+import (
+	"time"
+	"wb_l2/18/internal/model"
+)
+
+type versionRepository interface {
+	pinger
+
+	// Touch moves every user to a new version modified at the time
+	Touch(at time.Time, userIDs ...int) error
+	Get(userID int) (*model.Version, error)
+}
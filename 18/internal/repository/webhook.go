@@ -0,0 +1,17 @@
+package repository
+
+import "wb_l2/18/internal/model"
+
+type webhookRepository interface {
+	pinger
+
+	Create(webhook *model.Webhook) (int, error)
+	Get(ID int) (*model.Webhook, error)
+	ListForUser(userID int) ([]*model.Webhook, error)
+	Delete(ID int) error
+	Count() (int, error)
+
+	CreateDelivery(delivery *model.Delivery) (int, error)
+	UpdateDelivery(delivery *model.Delivery) error
+	ListDeliveries(userID int, status model.DeliveryStatus) ([]*model.Delivery, error)
+}
@@ -0,0 +1,315 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	htmltemplate "html/template"
+	"log/slog"
+	"net/smtp"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+	"wb_l2/18/internal/config"
+	"wb_l2/18/internal/model"
+	"wb_l2/18/pkg/date"
+)
+
+type AgendaService struct {
+	event        *EventService
+	availability *AvailabilityService
+	config       config.AgendaConfig
+
+	workers sync.WaitGroup
+}
+
+func NewAgendaService(event *EventService, availability *AvailabilityService, config config.AgendaConfig) *AgendaService {
+	return &AgendaService{
+		event:        event,
+		availability: availability,
+		config:       config,
+	}
+}
+
+// Agenda builds the digest asked for in the query: user_id, range (day by
+// default) and format (text by default)
+func (s *AgendaService) Agenda(query url.Values) ([]byte, model.AgendaFormat, error) {
+	verr := model.NewValidationError(InvalidQuery)
+	userID := queryUserID(query, verr)
+
+	agendaRange := model.AgendaRange(query.Get("range"))
+	if agendaRange == "" {
+		agendaRange = model.AgendaDay
+	} else if !slices.Contains(model.AgendaRanges, agendaRange) {
+		verr.Add("range", model.CodeUnknownValue, `range must be "day" or "week"`)
+	}
+
+	format := model.AgendaFormat(query.Get("format"))
+	if format == "" {
+		format = model.AgendaText
+	} else if !slices.Contains(model.AgendaFormats, format) {
+		verr.Add("format", model.CodeUnknownValue, `format must be "text", "md" or "html"`)
+	}
+
+	if err := verr.Err(); err != nil {
+		return nil, "", err
+	}
+
+	agenda, err := s.build(userID, agendaRange, time.Now())
+	if err != nil {
+		return nil, "", err
+	}
+
+	body, err := Render(agenda, format)
+	return body, format, err
+}
+
+// build collects the events of the day or the 7 days after now, "tomorrow"
+// is taken in the user's time zone
+func (s *AgendaService) build(userID int, agendaRange model.AgendaRange, now time.Time) (*model.Agenda, error) {
+	schedule, err := s.availability.scheduleOf(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	local := now.In(schedule.Location)
+	from := time.Date(local.Year(), local.Month(), local.Day()+1, 0, 0, 0, 0, time.UTC)
+
+	by, to := Day, from
+	if agendaRange == model.AgendaWeek {
+		by, to = Week, from.AddDate(0, 0, 6)
+	}
+
+	events, err := s.event.List(url.Values{
+		"user_id": {strconv.Itoa(userID)},
+		"date":    {date.StringFromTime(from)},
+	}, by)
+	if err != nil {
+		return nil, err
+	}
+
+	// shared events come after the user's own ones
+	slices.SortStableFunc(events, func(a, b *model.EventOut) int {
+		return strings.Compare(a.Date, b.Date)
+	})
+
+	agenda := &model.Agenda{UserID: userID, Range: agendaRange, From: from, To: to, Days: []*model.DayAgenda{}}
+	for _, event := range events {
+		if n := len(agenda.Days); n > 0 && date.StringFromTime(agenda.Days[n-1].Date) == event.Date {
+			agenda.Days[n-1].Events = append(agenda.Days[n-1].Events, event)
+			continue
+		}
+
+		day, err := date.TimeFromString(event.Date)
+		if err != nil {
+			return nil, err
+		}
+		agenda.Days = append(agenda.Days, &model.DayAgenda{Date: day, Events: []*model.EventOut{event}})
+	}
+
+	return agenda, nil
+}
+
+var agendaFuncs = map[string]any{
+	"day":    func(t time.Time) string { return t.Format("Monday, 2 January 2006") },
+	"short":  func(t time.Time) string { return t.Format("Mon 2 Jan 2006") },
+	"md":     markdownEscaper.Replace,
+	"single": func(a *model.Agenda) bool { return a.From.Equal(a.To) },
+}
+
+// markdownEscaper keeps event names from turning into markup
+var markdownEscaper = strings.NewReplacer(
+	`\`, `\\`, "*", `\*`, "_", `\_`, "`", "\\`", "[", `\[`, "]", `\]`,
+	"#", `\#`, "<", `\<`, ">", `\>`, "|", `\|`,
+)
+
+var (
+	textAgenda = template.Must(template.New("text").Funcs(agendaFuncs).Parse(
+		`Agenda of user {{.UserID}} for {{if single .}}{{short .From}}{{else}}{{short .From}} - {{short .To}}{{end}}
+{{range .Days}}
+{{day .Date}}
+{{range .Events}}  - {{.Name}}{{if ne .UserID $.UserID}} (shared by user {{.UserID}}){{end}}
+{{end}}{{else}}
+No events.
+{{end}}`))
+
+	markdownAgenda = template.Must(template.New("md").Funcs(agendaFuncs).Parse(
+		`# Agenda of user {{.UserID}}
+
+_{{if single .}}{{short .From}}{{else}}{{short .From}} – {{short .To}}{{end}}_
+{{range .Days}}
+## {{day .Date}}
+
+{{range .Events}}- {{md .Name}}{{if ne .UserID $.UserID}} _(shared by user {{.UserID}})_{{end}}
+{{end}}{{else}}
+No events.
+{{end}}`))
+
+	htmlAgenda = htmltemplate.Must(htmltemplate.New("html").Funcs(agendaFuncs).Parse(
+		`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Agenda of user {{.UserID}}</title>
+</head>
+<body>
+<h1>Agenda of user {{.UserID}}</h1>
+<p><em>{{if single .}}{{short .From}}{{else}}{{short .From}} – {{short .To}}{{end}}</em></p>
+{{range .Days}}<h2>{{day .Date}}</h2>
+<ul>
+{{range .Events}}<li>{{.Name}}{{if ne .UserID $.UserID}} <small>(shared by user {{.UserID}})</small>{{end}}</li>
+{{end}}</ul>
+{{else}}<p>No events.</p>
+{{end}}</body>
+</html>
+`))
+)
+
+// Render writes the agenda out in the format
+func Render(agenda *model.Agenda, format model.AgendaFormat) ([]byte, error) {
+	var buf bytes.Buffer
+
+	var err error
+	switch format {
+	case model.AgendaMarkdown:
+		err = markdownAgenda.Execute(&buf, agenda)
+	case model.AgendaHTML:
+		err = htmlAgenda.Execute(&buf, agenda)
+	default:
+		err = textAgenda.Execute(&buf, agenda)
+	}
+
+	return buf.Bytes(), err
+}
+
+// Start runs the digest job until ctx is done, when it is configured. Wait
+// blocks until it exits
+func (s *AgendaService) Start(ctx context.Context) {
+	if len(s.config.Users) == 0 || (s.config.OutputDir == "" && s.config.SMTP.Addr == "") {
+		return
+	}
+
+	if !slices.Contains(model.AgendaRanges, model.AgendaRange(s.config.Range)) || !slices.Contains(model.AgendaFormats, model.AgendaFormat(s.config.Format)) {
+		slog.Error("Agenda job is not started: unknown range or format", "range", s.config.Range, "format", s.config.Format)
+		return
+	}
+
+	first, err := nextRun(time.Now(), s.config.At)
+	if err != nil {
+		slog.Error("Agenda job is not started: " + err.Error())
+		return
+	}
+
+	s.workers.Add(1)
+	go func() {
+		defer s.workers.Done()
+
+		timer := time.NewTimer(time.Until(first))
+		defer timer.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-timer.C:
+				// failures are logged per user by Deliver
+				s.Deliver(ctx)
+				timer.Reset(max(s.config.Interval, time.Minute))
+			}
+		}
+	}()
+}
+
+func (s *AgendaService) Wait() {
+	s.workers.Wait()
+}
+
+// Deliver builds the configured digest for every configured user and writes
+// it to the output directory and/or mails it. It goes on after a failing
+// user and returns the first error
+func (s *AgendaService) Deliver(ctx context.Context) error {
+	agendaRange := model.AgendaRange(s.config.Range)
+	format := model.AgendaFormat(s.config.Format)
+
+	var first error
+	for _, userID := range s.config.Users {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		if err := s.deliver(userID, agendaRange, format); err != nil {
+			slog.Error("Unable to deliver agenda", "user_id", userID, "error", err)
+			if first == nil {
+				first = err
+			}
+		}
+	}
+
+	return first
+}
+
+func (s *AgendaService) deliver(userID int, agendaRange model.AgendaRange, format model.AgendaFormat) error {
+	agenda, err := s.build(userID, agendaRange, time.Now())
+	if err != nil {
+		return err
+	}
+
+	body, err := Render(agenda, format)
+	if err != nil {
+		return err
+	}
+
+	if s.config.OutputDir != "" {
+		name := fmt.Sprintf("agenda-%d-%s-%s.%s", userID, agendaRange, date.StringFromTime(agenda.From), format.Extension())
+		if err := os.MkdirAll(s.config.OutputDir, 0o755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(filepath.Join(s.config.OutputDir, name), body, 0o644); err != nil {
+			return err
+		}
+	}
+
+	if s.config.SMTP.Addr != "" {
+		return s.mail(userID, agenda, format, body)
+	}
+
+	return nil
+}
+
+// mail sends the digest to a local SMTP server, like MailHog, without
+// authentication
+func (s *AgendaService) mail(userID int, agenda *model.Agenda, format model.AgendaFormat, body []byte) error {
+	to := fmt.Sprintf("user%d@%s", userID, s.config.SMTP.Domain)
+
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", s.config.SMTP.From)
+	fmt.Fprintf(&msg, "To: %s\r\n", to)
+	fmt.Fprintf(&msg, "Subject: Agenda for the %s from %s\r\n", agenda.Range, date.StringFromTime(agenda.From))
+	fmt.Fprintf(&msg, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	fmt.Fprintf(&msg, "MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&msg, "Content-Type: %s\r\n\r\n", format.ContentType())
+	msg.Write(bytes.ReplaceAll(body, []byte("\n"), []byte("\r\n")))
+
+	return smtp.SendMail(s.config.SMTP.Addr, nil, s.config.SMTP.From, []string{to}, msg.Bytes())
+}
+
+// nextRun is the next time of day at HH:MM local time after now
+func nextRun(now time.Time, at string) (time.Time, error) {
+	clock, err := time.Parse("15:04", at)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("at must be a time like 07:00, got %q", at)
+	}
+
+	next := time.Date(now.Year(), now.Month(), now.Day(), clock.Hour(), clock.Minute(), 0, 0, now.Location())
+	if !next.After(now) {
+		next = next.AddDate(0, 0, 1)
+	}
+
+	return next, nil
+}
@@ -0,0 +1,203 @@
+package service
+
+import (
+	"fmt"
+	"maps"
+	"net/url"
+	"slices"
+	"time"
+	"wb_l2/18/internal/model"
+	"wb_l2/18/internal/repository"
+	"wb_l2/18/internal/repository/inmemory/schedule"
+	"wb_l2/18/pkg/date"
+)
+
+// maxAvailabilityDays bounds the from-to range of an availability request
+const maxAvailabilityDays = 366
+
+type AvailabilityService struct {
+	repo *repository.Repository
+
+	// holidays by calendar name, loaded once on start
+	holidays map[string][]model.Holiday
+}
+
+func NewAvailabilityService(repo *repository.Repository) *AvailabilityService {
+	return &AvailabilityService{
+		repo:     repo,
+		holidays: make(map[string][]model.Holiday),
+	}
+}
+
+// LoadHolidayCalendars reads the holiday calendars users can refer to by name
+func (s *AvailabilityService) LoadHolidayCalendars(paths map[string]string) error {
+	for name, path := range paths {
+		holidays, err := loadHolidays(path)
+		if err != nil {
+			return fmt.Errorf("holiday calendar %s: %w", name, err)
+		}
+
+		s.holidays[name] = holidays
+	}
+
+	return nil
+}
+
+// HolidayCalendars lists the names of the loaded calendars
+func (s *AvailabilityService) HolidayCalendars() []string {
+	return slices.Sorted(maps.Keys(s.holidays))
+}
+
+func (s *AvailabilityService) SetSchedule(body []byte) (*model.ScheduleOut, error) {
+	schedule, err := model.ScheduleFromBody(body)
+	if err != nil {
+		return nil, err
+	}
+
+	verr := model.NewValidationError(model.InvalidFormat)
+	for i, name := range schedule.HolidayCalendars {
+		if _, ok := s.holidays[name]; !ok {
+			verr.Add(fmt.Sprintf("holiday_calendars[%d]", i), model.CodeUnknownValue, fmt.Sprintf("holiday calendar must be one of %v", s.HolidayCalendars()))
+		}
+	}
+	if err := verr.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.Schedule.Save(schedule); err != nil {
+		return nil, err
+	}
+
+	return schedule.Format(), nil
+}
+
+func (s *AvailabilityService) Schedule(query url.Values) (*model.ScheduleOut, error) {
+	userID, err := userIDFromQuery(query)
+	if err != nil {
+		return nil, err
+	}
+
+	schedule, err := s.scheduleOf(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return schedule.Format(), nil
+}
+
+// scheduleOf falls back to the default schedule for users without one
+func (s *AvailabilityService) scheduleOf(userID int) (*model.Schedule, error) {
+	found, err := s.repo.Schedule.Get(userID)
+	if err == schedule.ErrorScheduleNotFound {
+		return model.DefaultSchedule(userID), nil
+	}
+
+	return found, err
+}
+
+// holiday returns the name of the holiday on the day from any of the user's
+// calendars, ok is false on usual days
+func (s *AvailabilityService) holiday(schedule *model.Schedule, day time.Time) (string, bool) {
+	for _, name := range schedule.HolidayCalendars {
+		for _, holiday := range s.holidays[name] {
+			if holiday.On(day) {
+				return holiday.Name, true
+			}
+		}
+	}
+
+	return "", false
+}
+
+// Availability lays out every day from from to to, both inclusive, with the
+// user's working hours, holidays and own events
+func (s *AvailabilityService) Availability(query url.Values) ([]*model.DayAvailabilityOut, error) {
+	verr := model.NewValidationError(InvalidQuery)
+	userID := queryUserID(query, verr)
+	from := queryDate(query, "from", verr)
+	to := queryDate(query, "to", verr)
+	if err := verr.Err(); err != nil {
+		return []*model.DayAvailabilityOut{}, err
+	}
+
+	switch {
+	case to.Before(from):
+		verr.Add("to", model.CodeTooSmall, "to must not be before from")
+	case to.Sub(from) >= maxAvailabilityDays*24*time.Hour:
+		verr.Add("to", model.CodeTooLong, fmt.Sprintf("the range must be at most %d days long", maxAvailabilityDays))
+	}
+	if err := verr.Err(); err != nil {
+		return []*model.DayAvailabilityOut{}, err
+	}
+
+	schedule, err := s.scheduleOf(userID)
+	if err != nil {
+		return []*model.DayAvailabilityOut{}, err
+	}
+
+	events, err := s.repo.Event.ListForPeriod(userID, from, to.AddDate(0, 0, 1))
+	if err != nil {
+		return []*model.DayAvailabilityOut{}, err
+	}
+
+	days := make([]*model.DayAvailabilityOut, 0)
+	for day := from; !day.After(to); day = day.AddDate(0, 0, 1) {
+		out := &model.DayAvailabilityOut{
+			Date:    date.StringFromTime(day),
+			Working: schedule.Works(day),
+			Hours:   []model.IntervalOut{},
+			Events:  []*model.EventOut{},
+		}
+
+		if name, ok := s.holiday(schedule, day); ok {
+			out.Working, out.Holiday = false, name
+		}
+
+		if out.Working {
+			out.Hours = schedule.Hours(day)
+		}
+
+		// events are sorted by date
+		for len(events) > 0 && events[0].Date.Equal(day) {
+			out.Events = append(out.Events, events[0].FormatDate())
+			events = events[1:]
+		}
+
+		out.Free = out.Working && len(out.Events) == 0
+		days = append(days, out)
+	}
+
+	return days, nil
+}
+
+// check applies the owner's outside hours policy to a new event. It returns
+// a warning for the warn policy and a ValidationError based on
+// model.OutsideWorkingHours for the reject one
+func (s *AvailabilityService) check(event *model.Event) ([]string, error) {
+	schedule, err := s.scheduleOf(event.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	if schedule.OutsideHours == model.OutsideHoursAllow {
+		return nil, nil
+	}
+
+	reason := ""
+	if name, ok := s.holiday(schedule, event.Date); ok {
+		reason = fmt.Sprintf("%s is a holiday: %s", date.StringFromTime(event.Date), name)
+	} else if !schedule.Works(event.Date) {
+		reason = fmt.Sprintf("%s is not a working day", date.StringFromTime(event.Date))
+	}
+
+	switch {
+	case reason == "":
+		return nil, nil
+	case schedule.OutsideHours == model.OutsideHoursWarn:
+		return []string{reason}, nil
+	default:
+		verr := model.NewValidationError(model.OutsideWorkingHours)
+		verr.Add("date", model.CodeOutsideHours, reason)
+		return nil, verr
+	}
+}
@@ -0,0 +1,78 @@
+package service
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"testing"
+	"wb_l2/18/internal/config"
+	"wb_l2/18/internal/repository"
+)
+
+const benchUsers = 100
+
+// benchEvents returns services with a year of events for every user, user 1
+// also sees the events shared by user 2
+func benchEvents(b *testing.B, perUser int) *Service {
+	s := NewService(repository.NewRepository(repository.InMemory), config.Default())
+	for i := range perUser * benchUsers {
+		body := fmt.Sprintf(`{"name": "Event", "date": "2024-%02d-%02d", "user_id": %d}`, 1+i%12, 1+i%28, 1+i%benchUsers)
+		if _, _, err := s.Event.Create([]byte(body), ""); err != nil {
+			b.Fatal(err)
+		}
+	}
+
+	if _, err := s.Share.Share([]byte(`{"owner_id": 2, "grantee_id": 1, "permission": "read"}`)); err != nil {
+		b.Fatal(err)
+	}
+
+	return s
+}
+
+func BenchmarkEventService_Create(b *testing.B) {
+	s := NewService(repository.NewRepository(repository.InMemory), config.Default())
+	body := []byte(`{"name": "Event", "date": "2024-01-15", "user_id": 1}`)
+
+	for b.Loop() {
+		if _, _, err := s.Event.Create(body, ""); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkEventService_CreateIdempotent(b *testing.B) {
+	s := NewService(repository.NewRepository(repository.InMemory), config.Default())
+	body := []byte(`{"name": "Event", "date": "2024-01-15", "user_id": 1}`)
+
+	for i := 0; b.Loop(); i++ {
+		if _, _, err := s.Event.Create(body, "key-"+strconv.Itoa(i)); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkEventService_List(b *testing.B) {
+	s := benchEvents(b, 100)
+
+	for _, by := range []ListFor{Day, Week, Month} {
+		b.Run(by.String(), func(b *testing.B) {
+			query := url.Values{"user_id": {"1"}, "date": {"2024-03-01"}}
+			for b.Loop() {
+				if _, err := s.Event.List(query, by); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkEventService_ListVersion(b *testing.B) {
+	s := benchEvents(b, 100)
+	query := url.Values{"user_id": {"1"}, "date": {"2024-03-01"}}
+
+	for b.Loop() {
+		if _, err := s.Event.ListVersion(query); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
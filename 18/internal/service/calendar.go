@@ -0,0 +1,194 @@
+package service
+
+import (
+	"net/url"
+	"wb_l2/18/internal/model"
+	"wb_l2/18/internal/repository"
+	"wb_l2/18/internal/repository/inmemory/calendar"
+)
+
+type CalendarService struct {
+	repo     *repository.Repository
+	notifier notifier
+	versions versions
+}
+
+func NewCalendarService(repo *repository.Repository, notifier notifier, versions versions) *CalendarService {
+	return &CalendarService{
+		repo:     repo,
+		notifier: notifier,
+		versions: versions,
+	}
+}
+
+func (s *CalendarService) Create(body []byte) (*model.CalendarOut, error) {
+	calendar, err := model.CalendarFromBody(body)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := s.repo.Calendar.Create(calendar); err != nil {
+		return nil, err
+	}
+	s.versions.touch(calendar.UserID)
+
+	return calendar.Format(), nil
+}
+
+func (s *CalendarService) List(query url.Values) ([]*model.CalendarOut, error) {
+	userID, err := userIDFromQuery(query)
+	if err != nil {
+		return []*model.CalendarOut{}, err
+	}
+
+	calendars, err := s.repo.Calendar.ListForUser(userID)
+
+	prettify := make([]*model.CalendarOut, 0, len(calendars))
+	for _, calendar := range calendars {
+		prettify = append(prettify, calendar.Format())
+	}
+
+	return prettify, err
+}
+
+// Update renames or hides a calendar on behalf of its owner
+func (s *CalendarService) Update(body []byte) (*model.CalendarOut, error) {
+	id, update, err := model.CalendarUpdateFromBody(body)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := s.owned(id, body); err != nil {
+		return nil, err
+	}
+
+	calendar, err := s.repo.Calendar.Update(id, update)
+	if err != nil {
+		return nil, err
+	}
+	// hiding a calendar changes the lists of its owner
+	s.versions.touch(calendar.UserID)
+
+	return calendar.Format(), nil
+}
+
+// Delete removes the calendar on behalf of its owner and, depending on the
+// requested mode, deletes its events or moves them to another calendar of the
+// same user
+func (s *CalendarService) Delete(body []byte) error {
+	calendarDelete, err := model.CalendarDeleteFromBody(body)
+	if err != nil {
+		return err
+	}
+
+	deleted, err := s.owned(calendarDelete.ID, body)
+	if err != nil {
+		return err
+	}
+
+	if calendarDelete.MoveTo != 0 {
+		target, err := s.repo.Calendar.Get(calendarDelete.MoveTo)
+		if err == calendar.ErrorCalendarNotFound || (err == nil && target.UserID != deleted.UserID) || calendarDelete.MoveTo == deleted.ID {
+			verr := model.NewValidationError(model.InvalidFormat)
+			verr.Add("move_to", model.CodeUnknownValue, "move_to must be another calendar of the same user")
+			return verr
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := s.repo.Calendar.Delete(deleted.ID); err != nil {
+		return err
+	}
+	s.versions.touch(deleted.UserID)
+
+	switch calendarDelete.Events {
+	case model.CalendarEventsDelete:
+		events, err := s.repo.Event.DeleteForCalendar(deleted.ID)
+		if err != nil {
+			return err
+		}
+
+		for _, event := range events {
+			s.notifier.Notify(model.EventDeleted, event)
+		}
+	case model.CalendarEventsMove:
+		events, err := s.repo.Event.MoveCalendar(deleted.ID, calendarDelete.MoveTo)
+		if err != nil {
+			return err
+		}
+
+		for _, event := range events {
+			s.notifier.Notify(model.EventUpdated, event)
+		}
+	}
+
+	return nil
+}
+
+// calendarOf checks that the calendar exists and belongs to the user, the
+// default calendar 0 always does
+func calendarOf(repo *repository.Repository, userID, calendarID int) error {
+	if calendarID == 0 {
+		return nil
+	}
+
+	found, err := repo.Calendar.Get(calendarID)
+	if err != nil && err != calendar.ErrorCalendarNotFound {
+		return err
+	}
+
+	if err != nil || found.UserID != userID {
+		verr := model.NewValidationError(model.InvalidFormat)
+		verr.Add("calendar_id", model.CodeUnknownValue, "calendar_id must be a calendar of the event owner")
+		return verr
+	}
+
+	return nil
+}
+
+// visibleFilter keeps events of the selected calendars, or of every calendar
+// that is not hidden when nothing is selected
+func visibleFilter(repo *repository.Repository, userID int, selected map[int]bool) (func(*model.Event) bool, error) {
+	if selected != nil {
+		return func(event *model.Event) bool {
+			return selected[event.CalendarID]
+		}, nil
+	}
+
+	calendars, err := repo.Calendar.ListForUser(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	hidden := make(map[int]bool)
+	for _, calendar := range calendars {
+		if !calendar.Visible {
+			hidden[calendar.ID] = true
+		}
+	}
+
+	return func(event *model.Event) bool {
+		return !hidden[event.CalendarID]
+	}, nil
+}
+
+// owned returns the calendar if the user_id of the body is its owner
+func (s *CalendarService) owned(id int, body []byte) (*model.Calendar, error) {
+	actorID, err := model.ActorFromBody(body)
+	if err != nil {
+		return nil, err
+	}
+
+	calendar, err := s.repo.Calendar.Get(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if calendar.UserID != actorID {
+		return nil, Forbidden
+	}
+
+	return calendar, nil
+}
@@ -1,33 +1,69 @@
 package service
 
 import (
-	"encoding/json"
 	"fmt"
 	"net/url"
-	"strconv"
+	"slices"
 	"time"
+	"wb_l2/18/internal/config"
 	"wb_l2/18/internal/model"
 	"wb_l2/18/internal/repository"
 	"wb_l2/18/pkg/date"
 )
 
 type EventService struct {
-	repo *repository.Repository
+	repo         *repository.Repository
+	notifier     notifier
+	availability *AvailabilityService
+	idempotency  config.IdempotencyConfig
 }
 
-func NewEventService(repo *repository.Repository) *EventService {
+func NewEventService(repo *repository.Repository, notifier notifier, availability *AvailabilityService, idempotency config.IdempotencyConfig) *EventService {
 	return &EventService{
-		repo: repo,
+		repo:         repo,
+		notifier:     notifier,
+		availability: availability,
+		idempotency:  idempotency,
 	}
 }
 
-func (s *EventService) Create(body []byte) (int, error) {
+// Create stores the event of the body. With an idempotency key, the first
+// response is given back to requests of the same user repeating the key until
+// it expires
+func (s *EventService) Create(body []byte, idempotencyKey string) (int, []string, error) {
 	event, err := model.EventFromBody(body)
 	if err != nil {
-		return 0, err
+		return 0, nil, err
 	}
 
-	return s.repo.Event.Create(event)
+	if idempotencyKey != "" {
+		return s.createOnce(event, body, idempotencyKey)
+	}
+
+	return s.CreateEvent(event)
+}
+
+// CreateEvent stores an already validated event, for callers that don't speak
+// JSON like the CalDAV interface. Events on days off of the owner come with
+// warnings or are rejected depending on the owner's schedule
+func (s *EventService) CreateEvent(event *model.Event) (int, []string, error) {
+	if err := calendarOf(s.repo, event.UserID, event.CalendarID); err != nil {
+		return 0, nil, err
+	}
+
+	warnings, err := s.availability.check(event)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	id, err := s.repo.Event.Create(event)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	s.notifier.Notify(model.EventCreated, event)
+
+	return id, warnings, nil
 }
 
 type ListFor int
@@ -48,70 +84,138 @@ func (st ListFor) String() string {
 	return listForName[st]
 }
 
-func (s *EventService) List(query url.Values, by ListFor) ([]*model.EventOut, error) {
-	userIDStr := query.Get("user_id")
-	if userIDStr == "" {
-		return []*model.EventOut{}, InvalidQuery
-	}
-
-	userID, err := strconv.Atoi(userIDStr)
-	if err != nil {
-		return []*model.EventOut{}, InvalidQuery
-	}
-
-	dateStr := query.Get("date")
-	if dateStr == "" {
-		return []*model.EventOut{}, InvalidQuery
-	}
+// Mode sets how week and month windows are laid out around the date
+type Mode string
 
-	time, err := date.TimeFromString(dateStr)
-	if err != nil {
-		return []*model.EventOut{}, InvalidQuery
-	}
+const (
+	// The window starts at the date: 7 days for a week, up to the same day of
+	// the next month for a month
+	ModeRolling Mode = "rolling"
+	// The window is the week (starting on week_start, Monday by default as in
+	// ISO 8601) or the calendar month containing the date
+	ModeCalendar Mode = "calendar"
+)
 
-	var res []*model.Event
+var modes = []Mode{ModeRolling, ModeCalendar}
 
+// window returns the half-open [from, to) period to list
+func window(by ListFor, day time.Time, mode Mode, weekStart time.Weekday) (time.Time, time.Time) {
 	switch by {
 	case Day:
-		res, err = s.repo.Event.ListForDay(userID, time)
+		return day, day.AddDate(0, 0, 1)
 	case Week:
-		res, err = s.repo.Event.ListForWeek(userID, time)
+		if mode == ModeCalendar {
+			day = day.AddDate(0, 0, -((int(day.Weekday()) - int(weekStart) + 7) % 7))
+		}
+		return day, day.AddDate(0, 0, 7)
 	case Month:
-		res, err = s.repo.Event.ListForMonth(userID, time)
+		if mode == ModeCalendar {
+			day = time.Date(day.Year(), day.Month(), 1, 0, 0, 0, 0, day.Location())
+		}
+		return day, day.AddDate(0, 1, 0)
 	default:
-		panic(fmt.Errorf("Unknown event list type: %s", listForName))
+		panic(fmt.Errorf("Unknown event list type: %s", by))
 	}
+}
 
-	prettify := make([]*model.EventOut, 0, len(res))
-	for _, event := range res {
-		prettify = append(prettify, event.FormatDate())
+// listParams are the parameters of List and ListVersion
+type listParams struct {
+	userID     int
+	day        time.Time
+	selected   map[int]bool
+	withShared bool
+	mode       Mode
+	weekStart  time.Weekday
+	dateFormat date.Format
+}
+
+func listParamsFromQuery(query url.Values) (*listParams, error) {
+	verr := model.NewValidationError(InvalidQuery)
+	params := &listParams{
+		userID:     queryUserID(query, verr),
+		day:        queryDate(query, "date", verr),
+		selected:   queryCalendars(query, verr),
+		withShared: queryBool(query, "shared", true, verr),
+		mode:       queryMode(query, verr),
+		weekStart:  queryWeekday(query, "week_start", time.Monday, verr),
+		dateFormat: queryDateFormat(query, verr),
+	}
+	if err := verr.Err(); err != nil {
+		return nil, err
 	}
 
-	return prettify, err
+	return params, nil
 }
 
-func (s *EventService) Update(body []byte) (*model.EventOut, error) {
-	var eventParse model.EventOut
-	if err := json.Unmarshal(body, &eventParse); err != nil {
-		return nil, model.InvalidFormat
+// List returns events of the user together with events of owners who shared
+// their calendar with the user, unless shared=false is passed, ordered by
+// date. The calendar selection applies to shared events too. Week and month
+// windows follow the mode parameter, rolling by default
+func (s *EventService) List(query url.Values, by ListFor) ([]*model.EventOut, error) {
+	params, err := listParamsFromQuery(query)
+	if err != nil {
+		return []*model.EventOut{}, err
 	}
 
-	var time time.Time
-	if eventParse.Date != "" {
-		parsed, err := date.TimeFromString(eventParse.Date)
+	from, to := window(by, params.day, params.mode, params.weekStart)
+
+	owners := []int{params.userID}
+	if params.withShared {
+		shares, err := s.repo.Share.ListForGrantee(params.userID)
 		if err != nil {
-			return nil, model.InvalidFormat
+			return []*model.EventOut{}, err
 		}
 
-		time = parsed
+		for _, share := range shares {
+			owners = append(owners, share.OwnerID)
+		}
+	}
+
+	listed := make([]*model.Event, 0)
+	for _, ownerID := range owners {
+		visible, err := visibleFilter(s.repo, ownerID, params.selected)
+		if err != nil {
+			return []*model.EventOut{}, err
+		}
+
+		res, err := s.repo.Event.ListForPeriod(ownerID, from, to)
+		if err != nil {
+			return []*model.EventOut{}, err
+		}
+
+		for _, event := range res {
+			if visible(event) {
+				listed = append(listed, event)
+			}
+		}
+	}
+
+	// every owner's events are sorted already, the user's own go first on the
+	// same date
+	slices.SortStableFunc(listed, func(a, b *model.Event) int {
+		return a.Date.Compare(b.Date)
+	})
+
+	prettify := make([]*model.EventOut, 0, len(listed))
+	for _, event := range listed {
+		prettify = append(prettify, event.FormatDateAs(params.dateFormat))
+	}
+
+	return prettify, nil
+}
+
+func (s *EventService) Update(body []byte) (*model.EventOut, error) {
+	id, update, err := model.EventUpdateFromBody(body)
+	if err != nil {
+		return nil, err
 	}
 
-	event := &model.Event{
-		Name: eventParse.Name,
-		Date: time,
+	actorID, err := model.ActorFromBody(body)
+	if err != nil {
+		return nil, err
 	}
 
-	event, err := s.repo.Event.Update(eventParse.ID, event)
+	event, err := s.UpdateEvent(id, actorID, update)
 	if err != nil {
 		return nil, err
 	}
@@ -119,11 +223,77 @@ func (s *EventService) Update(body []byte) (*model.EventOut, error) {
 	return event.FormatDate(), nil
 }
 
+// UpdateEvent applies a partial event on behalf of actorID
+func (s *EventService) UpdateEvent(id, actorID int, update *model.EventUpdate) (*model.Event, error) {
+	current, err := s.repo.Event.Get(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkWrite(s.repo, actorID, current); err != nil {
+		return nil, err
+	}
+
+	if update.CalendarID != nil {
+		if err := calendarOf(s.repo, current.UserID, *update.CalendarID); err != nil {
+			return nil, err
+		}
+	}
+
+	event, err := s.repo.Event.Update(id, update)
+	if err != nil {
+		return nil, err
+	}
+
+	s.notifier.Notify(model.EventUpdated, event)
+
+	return event, nil
+}
+
 func (s *EventService) Delete(body []byte) error {
-	var eventParse model.EventOut
-	if err := json.Unmarshal(body, &eventParse); err != nil {
-		return model.InvalidFormat
+	id, err := model.IDFromBody(body)
+	if err != nil {
+		return err
+	}
+
+	actorID, err := model.ActorFromBody(body)
+	if err != nil {
+		return err
+	}
+
+	return s.DeleteEvent(id, actorID)
+}
+
+func (s *EventService) DeleteEvent(id, actorID int) error {
+	event, err := s.repo.Event.Get(id)
+	if err != nil {
+		return err
 	}
 
-	return s.repo.Event.Delete(eventParse.ID)
+	if err := checkWrite(s.repo, actorID, event); err != nil {
+		return err
+	}
+
+	if err := s.repo.Event.Delete(id); err != nil {
+		return err
+	}
+
+	s.notifier.Notify(model.EventDeleted, event)
+
+	return nil
+}
+
+func (s *EventService) Get(id int) (*model.Event, error) {
+	return s.repo.Event.Get(id)
+}
+
+// GetByResource finds an event of the user by its CalDAV resource name
+func (s *EventService) GetByResource(userID int, resource string) (*model.Event, error) {
+	return s.repo.Event.GetByResource(userID, resource)
+}
+
+// Changes returns the user's own events changed after the since version, 0
+// lists all of them
+func (s *EventService) Changes(userID, since int) (*model.EventChanges, error) {
+	return s.repo.Event.Changes(userID, since)
 }
@@ -0,0 +1,60 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"runtime/debug"
+	"sync/atomic"
+	"time"
+	"wb_l2/18/internal/model"
+	"wb_l2/18/internal/repository"
+)
+
+var (
+	ShuttingDown       = fmt.Errorf("Server is shutting down")
+	StorageUnavailable = fmt.Errorf("Storage is unavailable")
+)
+
+// storages must answer a readiness check within this time
+const pingTimeout = time.Second
+
+type HealthService struct {
+	repo     *repository.Repository
+	draining atomic.Bool
+}
+
+func NewHealthService(repo *repository.Repository) *HealthService {
+	return &HealthService{repo: repo}
+}
+
+// Ready fails once draining has started or when the storage does not answer
+func (s *HealthService) Ready(ctx context.Context) error {
+	if s.draining.Load() {
+		return ShuttingDown
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, pingTimeout)
+	defer cancel()
+
+	if err := s.repo.Ping(ctx); err != nil {
+		return fmt.Errorf("%w: %w", StorageUnavailable, err)
+	}
+
+	return nil
+}
+
+// Drain marks the server as going away, so load balancers stop sending
+// requests before it shuts down
+func (s *HealthService) Drain() {
+	s.draining.Store(true)
+}
+
+func (s *HealthService) BuildInfo() *model.BuildInfo {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return &model.BuildInfo{Version: "unknown", GoVersion: runtime.Version()}
+	}
+
+	return model.BuildInfoFrom(info)
+}
@@ -0,0 +1,119 @@
+package service
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+	"wb_l2/18/internal/model"
+	"wb_l2/18/pkg/date"
+	"wb_l2/18/pkg/ics"
+
+	"gopkg.in/yaml.v3"
+)
+
+// loadHolidays reads a holiday calendar, the extension picks the format:
+// .ics for iCalendar, .yaml or .yml for a list of dates
+func loadHolidays(path string) ([]model.Holiday, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".ics":
+		return holidaysFromICS(data)
+	case ".yaml", ".yml":
+		return holidaysFromYAML(data)
+	default:
+		return nil, fmt.Errorf("unsupported holiday calendar format %q, use .ics or .yaml", filepath.Ext(path))
+	}
+}
+
+// holidaysFromICS takes every VEVENT as days off from DTSTART up to DTEND,
+// exclusive as in RFC 5545. RRULE:FREQ=YEARLY repeats them every year, other
+// rules are not supported
+func holidaysFromICS(data []byte) ([]model.Holiday, error) {
+	lines, ok := ics.Parse(string(data))
+	if !ok {
+		return nil, fmt.Errorf("invalid iCalendar object")
+	}
+
+	holidays := make([]model.Holiday, 0)
+
+	var (
+		inEvent    bool
+		start, end time.Time
+		name       string
+		yearly     bool
+	)
+	for _, line := range lines {
+		switch {
+		case line.Name == "BEGIN" && strings.EqualFold(line.Value, "VEVENT"):
+			inEvent = true
+			start, end, name, yearly = time.Time{}, time.Time{}, "", false
+		case line.Name == "END" && strings.EqualFold(line.Value, "VEVENT"):
+			inEvent = false
+			if start.IsZero() {
+				return nil, fmt.Errorf("holiday %q has no DTSTART", name)
+			}
+			if !end.After(start) {
+				end = start.AddDate(0, 0, 1)
+			}
+			for day := start; day.Before(end); day = day.AddDate(0, 0, 1) {
+				holidays = append(holidays, model.Holiday{Date: day, Name: name, Yearly: yearly})
+			}
+		case !inEvent:
+		case line.Name == "SUMMARY":
+			name = ics.UnescapeText(line.Value)
+		case line.Name == "DTSTART", line.Name == "DTEND":
+			day, ok := line.Date()
+			if !ok {
+				return nil, fmt.Errorf("invalid %s %q", line.Name, line.Value)
+			}
+			if line.Name == "DTSTART" {
+				start = day
+			} else {
+				end = day
+			}
+		case line.Name == "RRULE":
+			if !strings.Contains(strings.ToUpper(line.Value), "FREQ=YEARLY") {
+				return nil, fmt.Errorf("unsupported RRULE %q, only FREQ=YEARLY is", line.Value)
+			}
+			yearly = true
+		}
+	}
+
+	return holidays, nil
+}
+
+// holidayParse is an entry of a YAML holiday calendar:
+//
+//   - date: 2024-01-01
+//     name: New Year
+//     yearly: true
+type holidayParse struct {
+	Date   string `yaml:"date"`
+	Name   string `yaml:"name"`
+	Yearly bool   `yaml:"yearly"`
+}
+
+func holidaysFromYAML(data []byte) ([]model.Holiday, error) {
+	var parse []holidayParse
+	if err := yaml.Unmarshal(data, &parse); err != nil {
+		return nil, err
+	}
+
+	holidays := make([]model.Holiday, 0, len(parse))
+	for i, entry := range parse {
+		day, err := date.TimeFromString(entry.Date)
+		if err != nil {
+			return nil, fmt.Errorf("holiday %d: %w", i+1, err)
+		}
+
+		holidays = append(holidays, model.Holiday{Date: day, Name: entry.Name, Yearly: entry.Yearly})
+	}
+
+	return holidays, nil
+}
@@ -0,0 +1,59 @@
+package service
+
+import (
+	"fmt"
+	"log/slog"
+	"time"
+	"wb_l2/18/internal/model"
+)
+
+// createOnce claims the key of the event owner before creating the event, keys
+// of different users never collide. A retry with the same body gets the stored
+// response, a reused key with another body fails with
+// model.IdempotencyKeyReused and a retry racing the first request with
+// model.IdempotencyKeyInProgress. Failed requests release the key
+func (s *EventService) createOnce(event *model.Event, body []byte, key string) (int, []string, error) {
+	if err := model.ValidateIdempotencyKey(key); err != nil {
+		return 0, nil, err
+	}
+	key = fmt.Sprintf("%d:%s", event.UserID, key)
+
+	now := time.Now()
+	fingerprint := model.Fingerprint(body)
+	record := &model.Idempotency{
+		Key:         key,
+		Fingerprint: fingerprint,
+		ExpiresAt:   now.Add(s.idempotency.TTL),
+	}
+	existing, claimed, err := s.repo.Idempotency.Claim(record, now)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	if !claimed {
+		switch {
+		case existing.Fingerprint != fingerprint:
+			return 0, nil, model.IdempotencyKeyReused
+		case !existing.Done:
+			return 0, nil, model.IdempotencyKeyInProgress
+		default:
+			return existing.ID, existing.Warnings, nil
+		}
+	}
+
+	id, warnings, err := s.CreateEvent(event)
+	if err != nil {
+		if err := s.repo.Idempotency.Release(record); err != nil {
+			slog.Error("Unable to release idempotency key", "error", err)
+		}
+		return 0, nil, err
+	}
+
+	// the event is created anyway, a retry would fail with
+	// IdempotencyKeyInProgress until the key expires
+	if err := s.repo.Idempotency.Complete(record, id, warnings); err != nil {
+		slog.Error("Unable to store idempotent response", "error", err)
+	}
+
+	return id, warnings, nil
+}
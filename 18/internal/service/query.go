@@ -0,0 +1,136 @@
+package service
+
+import (
+	"fmt"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"wb_l2/18/internal/model"
+	"wb_l2/18/pkg/date"
+)
+
+func queryUserID(query url.Values, verr *model.ValidationError) int {
+	return queryPositive(query, "user_id", true, verr)
+}
+
+func queryPositive(query url.Values, field string, required bool, verr *model.ValidationError) int {
+	str := query.Get(field)
+	if str == "" {
+		if required {
+			verr.Add(field, model.CodeRequired, field+" is required")
+		}
+		return 0
+	}
+
+	value, err := strconv.Atoi(str)
+	if err != nil {
+		verr.Add(field, model.CodeInvalidType, field+" must be an integer")
+		return 0
+	}
+
+	if value <= 0 {
+		verr.Add(field, model.CodeNotPositive, field+" must be a positive integer")
+	}
+
+	return value
+}
+
+func queryDate(query url.Values, field string, verr *model.ValidationError) time.Time {
+	str := query.Get(field)
+	if str == "" {
+		verr.Add(field, model.CodeRequired, field+" is required")
+		return time.Time{}
+	}
+
+	time, err := date.TimeFromString(str)
+	if err != nil {
+		verr.Add(field, model.CodeInvalidDate, field+" must be an ISO 8601 date like 2024-01-15, 2024-W03-1 or 2024-015")
+	}
+
+	return time
+}
+
+func userIDFromQuery(query url.Values) (int, error) {
+	verr := model.NewValidationError(InvalidQuery)
+	userID := queryUserID(query, verr)
+
+	return userID, verr.Err()
+}
+
+// queryCalendars parses a comma separated list of calendar ids, 0 stands for
+// the default calendar. Returns nil when the parameter is absent
+func queryCalendars(query url.Values, verr *model.ValidationError) map[int]bool {
+	str := query.Get("calendars")
+	if str == "" {
+		return nil
+	}
+
+	calendars := make(map[int]bool)
+	for _, part := range strings.Split(str, ",") {
+		id, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil || id < 0 {
+			verr.Add("calendars", model.CodeInvalidType, "calendars must be a comma separated list of calendar ids")
+			return nil
+		}
+
+		calendars[id] = true
+	}
+
+	return calendars
+}
+
+func queryBool(query url.Values, field string, def bool, verr *model.ValidationError) bool {
+	str := query.Get(field)
+	if str == "" {
+		return def
+	}
+
+	value, err := strconv.ParseBool(str)
+	if err != nil {
+		verr.Add(field, model.CodeInvalidType, field+" must be a boolean")
+		return def
+	}
+
+	return value
+}
+
+func queryMode(query url.Values, verr *model.ValidationError) Mode {
+	mode := Mode(query.Get("mode"))
+	if mode == "" {
+		return ModeRolling
+	}
+
+	if !slices.Contains(modes, mode) {
+		verr.Add("mode", model.CodeUnknownValue, `mode must be "rolling" or "calendar"`)
+		return ModeRolling
+	}
+
+	return mode
+}
+
+func queryWeekday(query url.Values, field string, def time.Weekday, verr *model.ValidationError) time.Weekday {
+	str := query.Get(field)
+	if str == "" {
+		return def
+	}
+
+	weekday, err := date.WeekdayFromString(str)
+	if err != nil {
+		verr.Add(field, model.CodeUnknownValue, field+" must be a weekday name like monday")
+		return def
+	}
+
+	return weekday
+}
+
+func queryDateFormat(query url.Values, verr *model.ValidationError) date.Format {
+	format, err := date.FormatFromString(query.Get("date_format"))
+	if err != nil {
+		verr.Add("date_format", model.CodeUnknownValue, fmt.Sprintf("date_format must be one of %v", date.Formats))
+		return date.FormatDate
+	}
+
+	return format
+}
@@ -2,17 +2,57 @@ package service
 
 import (
 	"fmt"
+	"wb_l2/18/internal/config"
 	"wb_l2/18/internal/repository"
 )
 
-var InvalidQuery = fmt.Errorf("Invalid query provided")
+var (
+	InvalidQuery = fmt.Errorf("Invalid query provided")
+	Forbidden    = fmt.Errorf("Not enough permissions")
+)
 
 type Service struct {
-	Event *EventService
+	Event    *EventService
+	Calendar *CalendarService
+	Share    *ShareService
+	Webhook  *WebhookService
+	Health   *HealthService
+	Watch    *WatchService
+
+	Availability *AvailabilityService
+	Agenda       *AgendaService
+
+	// Tenants is shared by the services of every tenant
+	Tenants *TenantService
 }
 
-func NewService(repo *repository.Repository) *Service {
+// NewService builds the services of the default tenant on repo, other tenants
+// are added with Tenants.Create
+func NewService(repo *repository.Repository, config *config.Config) *Service {
+	s := newService(repo, config)
+	s.Tenants = newTenantService(repo, config, s)
+
+	return s
+}
+
+func newService(repo *repository.Repository, config *config.Config) *Service {
+	webhook := NewWebhookService(repo, config.Webhook)
+	watch := NewWatchService(repo)
+	availability := NewAvailabilityService(repo)
+
+	versions := versions{repo}
+	notifier := notifiers{webhook, watch, versions}
+	event := NewEventService(repo, notifier, availability, config.Idempotency)
+
 	return &Service{
-		Event: NewEventService(repo),
+		Event:    event,
+		Calendar: NewCalendarService(repo, notifier, versions),
+		Share:    NewShareService(repo, versions),
+		Webhook:  webhook,
+		Health:   NewHealthService(repo),
+		Watch:    watch,
+
+		Availability: availability,
+		Agenda:       NewAgendaService(event, availability, config.Agenda),
 	}
 }
@@ -0,0 +1,104 @@
+package service
+
+import (
+	"net/url"
+	"wb_l2/18/internal/model"
+	"wb_l2/18/internal/repository"
+	"wb_l2/18/internal/repository/inmemory/event"
+	"wb_l2/18/internal/repository/inmemory/share"
+)
+
+type ShareService struct {
+	repo     *repository.Repository
+	versions versions
+}
+
+func NewShareService(repo *repository.Repository, versions versions) *ShareService {
+	return &ShareService{
+		repo:     repo,
+		versions: versions,
+	}
+}
+
+func (s *ShareService) Share(body []byte) (*model.ShareOut, error) {
+	share, err := model.ShareFromBody(body)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.Share.Save(share); err != nil {
+		return nil, err
+	}
+	// the grantee now sees events of the owner
+	s.versions.touch(share.GranteeID)
+
+	return share.Format(), nil
+}
+
+func (s *ShareService) List(query url.Values) (*model.SharesOut, error) {
+	userID, err := userIDFromQuery(query)
+	if err != nil {
+		return nil, err
+	}
+
+	given, err := s.repo.Share.ListForOwner(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	received, err := s.repo.Share.ListForGrantee(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.SharesOut{
+		Given:    formatShares(given),
+		Received: formatShares(received),
+	}, nil
+}
+
+func (s *ShareService) Revoke(body []byte) error {
+	ownerID, granteeID, err := model.ShareRevokeFromBody(body)
+	if err != nil {
+		return err
+	}
+
+	if err := s.repo.Share.Delete(ownerID, granteeID); err != nil {
+		return err
+	}
+	s.versions.touch(granteeID)
+
+	return nil
+}
+
+func formatShares(shares []*model.Share) []*model.ShareOut {
+	prettify := make([]*model.ShareOut, 0, len(shares))
+	for _, share := range shares {
+		prettify = append(prettify, share.Format())
+	}
+
+	return prettify
+}
+
+// checkWrite allows the owner and grantees with write permission. Users without
+// any access get the same error as for a missing event, so existence of events
+// is not disclosed
+func checkWrite(repo *repository.Repository, actorID int, target *model.Event) error {
+	if actorID == target.UserID {
+		return nil
+	}
+
+	granted, err := repo.Share.Get(target.UserID, actorID)
+	if err == share.ErrorShareNotFound {
+		return event.ErrorEventNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	if granted.Permission != model.PermissionWrite {
+		return Forbidden
+	}
+
+	return nil
+}
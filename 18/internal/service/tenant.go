@@ -0,0 +1,187 @@
+package service
+
+import (
+	"context"
+	"net/url"
+	"sync"
+	"time"
+	"wb_l2/18/internal/config"
+	"wb_l2/18/internal/model"
+	"wb_l2/18/internal/repository"
+	"wb_l2/18/internal/repository/inmemory/tenant"
+)
+
+// TenantService keeps a separate storage and services per tenant, so data of
+// one tenant can't show up in another one and ids are assigned per tenant.
+// The registry of tenants lives in the repository of the default tenant
+type TenantService struct {
+	repo   *repository.Repository
+	config *config.Config
+
+	root     *Service
+	services map[string]*Service
+
+	// ctx starts webhook workers and agenda jobs of tenants created after
+	// Start
+	ctx context.Context
+
+	mu sync.Mutex
+}
+
+func newTenantService(repo *repository.Repository, config *config.Config, root *Service) *TenantService {
+	s := &TenantService{
+		repo:     repo,
+		config:   config,
+		root:     root,
+		services: map[string]*Service{config.Tenancy.Default: root},
+	}
+
+	// the repository is new, so the id is not taken
+	repo.Tenant.Create(&model.Tenant{ID: config.Tenancy.Default, Name: "Default", CreatedAt: time.Now().UTC()})
+
+	return s
+}
+
+func (s *TenantService) Create(body []byte) (*model.TenantOut, error) {
+	created, err := model.TenantFromBody(body)
+	if err != nil {
+		return nil, err
+	}
+	created.CreatedAt = time.Now().UTC()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.repo.Tenant.Create(created); err != nil {
+		return nil, err
+	}
+
+	services := newService(repository.NewRepository(repository.InMemory), s.config)
+	services.Tenants = s
+	// holiday calendars are loaded once and only read afterwards
+	services.Availability.holidays = s.root.Availability.holidays
+	services.Agenda.config = s.config.Agenda.ForTenant(created.ID)
+
+	if s.ctx != nil {
+		services.Webhook.Start(s.ctx)
+		services.Agenda.Start(s.ctx)
+	}
+	s.services[created.ID] = services
+
+	return created.Format(), nil
+}
+
+func (s *TenantService) List() ([]*model.TenantOut, error) {
+	tenants, err := s.repo.Tenant.List()
+	if err != nil {
+		return []*model.TenantOut{}, err
+	}
+
+	res := make([]*model.TenantOut, 0, len(tenants))
+	for _, tenant := range tenants {
+		res = append(res, tenant.Format())
+	}
+
+	return res, nil
+}
+
+// Resolve picks the services of the tenant with the id or, without an id, of
+// the tenant owning the host, falling back to the default tenant. An unknown
+// id fails with tenant.ErrorTenantNotFound
+func (s *TenantService) Resolve(id, host string) (*Service, error) {
+	if id == "" {
+		found, err := s.repo.Tenant.GetByHost(host)
+		if err == tenant.ErrorTenantNotFound {
+			return s.root, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		id = found.ID
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	services, ok := s.services[id]
+	if !ok {
+		return nil, tenant.ErrorTenantNotFound
+	}
+
+	return services, nil
+}
+
+// Usage counts what the tenant from the tenant_id query stores
+func (s *TenantService) Usage(query url.Values) (*model.TenantUsageOut, error) {
+	verr := model.NewValidationError(InvalidQuery)
+	id := model.ValidateTenantID("tenant_id", query.Get("tenant_id"), verr)
+	if err := verr.Err(); err != nil {
+		return nil, err
+	}
+
+	services, err := s.Resolve(id, "")
+	if err != nil {
+		return nil, err
+	}
+	repo := services.Event.repo
+
+	usage := &model.TenantUsageOut{TenantID: id}
+	counts := []struct {
+		count func() (int, error)
+		to    *int
+	}{
+		{repo.Event.Count, &usage.Events},
+		{repo.Calendar.Count, &usage.Calendars},
+		{repo.Share.Count, &usage.Shares},
+		{repo.Webhook.Count, &usage.Webhooks},
+	}
+	for _, c := range counts {
+		n, err := c.count()
+		if err != nil {
+			return nil, err
+		}
+		*c.to = n
+	}
+
+	return usage, nil
+}
+
+// Start runs the webhook workers and the agenda job of every tenant, now and
+// when created, until ctx is done
+func (s *TenantService) Start(ctx context.Context) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.ctx = ctx
+	for _, services := range s.services {
+		services.Webhook.Start(ctx)
+		services.Agenda.Start(ctx)
+	}
+}
+
+// Wait blocks until the workers started by Start exit
+func (s *TenantService) Wait() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, services := range s.services {
+		services.Webhook.Wait()
+		services.Agenda.Wait()
+	}
+}
+
+// Default returns the services of the default tenant
+func (s *TenantService) Default() *Service {
+	return s.root
+}
+
+// Drain fails readiness checks of every tenant
+func (s *TenantService) Drain() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, services := range s.services {
+		services.Health.Drain()
+	}
+}
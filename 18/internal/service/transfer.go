@@ -0,0 +1,358 @@
+package service
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"slices"
+	"wb_l2/18/internal/model"
+)
+
+// maxImportRows bounds the events held in memory until every row of an import
+// is validated
+const maxImportRows = 10000
+
+// maxImportLine bounds a line of an imported JSONL file
+const maxImportLine = 64 * 1024
+
+func queryTransferFormat(query url.Values, verr *model.ValidationError) model.TransferFormat {
+	format := model.TransferFormat(query.Get("format"))
+	if format == "" {
+		return model.TransferCSV
+	}
+
+	if !slices.Contains(model.TransferFormats, format) {
+		verr.Add("format", model.CodeUnknownValue, `format must be "csv" or "jsonl"`)
+		return model.TransferCSV
+	}
+
+	return format
+}
+
+// Export validates the query (user_id, from and to, both inclusive, and format,
+// csv by default) and returns a function writing the user's own events. The
+// events are read and written a month at a time, so the whole period is never
+// held in memory
+func (s *EventService) Export(query url.Values) (model.TransferFormat, func(io.Writer) error, error) {
+	verr := model.NewValidationError(InvalidQuery)
+	userID := queryUserID(query, verr)
+	from := queryDate(query, "from", verr)
+	to := queryDate(query, "to", verr)
+	format := queryTransferFormat(query, verr)
+	if err := verr.Err(); err != nil {
+		return "", nil, err
+	}
+
+	if to.Before(from) {
+		verr.Add("to", model.CodeTooSmall, "to must not be before from")
+		return "", nil, verr
+	}
+
+	write := func(w io.Writer) error {
+		var out eventWriter
+		if format == model.TransferJSONL {
+			out = newJSONLWriter(w)
+		} else {
+			out = newCSVWriter(w)
+		}
+
+		end := to.AddDate(0, 0, 1)
+		for start := from; start.Before(end); start = start.AddDate(0, 1, 0) {
+			next := start.AddDate(0, 1, 0)
+			if next.After(end) {
+				next = end
+			}
+
+			events, err := s.repo.Event.ListForPeriod(userID, start, next)
+			if err != nil {
+				return err
+			}
+
+			for _, event := range events {
+				if err := out.write(event.FormatDate()); err != nil {
+					return err
+				}
+			}
+
+			if err := out.flush(); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	return format, write, nil
+}
+
+// eventWriter buffers exported events until flush
+type eventWriter interface {
+	write(event *model.EventOut) error
+	flush() error
+}
+
+type csvWriter struct {
+	writer *csv.Writer
+	header bool
+}
+
+func newCSVWriter(w io.Writer) *csvWriter {
+	return &csvWriter{writer: csv.NewWriter(w)}
+}
+
+// writeHeader comes before the first event or on the first flush, so an empty
+// export has the header too
+func (w *csvWriter) writeHeader() error {
+	if w.header {
+		return nil
+	}
+
+	w.header = true
+	return w.writer.Write(model.EventColumns)
+}
+
+func (w *csvWriter) write(event *model.EventOut) error {
+	if err := w.writeHeader(); err != nil {
+		return err
+	}
+
+	return w.writer.Write(event.Record())
+}
+
+func (w *csvWriter) flush() error {
+	if err := w.writeHeader(); err != nil {
+		return err
+	}
+
+	w.writer.Flush()
+	return w.writer.Error()
+}
+
+type jsonlWriter struct {
+	buf     *bufio.Writer
+	encoder *json.Encoder
+}
+
+func newJSONLWriter(w io.Writer) *jsonlWriter {
+	buf := bufio.NewWriter(w)
+	return &jsonlWriter{buf: buf, encoder: json.NewEncoder(buf)}
+}
+
+func (w *jsonlWriter) write(event *model.EventOut) error {
+	return w.encoder.Encode(event)
+}
+
+func (w *jsonlWriter) flush() error {
+	return w.buf.Flush()
+}
+
+// importRow is a valid event with the line it came from
+type importRow struct {
+	line  int
+	event *model.Event
+}
+
+// Import creates the events of a CSV file with a header or of a JSONL file,
+// one event object per line. Every row is validated like a body of
+// /create_event first and nothing is stored when any row fails, the
+// ValidationError then lists the failing fields with their lines. Other
+// failures come as an ImportError with the line and the events created
+// before it. With dry_run=true the rows are only validated
+func (s *EventService) Import(query url.Values, body io.Reader) (*model.ImportOut, error) {
+	verr := model.NewValidationError(InvalidQuery)
+	format := queryTransferFormat(query, verr)
+	dryRun := queryBool(query, "dry_run", false, verr)
+	if err := verr.Err(); err != nil {
+		return nil, err
+	}
+
+	var rows []importRow
+	var err error
+	if format == model.TransferJSONL {
+		rows, err = readJSONL(body)
+	} else {
+		rows, err = readCSV(body)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	res := &model.ImportOut{DryRun: dryRun}
+
+	verr = model.NewValidationError(model.InvalidFormat)
+	for _, row := range rows {
+		warnings, err := s.checkNew(row.event)
+		var rowErr *model.ValidationError
+		if errors.As(err, &rowErr) {
+			verr.AddLine(row.line, rowErr)
+			continue
+		}
+		if err != nil {
+			return nil, &model.ImportError{Line: row.line, Err: err}
+		}
+
+		for _, warning := range warnings {
+			res.Warnings = append(res.Warnings, fmt.Sprintf("line %d: %s", row.line, warning))
+		}
+	}
+	if err := verr.Err(); err != nil {
+		return nil, err
+	}
+
+	if dryRun {
+		res.Imported = len(rows)
+		return res, nil
+	}
+
+	// rows are checked again on creation, a calendar deleted in between
+	// stops the import halfway
+	for _, row := range rows {
+		if _, _, err := s.CreateEvent(row.event); err != nil {
+			return nil, &model.ImportError{Line: row.line, Imported: res.Imported, Err: err}
+		}
+		res.Imported++
+	}
+
+	return res, nil
+}
+
+// checkNew applies the checks of CreateEvent without storing the event
+func (s *EventService) checkNew(event *model.Event) ([]string, error) {
+	if err := calendarOf(s.repo, event.UserID, event.CalendarID); err != nil {
+		return nil, err
+	}
+
+	return s.availability.check(event)
+}
+
+func readCSV(body io.Reader) ([]importRow, error) {
+	reader := csv.NewReader(body)
+
+	verr := model.NewValidationError(model.InvalidFormat)
+	invalid := func(err error) error {
+		var parseErr *csv.ParseError
+		if !errors.As(err, &parseErr) {
+			return err
+		}
+
+		verr.Fields = append(verr.Fields, model.FieldError{
+			Code:    model.CodeInvalidCSV,
+			Message: parseErr.Err.Error(),
+			Line:    parseErr.Line,
+		})
+		return verr
+	}
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, invalid(err)
+	}
+
+	for _, column := range header {
+		if !slices.Contains(model.EventColumns, column) {
+			verr.Fields = append(verr.Fields, model.FieldError{
+				Field:   column,
+				Code:    model.CodeUnknownField,
+				Message: "column must be one of id, name, date, user_id and calendar_id",
+				Line:    1,
+			})
+		}
+	}
+	if err := verr.Err(); err != nil {
+		return nil, err
+	}
+
+	rows := make([]importRow, 0)
+	for n := 0; ; n++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, invalid(err)
+		}
+
+		line, _ := reader.FieldPos(0)
+		if n == maxImportRows {
+			verr.Fields = append(verr.Fields, tooManyRows(line))
+			return nil, verr
+		}
+
+		event, err := model.EventFromRecord(header, record)
+		var rowErr *model.ValidationError
+		if errors.As(err, &rowErr) {
+			verr.AddLine(line, rowErr)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		rows = append(rows, importRow{line: line, event: event})
+	}
+
+	return rows, verr.Err()
+}
+
+func readJSONL(body io.Reader) ([]importRow, error) {
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 0, 4096), maxImportLine)
+
+	verr := model.NewValidationError(model.InvalidFormat)
+	rows := make([]importRow, 0)
+	line, n := 0, 0
+	for scanner.Scan() {
+		line++
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+
+		if n == maxImportRows {
+			verr.Fields = append(verr.Fields, tooManyRows(line))
+			return nil, verr
+		}
+
+		n++
+		event, err := model.EventFromBody(scanner.Bytes())
+		var rowErr *model.ValidationError
+		if errors.As(err, &rowErr) {
+			verr.AddLine(line, rowErr)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		rows = append(rows, importRow{line: line, event: event})
+	}
+
+	if errors.Is(scanner.Err(), bufio.ErrTooLong) {
+		verr.Fields = append(verr.Fields, model.FieldError{
+			Code:    model.CodeTooLong,
+			Message: fmt.Sprintf("lines must be at most %d bytes long", maxImportLine),
+			Line:    line + 1,
+		})
+		return nil, verr
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return rows, verr.Err()
+}
+
+func tooManyRows(line int) model.FieldError {
+	return model.FieldError{
+		Code:    model.CodeTooLong,
+		Message: fmt.Sprintf("at most %d events can be imported at once", maxImportRows),
+		Line:    line,
+	}
+}
@@ -0,0 +1,58 @@
+package service
+
+import (
+	"log/slog"
+	"net/url"
+	"time"
+	"wb_l2/18/internal/model"
+	"wb_l2/18/internal/repository"
+)
+
+// versions moves users to a new version whenever what they see in lists of
+// events changes, see EventService.ListVersion
+type versions struct {
+	repo *repository.Repository
+}
+
+func (v versions) Notify(eventType model.EventType, event *model.Event) {
+	v.touch(event.UserID)
+}
+
+func (v versions) touch(userIDs ...int) {
+	if err := v.repo.Version.Touch(time.Now().UTC(), userIDs...); err != nil {
+		slog.Error(err.Error())
+	}
+}
+
+// ListVersion identifies the list the query of List would return without
+// building it: the list changes only with the version of the user or, with
+// shared events, of the owners sharing with the user
+func (s *EventService) ListVersion(query url.Values) (*model.ListVersion, error) {
+	params, err := listParamsFromQuery(query)
+	if err != nil {
+		return nil, err
+	}
+
+	own, err := s.repo.Version.Get(params.userID)
+	if err != nil {
+		return nil, err
+	}
+	listed := []*model.Version{own}
+
+	if params.withShared {
+		shares, err := s.repo.Share.ListForGrantee(params.userID)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, share := range shares {
+			version, err := s.repo.Version.Get(share.OwnerID)
+			if err != nil {
+				return nil, err
+			}
+			listed = append(listed, version)
+		}
+	}
+
+	return model.NewListVersion(listed), nil
+}
@@ -0,0 +1,100 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"wb_l2/18/internal/model"
+	"wb_l2/18/internal/repository"
+)
+
+// notifier is told about every change of an event after it is stored
+type notifier interface {
+	Notify(eventType model.EventType, event *model.Event)
+}
+
+// notifiers passes a change on to each of them, webhooks and watchers
+type notifiers []notifier
+
+func (n notifiers) Notify(eventType model.EventType, event *model.Event) {
+	for _, notifier := range n {
+		notifier.Notify(eventType, event)
+	}
+}
+
+// WatchService wakes up the watchers of a user's events on every change
+type WatchService struct {
+	repo     *repository.Repository
+	watchers map[int]map[chan struct{}]struct{}
+
+	mu sync.Mutex
+}
+
+func NewWatchService(repo *repository.Repository) *WatchService {
+	return &WatchService{
+		repo:     repo,
+		watchers: make(map[int]map[chan struct{}]struct{}),
+	}
+}
+
+// Notify never blocks: a watcher that has not picked up the previous signal
+// yet will see this change too
+func (s *WatchService) Notify(_ model.EventType, event *model.Event) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for watcher := range s.watchers[event.UserID] {
+		select {
+		case watcher <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func (s *WatchService) subscribe(userID int) (<-chan struct{}, func()) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	watcher := make(chan struct{}, 1)
+	if s.watchers[userID] == nil {
+		s.watchers[userID] = make(map[chan struct{}]struct{})
+	}
+	s.watchers[userID][watcher] = struct{}{}
+
+	return watcher, func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		delete(s.watchers[userID], watcher)
+		if len(s.watchers[userID]) == 0 {
+			delete(s.watchers, userID)
+		}
+	}
+}
+
+// Watch calls send with the user's own events changed after the since
+// version, then with every following change until ctx is done or send fails
+func (s *WatchService) Watch(ctx context.Context, userID, since int, send func(*model.EventChanges) error) error {
+	// subscribe before reading, so no change slips in between
+	changed, unsubscribe := s.subscribe(userID)
+	defer unsubscribe()
+
+	for {
+		changes, err := s.repo.Event.Changes(userID, since)
+		if err != nil {
+			return err
+		}
+
+		if len(changes.Changed) > 0 || len(changes.Deleted) > 0 {
+			if err := send(changes); err != nil {
+				return err
+			}
+		}
+		since = changes.Version
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-changed:
+		}
+	}
+}
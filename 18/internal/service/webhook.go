@@ -0,0 +1,287 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+	"wb_l2/18/internal/config"
+	"wb_l2/18/internal/model"
+	"wb_l2/18/internal/repository"
+)
+
+const (
+	SignatureHeader = "X-Webhook-Signature"
+	EventHeader     = "X-Webhook-Event"
+	DeliveryHeader  = "X-Webhook-Delivery"
+)
+
+type WebhookService struct {
+	repo   *repository.Repository
+	config config.WebhookConfig
+	client *http.Client
+
+	queue   chan *delivery
+	workers sync.WaitGroup
+}
+
+// delivery is a queued job, the secret never leaves the service
+type delivery struct {
+	model  *model.Delivery
+	url    string
+	secret string
+}
+
+func NewWebhookService(repo *repository.Repository, config config.WebhookConfig) *WebhookService {
+	return &WebhookService{
+		repo:   repo,
+		config: config,
+		client: &http.Client{Timeout: config.Timeout},
+		queue:  make(chan *delivery, config.QueueSize),
+	}
+}
+
+func (s *WebhookService) Create(body []byte) (*model.WebhookOut, error) {
+	webhook, err := model.WebhookFromBody(body)
+	if err != nil {
+		return nil, err
+	}
+
+	if webhook.Secret == "" {
+		webhook.Secret = rand.Text()
+	}
+
+	if _, err := s.repo.Webhook.Create(webhook); err != nil {
+		return nil, err
+	}
+
+	out := webhook.Format()
+	out.Secret = webhook.Secret
+
+	return out, nil
+}
+
+func (s *WebhookService) List(query url.Values) ([]*model.WebhookOut, error) {
+	userID, err := userIDFromQuery(query)
+	if err != nil {
+		return []*model.WebhookOut{}, err
+	}
+
+	webhooks, err := s.repo.Webhook.ListForUser(userID)
+
+	prettify := make([]*model.WebhookOut, 0, len(webhooks))
+	for _, webhook := range webhooks {
+		prettify = append(prettify, webhook.Format())
+	}
+
+	return prettify, err
+}
+
+// Delete removes a webhook on behalf of its owner
+func (s *WebhookService) Delete(body []byte) error {
+	id, err := model.IDFromBody(body)
+	if err != nil {
+		return err
+	}
+
+	actorID, err := model.ActorFromBody(body)
+	if err != nil {
+		return err
+	}
+
+	webhook, err := s.repo.Webhook.Get(id)
+	if err != nil {
+		return err
+	}
+
+	if webhook.UserID != actorID {
+		return Forbidden
+	}
+
+	return s.repo.Webhook.Delete(id)
+}
+
+func (s *WebhookService) Deliveries(query url.Values, status model.DeliveryStatus) ([]*model.DeliveryOut, error) {
+	userID, err := userIDFromQuery(query)
+	if err != nil {
+		return []*model.DeliveryOut{}, err
+	}
+
+	deliveries, err := s.repo.Webhook.ListDeliveries(userID, status)
+
+	prettify := make([]*model.DeliveryOut, 0, len(deliveries))
+	for _, delivery := range deliveries {
+		prettify = append(prettify, delivery.Format())
+	}
+
+	return prettify, err
+}
+
+// Notify queues a delivery for every webhook of the event owner subscribed to
+// the event type. It never blocks the caller: when the queue is full the
+// delivery goes straight to the dead-letter list
+func (s *WebhookService) Notify(eventType model.EventType, event *model.Event) {
+	webhooks, err := s.repo.Webhook.ListForUser(event.UserID)
+	if err != nil {
+		slog.Error("Unable to list webhooks: " + err.Error())
+		return
+	}
+
+	now := time.Now()
+	payload := marshalPayload(model.WebhookPayload{
+		Type:       eventType,
+		OccurredAt: now.Format(time.RFC3339),
+		Event:      event.FormatDate(),
+	})
+
+	for _, webhook := range webhooks {
+		if !webhook.Subscribed(eventType) {
+			continue
+		}
+
+		job := &delivery{
+			model: &model.Delivery{
+				WebhookID: webhook.ID,
+				Event:     eventType,
+				Payload:   payload,
+				Status:    model.DeliveryPending,
+				CreatedAt: now,
+				UserID:    webhook.UserID,
+			},
+			url:    webhook.URL,
+			secret: webhook.Secret,
+		}
+
+		if _, err := s.repo.Webhook.CreateDelivery(job.model); err != nil {
+			slog.Error("Unable to store webhook delivery: " + err.Error())
+			continue
+		}
+
+		select {
+		case s.queue <- job:
+		default:
+			job.model.Status = model.DeliveryDead
+			job.model.Attempts = append(job.model.Attempts, model.DeliveryAttempt{
+				At:    now,
+				Error: "delivery queue is full",
+			})
+			s.save(job.model)
+		}
+	}
+}
+
+// Start runs delivery workers until ctx is done. Wait blocks until they exit
+func (s *WebhookService) Start(ctx context.Context) {
+	for range max(s.config.Workers, 1) {
+		s.workers.Add(1)
+		go func() {
+			defer s.workers.Done()
+
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case job := <-s.queue:
+					s.deliver(ctx, job)
+				}
+			}
+		}()
+	}
+}
+
+func (s *WebhookService) Wait() {
+	s.workers.Wait()
+}
+
+func (s *WebhookService) deliver(ctx context.Context, job *delivery) {
+	backoff := s.config.Backoff
+
+	for attempt := 1; attempt <= s.config.MaxAttempts; attempt++ {
+		statusCode, err := s.send(ctx, job)
+
+		result := model.DeliveryAttempt{At: time.Now(), StatusCode: statusCode}
+		if err != nil {
+			result.Error = err.Error()
+		}
+		job.model.Attempts = append(job.model.Attempts, result)
+
+		if err == nil {
+			job.model.Status = model.DeliverySucceeded
+			s.save(job.model)
+			return
+		}
+
+		if attempt == s.config.MaxAttempts {
+			break
+		}
+		s.save(job.model)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+
+	job.model.Status = model.DeliveryDead
+	s.save(job.model)
+}
+
+func (s *WebhookService) send(ctx context.Context, job *delivery) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, job.url, bytes.NewReader(job.model.Payload))
+	if err != nil {
+		return 0, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EventHeader, string(job.model.Event))
+	req.Header.Set(DeliveryHeader, strconv.Itoa(job.model.ID))
+	req.Header.Set(SignatureHeader, Sign(job.secret, job.model.Payload))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("Receiver responded with %s", resp.Status)
+	}
+
+	return resp.StatusCode, nil
+}
+
+func (s *WebhookService) save(delivery *model.Delivery) {
+	if err := s.repo.Webhook.UpdateDelivery(delivery); err != nil {
+		slog.Error("Unable to update webhook delivery: " + err.Error())
+	}
+}
+
+// Sign returns the value of the signature header: hex encoded HMAC-SHA256 of
+// the payload keyed with the webhook secret
+func Sign(secret string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func marshalPayload(payload model.WebhookPayload) []byte {
+	bytes, err := json.Marshal(payload)
+	if err != nil {
+		panic(fmt.Sprintf("Unable to marshal webhook payload: %s", err))
+	}
+
+	return bytes
+}
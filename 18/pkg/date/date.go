@@ -1,13 +1,152 @@
 package date
 
 import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
 	"time"
 )
 
+// Mode picks which inputs Parse accepts
+type Mode int
+
+const (
+	// Strict accepts the ISO 8601 and RFC 3339 forms only: 2024-01-15,
+	// 2024-01-15T10:00:00+03:00, 2024-W03-1 and 2024-015. The API parses dates
+	// this way
+	Strict Mode = iota
+	// Lenient also accepts DD.MM.YYYY, unpadded numbers, 20240115, 2024-W03
+	// (Monday of the week), any case and surrounding spaces, and relative
+	// expressions like today or +2w. For people typing dates in the CLI
+	Lenient
+)
+
+const layout = "2006-01-02"
+
+var (
+	strictWeek    = regexp.MustCompile(`^(\d{4})-W(\d{2})-(\d)$`)
+	strictOrdinal = regexp.MustCompile(`^(\d{4})-(\d{3})$`)
+
+	lenientDate    = regexp.MustCompile(`^(\d{4})-(\d{1,2})-(\d{1,2})$`)
+	lenientBasic   = regexp.MustCompile(`^(\d{4})(\d{2})(\d{2})$`)
+	lenientDotted  = regexp.MustCompile(`^(\d{1,2})\.(\d{1,2})\.(\d{4})$`)
+	lenientWeek    = regexp.MustCompile(`^(\d{4})-?w(\d{1,2})(?:-?(\d))?$`)
+	lenientOrdinal = regexp.MustCompile(`^(\d{4})-?(\d{3})$`)
+)
+
+// TimeFromString parses a date the way the API accepts it
 func TimeFromString(str string) (time.Time, error) {
-	return time.Parse("2006-01-02", str)
+	return Parse(str, Strict, time.Time{})
 }
 
 func StringFromTime(date time.Time) string {
-	return date.Format("2006-01-02")
+	return date.Format(layout)
+}
+
+// Parse reads a date into midnight UTC. Date-times keep the calendar date they
+// were written with, whatever their offset. now is the reference for relative
+// expressions of the lenient mode
+func Parse(str string, mode Mode, now time.Time) (time.Time, error) {
+	if mode == Lenient {
+		return parseLenient(str, now)
+	}
+
+	if day, err := time.Parse(layout, str); err == nil {
+		return day, nil
+	}
+
+	if t, err := time.Parse(time.RFC3339, str); err == nil {
+		return fromDate(t.Year(), int(t.Month()), t.Day())
+	}
+
+	if m := strictWeek.FindStringSubmatch(str); m != nil {
+		return fromWeek(atoi(m[1]), atoi(m[2]), atoi(m[3]))
+	}
+
+	if m := strictOrdinal.FindStringSubmatch(str); m != nil {
+		return fromOrdinal(atoi(m[1]), atoi(m[2]))
+	}
+
+	return time.Time{}, fmt.Errorf("unrecognized date %q", str)
+}
+
+func parseLenient(str string, now time.Time) (time.Time, error) {
+	str = strings.ToLower(strings.Join(strings.Fields(str), " "))
+
+	if day, ok, err := relative(str, now); ok {
+		return day, err
+	}
+
+	if day, err := Parse(strings.ToUpper(str), Strict, now); err == nil {
+		return day, nil
+	}
+
+	switch {
+	case lenientDate.MatchString(str):
+		m := lenientDate.FindStringSubmatch(str)
+		return fromDate(atoi(m[1]), atoi(m[2]), atoi(m[3]))
+	case lenientBasic.MatchString(str):
+		m := lenientBasic.FindStringSubmatch(str)
+		return fromDate(atoi(m[1]), atoi(m[2]), atoi(m[3]))
+	case lenientDotted.MatchString(str):
+		m := lenientDotted.FindStringSubmatch(str)
+		return fromDate(atoi(m[3]), atoi(m[2]), atoi(m[1]))
+	case lenientWeek.MatchString(str):
+		m := lenientWeek.FindStringSubmatch(str)
+		weekday := 1
+		if m[3] != "" {
+			weekday = atoi(m[3])
+		}
+		return fromWeek(atoi(m[1]), atoi(m[2]), weekday)
+	case lenientOrdinal.MatchString(str):
+		m := lenientOrdinal.FindStringSubmatch(str)
+		return fromOrdinal(atoi(m[1]), atoi(m[2]))
+	}
+
+	return time.Time{}, fmt.Errorf("unrecognized date %q", str)
+}
+
+// fromDate rejects days that time.Date would normalize, like February 30
+func fromDate(year, month, day int) (time.Time, error) {
+	t := time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.UTC)
+	if t.Year() != year || int(t.Month()) != month || t.Day() != day {
+		return time.Time{}, fmt.Errorf("%04d-%02d-%02d is not a valid date", year, month, day)
+	}
+
+	return t, nil
+}
+
+// fromWeek resolves an ISO 8601 week date, weekday 1 is Monday. Week 1 is the
+// one containing January 4th
+func fromWeek(year, week, weekday int) (time.Time, error) {
+	if weekday < 1 || weekday > 7 || week < 1 || week > weeksInYear(year) {
+		return time.Time{}, fmt.Errorf("%04d-W%02d-%d is not a valid week date", year, week, weekday)
+	}
+
+	jan4 := time.Date(year, time.January, 4, 0, 0, 0, 0, time.UTC)
+	monday := jan4.AddDate(0, 0, -((int(jan4.Weekday()) + 6) % 7))
+
+	return monday.AddDate(0, 0, (week-1)*7+weekday-1), nil
+}
+
+// weeksInYear is 53 for years whose December 28th falls in week 53
+func weeksInYear(year int) int {
+	_, week := time.Date(year, time.December, 28, 0, 0, 0, 0, time.UTC).ISOWeek()
+	return week
+}
+
+func fromOrdinal(year, day int) (time.Time, error) {
+	t := time.Date(year, time.January, day, 0, 0, 0, 0, time.UTC)
+	if day < 1 || t.Year() != year {
+		return time.Time{}, fmt.Errorf("%04d-%03d is not a valid ordinal date", year, day)
+	}
+
+	return t, nil
+}
+
+// atoi is for regexp groups that are digits already
+func atoi(str string) int {
+	n, _ := strconv.Atoi(str)
+	return n
 }
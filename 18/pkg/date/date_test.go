@@ -0,0 +1,115 @@
+package date
+
+import (
+	"testing"
+	"time"
+)
+
+// a Wednesday, late in the day to catch time leaking into the result
+var now = time.Date(2024, 1, 17, 23, 30, 0, 0, time.UTC)
+
+func TestParse_Strict(t *testing.T) {
+	cases := map[string]string{
+		"2024-01-15":                "2024-01-15",
+		"2024-01-15T10:30:00Z":      "2024-01-15",
+		"2024-01-15T23:30:00-05:00": "2024-01-15",
+		"2024-W03-1":                "2024-01-15",
+		"2024-W03-2":                "2024-01-16",
+		"2020-W53-7":                "2021-01-03",
+		"2025-W01-1":                "2024-12-30",
+		"2024-015":                  "2024-01-15",
+		"2024-366":                  "2024-12-31",
+	}
+
+	for input, want := range cases {
+		got, err := Parse(input, Strict, now)
+		if err != nil {
+			t.Errorf("%q: unexpected error %v", input, err)
+			continue
+		}
+
+		if StringFromTime(got) != want || got.Hour() != 0 || got.Location() != time.UTC {
+			t.Errorf("%q: expected %s, got %s", input, want, got)
+		}
+	}
+
+	for _, input := range []string{
+		"", "today", "+2w", "15.01.2024", "2024-1-15", " 2024-01-15", "2024-w03-1",
+		"2024-02-30", "2024-W54-1", "2023-W53-1", "2024-W03-8", "2023-366", "2024-000",
+	} {
+		if _, err := Parse(input, Strict, now); err == nil {
+			t.Errorf("%q: expected an error in strict mode", input)
+		}
+	}
+}
+
+func TestParse_Lenient(t *testing.T) {
+	cases := map[string]string{
+		"today":           "2024-01-17",
+		" Tomorrow ":      "2024-01-18",
+		"yesterday":       "2024-01-16",
+		"+3d":             "2024-01-20",
+		"-2w":             "2024-01-03",
+		"+1m":             "2024-02-17",
+		"+1y":             "2025-01-17",
+		"wednesday":       "2024-01-17",
+		"next  Wednesday": "2024-01-24",
+		"next monday":     "2024-01-22",
+		"last wednesday":  "2024-01-10",
+		"last friday":     "2024-01-12",
+		"2024-03-01":      "2024-03-01",
+		"15.01.2024":      "2024-01-15",
+		"5.1.2024":        "2024-01-05",
+		"2024-1-5":        "2024-01-05",
+		"20240115":        "2024-01-15",
+		"2024-w03-2":      "2024-01-16",
+		"2024W03":         "2024-01-15",
+		"2024015":         "2024-01-15",
+	}
+
+	for input, want := range cases {
+		got, err := Parse(input, Lenient, now)
+		if err != nil {
+			t.Errorf("%q: unexpected error %v", input, err)
+			continue
+		}
+
+		if StringFromTime(got) != want || got.Hour() != 0 {
+			t.Errorf("%q: expected %s, got %s", input, want, got)
+		}
+	}
+
+	for _, input := range []string{"", "+3", "+d", "+3h", "next month", "soon monday", "31.02.2024", "2024-13-01"} {
+		if _, err := Parse(input, Lenient, now); err == nil {
+			t.Errorf("%q: expected an error", input)
+		}
+	}
+}
+
+func TestStringFromTimeAs(t *testing.T) {
+	day := time.Date(2024, 1, 14, 0, 0, 0, 0, time.UTC)
+
+	cases := map[Format]string{
+		FormatDate:    "2024-01-14",
+		FormatRFC3339: "2024-01-14T00:00:00Z",
+		FormatDotted:  "14.01.2024",
+		FormatWeek:    "2024-W02-7",
+		FormatOrdinal: "2024-014",
+	}
+
+	for format, want := range cases {
+		got := StringFromTimeAs(day, format)
+		if got != want {
+			t.Errorf("%s: expected %s, got %s", format, want, got)
+		}
+
+		back, err := Parse(got, Lenient, now)
+		if err != nil || !back.Equal(day) {
+			t.Errorf("%s: %s does not parse back: %s, %v", format, got, back, err)
+		}
+	}
+
+	if _, err := FormatFromString("iso"); err == nil {
+		t.Error("Expected an error for an unknown format")
+	}
+}
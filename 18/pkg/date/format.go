@@ -0,0 +1,57 @@
+package date
+
+import (
+	"fmt"
+	"slices"
+	"time"
+)
+
+// Format names an output layout clients can ask for
+type Format string
+
+const (
+	// 2024-01-15, the default
+	FormatDate Format = "date"
+	// 2024-01-15T00:00:00Z
+	FormatRFC3339 Format = "rfc3339"
+	// 15.01.2024
+	FormatDotted Format = "dotted"
+	// 2024-W03-1, ISO 8601 week date
+	FormatWeek Format = "week"
+	// 2024-015, ISO 8601 ordinal date
+	FormatOrdinal Format = "ordinal"
+)
+
+var Formats = []Format{FormatDate, FormatRFC3339, FormatDotted, FormatWeek, FormatOrdinal}
+
+// FormatFromString reads a format name, an empty one is FormatDate
+func FormatFromString(str string) (Format, error) {
+	if str == "" {
+		return FormatDate, nil
+	}
+
+	format := Format(str)
+	if !slices.Contains(Formats, format) {
+		return "", fmt.Errorf("unknown date format %q", str)
+	}
+
+	return format, nil
+}
+
+// StringFromTimeAs renders the date in the given format, lenient Parse reads
+// every one of them back
+func StringFromTimeAs(date time.Time, format Format) string {
+	switch format {
+	case FormatRFC3339:
+		return date.Format(time.RFC3339)
+	case FormatDotted:
+		return date.Format("02.01.2006")
+	case FormatWeek:
+		year, week := date.ISOWeek()
+		return fmt.Sprintf("%04d-W%02d-%d", year, week, (int(date.Weekday())+6)%7+1)
+	case FormatOrdinal:
+		return fmt.Sprintf("%04d-%03d", date.Year(), date.YearDay())
+	default:
+		return StringFromTime(date)
+	}
+}
@@ -0,0 +1,92 @@
+package date
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var weekdays = map[string]time.Weekday{
+	"sunday":    time.Sunday,
+	"monday":    time.Monday,
+	"tuesday":   time.Tuesday,
+	"wednesday": time.Wednesday,
+	"thursday":  time.Thursday,
+	"friday":    time.Friday,
+	"saturday":  time.Saturday,
+}
+
+// WeekdayFromString reads an English weekday name, case-insensitive
+func WeekdayFromString(str string) (time.Weekday, error) {
+	weekday, ok := weekdays[strings.ToLower(str)]
+	if !ok {
+		return 0, fmt.Errorf("unknown weekday %q", str)
+	}
+
+	return weekday, nil
+}
+
+// relative reads today, tomorrow, yesterday, weekday names with an optional
+// next/last and offsets like +3d, -2w, +1m or +1y. ok is false when str is not
+// a relative expression at all
+func relative(str string, now time.Time) (time.Time, bool, error) {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+
+	switch str {
+	case "today":
+		return today, true, nil
+	case "tomorrow":
+		return today.AddDate(0, 0, 1), true, nil
+	case "yesterday":
+		return today.AddDate(0, 0, -1), true, nil
+	}
+
+	if str != "" && (str[0] == '+' || str[0] == '-') {
+		day, err := offset(str, today)
+		return day, true, err
+	}
+
+	direction, name, ok := strings.Cut(str, " ")
+	if !ok {
+		direction, name = "", str
+	}
+
+	weekday, ok := weekdays[name]
+	if !ok {
+		return time.Time{}, false, nil
+	}
+
+	switch direction {
+	case "":
+		return today.AddDate(0, 0, (int(weekday)-int(today.Weekday())+7)%7), true, nil
+	case "next":
+		return today.AddDate(0, 0, (int(weekday)-int(today.Weekday())+6)%7+1), true, nil
+	case "last":
+		return today.AddDate(0, 0, -((int(today.Weekday())-int(weekday)+6)%7 + 1)), true, nil
+	default:
+		return time.Time{}, true, fmt.Errorf("invalid weekday expression %q, use next or last", str)
+	}
+}
+
+// offset applies +Nd, -Nw, +Nm or +Ny to the day
+func offset(str string, today time.Time) (time.Time, error) {
+	unit := str[len(str)-1]
+	n, err := strconv.Atoi(str[:len(str)-1])
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid date offset %q", str)
+	}
+
+	switch unit {
+	case 'd':
+		return today.AddDate(0, 0, n), nil
+	case 'w':
+		return today.AddDate(0, 0, 7*n), nil
+	case 'm':
+		return today.AddDate(0, n, 0), nil
+	case 'y':
+		return today.AddDate(n, 0, 0), nil
+	default:
+		return time.Time{}, fmt.Errorf("invalid date offset unit %q, use d, w, m or y", unit)
+	}
+}
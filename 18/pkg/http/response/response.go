@@ -28,10 +28,22 @@ func InternalServerError(w http.ResponseWriter) {
 }
 
 func MethodNotAllowed(w http.ResponseWriter, allow string) {
-	w.Header().Set("Allow", "POST")
+	w.Header().Set("Allow", allow)
 	Response(w, http.StatusMethodNotAllowed, model.ErrorResp("Method is not allowed"))
 }
 
+// JSON sends an already serialized JSON body
+func JSON(w http.ResponseWriter, status int, json []byte) {
+	send(w, status, json)
+}
+
+// Content sends a non-JSON body, like a rendered document
+func Content(w http.ResponseWriter, status int, contentType string, body []byte) {
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(status)
+	w.Write(body)
+}
+
 func send(w http.ResponseWriter, status int, json []byte) {
 	w.Header().Set("Content-Type", "application/json; charset=utf-8")
 	w.WriteHeader(status)
@@ -0,0 +1,126 @@
+// Package ics reads and writes the content lines of iCalendar (RFC 5545)
+package ics
+
+import (
+	"bufio"
+	"strings"
+	"time"
+)
+
+const (
+	DateLayout     = "20060102"
+	DateTimeLayout = "20060102T150405Z"
+
+	// lines longer than this are folded, RFC 5545 section 3.1
+	MaxLineLength = 75
+)
+
+// Line is a content line "NAME;PARAM=VALUE:value", names and parameter keys
+// are upper-cased
+type Line struct {
+	Name   string
+	Params map[string]string
+	Value  string
+}
+
+// Parse unfolds the data and splits it into content lines, ok is false on a
+// line without a colon
+func Parse(data string) ([]Line, bool) {
+	raws, err := unfold(data)
+	if err != nil {
+		return nil, false
+	}
+
+	lines := make([]Line, 0, len(raws))
+	for _, raw := range raws {
+		head, value, ok := strings.Cut(raw, ":")
+		if !ok {
+			return nil, false
+		}
+
+		parts := strings.Split(head, ";")
+		params := make(map[string]string, len(parts)-1)
+		for _, param := range parts[1:] {
+			key, val, _ := strings.Cut(param, "=")
+			params[strings.ToUpper(key)] = strings.Trim(val, `"`)
+		}
+
+		lines = append(lines, Line{Name: strings.ToUpper(parts[0]), Params: params, Value: value})
+	}
+
+	return lines, true
+}
+
+func unfold(data string) ([]string, error) {
+	lines := make([]string, 0)
+	scanner := bufio.NewScanner(strings.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if line == "" {
+			continue
+		}
+
+		if (line[0] == ' ' || line[0] == '\t') && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+
+		lines = append(lines, line)
+	}
+
+	return lines, scanner.Err()
+}
+
+// Date reads the calendar date of a DTSTART/DTEND value, the time of
+// date-times is dropped
+func (l Line) Date() (time.Time, bool) {
+	if len(l.Value) < len(DateLayout) {
+		return time.Time{}, false
+	}
+
+	if l.Params["VALUE"] != "DATE" && len(l.Value) > len(DateLayout) && l.Value[len(DateLayout)] != 'T' {
+		return time.Time{}, false
+	}
+
+	date, err := time.Parse(DateLayout, l.Value[:len(DateLayout)])
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	return date, true
+}
+
+// Writer builds an iCalendar object with CRLF line ends and folded lines
+type Writer struct {
+	b strings.Builder
+}
+
+func (w *Writer) Line(content string) {
+	for len(content) > MaxLineLength {
+		cut := MaxLineLength
+		// don't split multibyte characters
+		for cut > 0 && content[cut]&0xC0 == 0x80 {
+			cut--
+		}
+		w.b.WriteString(content[:cut] + "\r\n")
+		content = " " + content[cut:]
+	}
+	w.b.WriteString(content + "\r\n")
+}
+
+func (w *Writer) Bytes() []byte {
+	return []byte(w.b.String())
+}
+
+var (
+	textEscaper   = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\n", `\n`)
+	textUnescaper = strings.NewReplacer(`\\`, `\`, `\;`, ";", `\,`, ",", `\n`, "\n", `\N`, "\n")
+)
+
+func EscapeText(text string) string {
+	return textEscaper.Replace(text)
+}
+
+func UnescapeText(text string) string {
+	return textUnescaper.Replace(text)
+}
@@ -5,10 +5,14 @@ go 1.24.3
 require (
 	github.com/beevik/ntp v1.5.0
 	github.com/urfave/cli/v3 v3.5.0
+	golang.org/x/sys v0.36.0
+	google.golang.org/genproto/googleapis/rpc v0.0.0-20250804133106-a7a43d27e69b
+	google.golang.org/grpc v1.76.0
+	google.golang.org/protobuf v1.36.6
 	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
 	golang.org/x/net v0.44.0 // indirect
-	golang.org/x/sys v0.36.0 // indirect
+	golang.org/x/text v0.29.0 // indirect
 )